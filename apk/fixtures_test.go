@@ -0,0 +1,239 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// testManifest describes the AndroidManifest.xml buildManifest compiles.
+type testManifest struct {
+	Package     string
+	VersionCode uint32
+	VersionName string
+	MinSDK      uint32
+	TargetSDK   uint32
+	UTF8        bool
+	// StripNames leaves the android: attribute names empty, as shrinkers do, so
+	// they can only be found through the resource map.
+	StripNames bool
+}
+
+func le16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// xmlChunk encodes a chunk whose header holds the 8-byte chunk header plus
+// header, followed by body.
+func xmlChunk(chunkType uint16, header []byte, body []byte) []byte {
+	headerSize := 8 + len(header)
+	return concat(le16(chunkType), le16(uint16(headerSize)), le32(uint32(headerSize+len(body))), header, body)
+}
+
+func stringPoolChunk(pool []string, utf8 bool) []byte {
+	var offsets, data []byte
+	for _, s := range pool {
+		offsets = append(offsets, le32(uint32(len(data)))...)
+		if utf8 {
+			data = append(data, byte(len(utf16.Encode([]rune(s)))), byte(len(s)))
+			data = append(data, s...)
+			data = append(data, 0)
+		} else {
+			units := utf16.Encode([]rune(s))
+			data = append(data, le16(uint16(len(units)))...)
+			for _, unit := range units {
+				data = append(data, le16(unit)...)
+			}
+			data = append(data, 0, 0)
+		}
+	}
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+
+	var flags uint32
+	if utf8 {
+		flags = stringPoolUTF8
+	}
+
+	header := concat(le32(uint32(len(pool))), le32(0), le32(flags), le32(uint32(28+len(offsets))), le32(0))
+	return xmlChunk(chunkStringPool, header, concat(offsets, data))
+}
+
+type testAttribute struct {
+	ns, name, raw uint32
+	dataType      uint8
+	data          uint32
+}
+
+func startElementChunk(name uint32, attributes []testAttribute) []byte {
+	body := concat(le32(noIndex), le32(name), le16(20), le16(20), le16(uint16(len(attributes))), le16(0), le16(0), le16(0))
+	for _, a := range attributes {
+		body = concat(body, le32(a.ns), le32(a.name), le32(a.raw), le16(8), []byte{0, a.dataType}, le32(a.data))
+	}
+	return xmlChunk(chunkStartElement, concat(le32(1), le32(noIndex)), body)
+}
+
+func endElementChunk(name uint32) []byte {
+	return xmlChunk(0x0103, concat(le32(1), le32(noIndex)), concat(le32(noIndex), le32(name)))
+}
+
+// buildManifest compiles m into the binary XML aapt produces.
+func buildManifest(m testManifest) []byte {
+	pool := []string{"versionCode", "versionName", "minSdkVersion", "targetSdkVersion",
+		"android", "http://schemas.android.com/apk/res/android", "package", "manifest", "uses-sdk", m.Package, m.VersionName}
+	if m.StripNames {
+		for i := 0; i < 4; i++ {
+			pool[i] = ""
+		}
+	}
+
+	const ns = 5
+	resourceMap := concat(le32(attrVersionCode), le32(attrVersionName), le32(attrMinSDKVersion), le32(attrTargetSDKVersion))
+
+	body := concat(
+		stringPoolChunk(pool, m.UTF8),
+		xmlChunk(chunkResourceMap, nil, resourceMap),
+		xmlChunk(0x0100, concat(le32(1), le32(noIndex)), concat(le32(4), le32(ns))),
+		startElementChunk(7, []testAttribute{
+			{ns: ns, name: 0, raw: noIndex, dataType: typeIntDec, data: m.VersionCode},
+			{ns: ns, name: 1, raw: 10, dataType: typeString, data: 10},
+			{ns: noIndex, name: 6, raw: 9, dataType: typeString, data: 9},
+		}),
+		startElementChunk(8, []testAttribute{
+			{ns: ns, name: 2, raw: noIndex, dataType: typeIntDec, data: m.MinSDK},
+			{ns: ns, name: 3, raw: noIndex, dataType: typeIntDec, data: m.TargetSDK},
+		}),
+		endElementChunk(8),
+		endElementChunk(7),
+	)
+
+	return xmlChunk(chunkXML, nil, body)
+}
+
+func newTestCertificate(t *testing.T, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2054, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+// buildPKCS7 wraps cert in a PKCS #7 SignedData structure with no signers,
+// which is all v1Certificates reads.
+func buildPKCS7(t *testing.T, cert *x509.Certificate) []byte {
+	data, _ := asn1.Marshal(struct{ ContentType asn1.ObjectIdentifier }{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}})
+
+	signed, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      asn1.RawValue{FullBytes: data},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: cert.Raw},
+		SignerInfos:      asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := asn1.Marshal(contentInfo{
+		ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2},
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info
+}
+
+func withLength(data []byte) []byte {
+	return concat(le32(uint32(len(data))), data)
+}
+
+// buildSchemeValue encodes a v2/v3 signature block value with one signer that
+// carries cert. Digests, signatures and the public key are left empty.
+func buildSchemeValue(cert *x509.Certificate) []byte {
+	signedData := concat(withLength(nil), withLength(withLength(cert.Raw)), withLength(nil))
+	signer := concat(withLength(signedData), withLength(nil), withLength(nil))
+	return withLength(withLength(signer))
+}
+
+// buildSigningBlock encodes an APK Signing Block holding the given ID-value pairs.
+func buildSigningBlock(pairs map[uint32][]byte) []byte {
+	var body []byte
+	for _, id := range []uint32{blockIDSchemeV2, blockIDSchemeV3} {
+		value, ok := pairs[id]
+		if !ok {
+			continue
+		}
+		body = concat(body, binary.LittleEndian.AppendUint64(nil, uint64(4+len(value))), le32(id), value)
+	}
+
+	size := binary.LittleEndian.AppendUint64(nil, uint64(len(body)+signingBlockFooter))
+	return concat(size, body, size, []byte(signingBlockMagic))
+}
+
+// buildAPK zips files and, when block is not nil, inserts it right before the
+// central directory the way apksigner does.
+func buildAPK(t *testing.T, files map[string][]byte, block []byte) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range []string{ManifestName, "classes.dex", "META-INF/CERT.RSA"} {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if block == nil {
+		return data
+	}
+
+	eocd := bytes.LastIndex(data, le32(eocdSignature))
+	centralDirectory := binary.LittleEndian.Uint32(data[eocd+16:])
+
+	signed := concat(data[:centralDirectory], block, data[centralDirectory:])
+	binary.LittleEndian.PutUint32(signed[eocd+len(block)+16:], centralDirectory+uint32(len(block)))
+
+	return signed
+}
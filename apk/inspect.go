@@ -0,0 +1,125 @@
+package apk
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	storage "go_aws_services/s3"
+)
+
+const maxManifestSize = 10 * 1024 * 1024
+
+var _ ObjectOpener = (*storage.S3Service)(nil)
+
+// Inspect checks that r holds an APK with a signature and extracts its package
+// name, version, SDK levels and the certificates of its signatures. Only the
+// central directory, AndroidManifest.xml, the v1 signature files and the APK
+// Signing Block are read, so r can be a ranged reader over a remote object.
+// This also means the signatures and content digests are not verified: the
+// certificates are reported as UnverifiedCertificates, and an APK needs to be
+// checked with apksigner before they can be trusted.
+//
+// Parameters:
+//
+//	r (io.ReaderAt): The APK contents.
+//	size (int64): The size of the APK in bytes.
+//
+// Returns:
+//
+//	(*Artifact, error): What was found in the APK, or ErrNotAPK, ErrBadManifest or ErrUnsigned if it is not an APK or has no signature.
+func Inspect(r io.ReaderAt, size int64) (*Artifact, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAPK, err)
+	}
+
+	var manifestFile *zip.File
+	for _, file := range archive.File {
+		if file.Name == ManifestName {
+			manifestFile = file
+			break
+		}
+	}
+
+	if manifestFile == nil {
+		return nil, fmt.Errorf("%w: no %s", ErrNotAPK, ManifestName)
+	}
+
+	data, err := readEntry(manifestFile, maxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	schemes, certs, err := embeddedCertificates(r, size, archive.File)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certs) == 0 {
+		return nil, ErrUnsigned
+	}
+
+	return &Artifact{
+		PackageName:            manifest.PackageName,
+		VersionCode:            manifest.VersionCode,
+		VersionName:            manifest.VersionName,
+		MinSDKVersion:          manifest.MinSDKVersion,
+		TargetSDKVersion:       manifest.TargetSDKVersion,
+		Size:                   size,
+		Entries:                len(archive.File),
+		SigningSchemes:         schemes,
+		UnverifiedCertificates: certs,
+	}, nil
+}
+
+// InspectObject inspects the APK stored under key, reading only the parts of it
+// Inspect needs.
+func InspectObject(ctx context.Context, opener ObjectOpener, key string) (*Artifact, error) {
+	object, err := opener.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	size, err := object.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	readerAt, ok := object.(io.ReaderAt)
+	if !ok {
+		readerAt = &seekReaderAt{r: object}
+	}
+
+	return Inspect(readerAt, size)
+}
+
+// seekReaderAt adapts an io.ReadSeeker that does not implement io.ReaderAt.
+type seekReaderAt struct {
+	mu sync.Mutex
+	r  io.ReadSeeker
+}
+
+func (s *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(s.r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
@@ -0,0 +1,129 @@
+package apk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockObjectOpener struct {
+	mock.Mock
+}
+
+func (m *mockObjectOpener) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	args := m.Called(ctx, key)
+	if rc, ok := args.Get(0).(io.ReadSeekCloser); ok {
+		return rc, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// seekOnlyReader hides the io.ReaderAt of a *bytes.Reader.
+type seekOnlyReader struct {
+	io.ReadSeeker
+}
+
+func (seekOnlyReader) Close() error { return nil }
+
+type readerAtCloser struct {
+	*bytes.Reader
+}
+
+func (readerAtCloser) Close() error { return nil }
+
+func buildSignedAPK(t *testing.T) ([]byte, Certificate) {
+	cert := newTestCertificate(t, "release")
+	manifest := buildManifest(testManifest{Package: "com.brunojet.player", VersionCode: 7, VersionName: "1.0.7", MinSDK: 21, TargetSDK: 34})
+	block := buildSigningBlock(map[uint32][]byte{blockIDSchemeV2: buildSchemeValue(cert)})
+
+	data := buildAPK(t, map[string][]byte{
+		ManifestName:        manifest,
+		"classes.dex":       []byte("dex\n035"),
+		"META-INF/CERT.RSA": buildPKCS7(t, cert),
+	}, block)
+
+	return data, newCertificate(cert)
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("Signed APK", func(t *testing.T) {
+		data, cert := buildSignedAPK(t)
+
+		artifact, err := Inspect(bytes.NewReader(data), int64(len(data)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Artifact{
+			PackageName:            "com.brunojet.player",
+			VersionCode:            7,
+			VersionName:            "1.0.7",
+			MinSDKVersion:          21,
+			TargetSDKVersion:       34,
+			Size:                   int64(len(data)),
+			Entries:                3,
+			SigningSchemes:         []string{SchemeV1, SchemeV2},
+			UnverifiedCertificates: []Certificate{cert},
+		}, artifact)
+	})
+
+	t.Run("Not a ZIP", func(t *testing.T) {
+		data := []byte("definitely not a zip file")
+
+		_, err := Inspect(bytes.NewReader(data), int64(len(data)))
+
+		assert.True(t, errors.Is(err, ErrNotAPK))
+	})
+
+	t.Run("ZIP without manifest", func(t *testing.T) {
+		data := buildAPK(t, map[string][]byte{"classes.dex": []byte("dex")}, nil)
+
+		_, err := Inspect(bytes.NewReader(data), int64(len(data)))
+
+		assert.True(t, errors.Is(err, ErrNotAPK))
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		data := buildAPK(t, map[string][]byte{ManifestName: buildManifest(testManifest{Package: "com.example"})}, nil)
+
+		_, err := Inspect(bytes.NewReader(data), int64(len(data)))
+
+		assert.Equal(t, ErrUnsigned, err)
+	})
+}
+
+func TestInspectObject(t *testing.T) {
+	data, cert := buildSignedAPK(t)
+
+	t.Run("Reader with ReadAt", func(t *testing.T) {
+		opener := new(mockObjectOpener)
+		opener.On("Open", mock.Anything, "uploads/1-2-3.apk").Return(readerAtCloser{bytes.NewReader(data)}, nil)
+
+		artifact, err := InspectObject(context.Background(), opener, "uploads/1-2-3.apk")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Certificate{cert}, artifact.UnverifiedCertificates)
+	})
+
+	t.Run("Seek-only reader", func(t *testing.T) {
+		opener := new(mockObjectOpener)
+		opener.On("Open", mock.Anything, "uploads/1-2-3.apk").Return(seekOnlyReader{bytes.NewReader(data)}, nil)
+
+		artifact, err := InspectObject(context.Background(), opener, "uploads/1-2-3.apk")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "com.brunojet.player", artifact.PackageName)
+	})
+
+	t.Run("Open failure", func(t *testing.T) {
+		opener := new(mockObjectOpener)
+		opener.On("Open", mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+
+		_, err := InspectObject(context.Background(), opener, "uploads/missing.apk")
+
+		assert.EqualError(t, err, "not found")
+	})
+}
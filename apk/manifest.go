@@ -0,0 +1,311 @@
+package apk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Chunk types of the binary XML format aapt compiles AndroidManifest.xml into.
+const (
+	chunkStringPool   = 0x0001
+	chunkXML          = 0x0003
+	chunkStartElement = 0x0102
+	chunkResourceMap  = 0x0180
+)
+
+const (
+	stringPoolUTF8 = 1 << 8
+	noIndex        = 0xffffffff
+)
+
+// Typed value data types.
+const (
+	typeString = 0x03
+	typeIntDec = 0x10
+	typeIntHex = 0x11
+)
+
+// Resource IDs of the android: attributes read from the manifest. Attributes are
+// matched by ID first because shrinkers may strip their names from the pool.
+const (
+	attrMinSDKVersion    = 0x0101020c
+	attrVersionCode      = 0x0101021b
+	attrVersionName      = 0x0101021c
+	attrTargetSDKVersion = 0x01010270
+)
+
+var attrNames = map[uint32]string{
+	attrMinSDKVersion:    "minSdkVersion",
+	attrVersionCode:      "versionCode",
+	attrVersionName:      "versionName",
+	attrTargetSDKVersion: "targetSdkVersion",
+}
+
+type xmlAttribute struct {
+	name     string
+	value    string
+	dataType uint8
+	data     uint32
+}
+
+func (a xmlAttribute) int() (int64, bool) {
+	switch a.dataType {
+	case typeIntDec, typeIntHex:
+		return int64(int32(a.data)), true
+	case typeString:
+		n, err := strconv.ParseInt(a.value, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// ParseManifest decodes a compiled AndroidManifest.xml and returns the package
+// name, version and SDK levels declared on <manifest> and <uses-sdk>.
+func ParseManifest(data []byte) (Manifest, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != chunkXML {
+		return Manifest{}, ErrBadManifest
+	}
+
+	var (
+		manifest     Manifest
+		stringPool   []string
+		resourceIDs  []uint32
+		seenManifest bool
+	)
+
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+
+	for offset+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		headerSize := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+
+		if size < 8 || headerSize > size || offset+size > len(data) {
+			return Manifest{}, fmt.Errorf("%w: chunk at %d overruns the file", ErrBadManifest, offset)
+		}
+
+		chunk := data[offset : offset+size]
+
+		switch chunkType {
+		case chunkStringPool:
+			pool, err := parseStringPool(chunk, headerSize)
+			if err != nil {
+				return Manifest{}, err
+			}
+			stringPool = pool
+
+		case chunkResourceMap:
+			for i := headerSize; i+4 <= size; i += 4 {
+				resourceIDs = append(resourceIDs, binary.LittleEndian.Uint32(chunk[i:]))
+			}
+
+		case chunkStartElement:
+			name, attributes, err := parseStartElement(chunk, headerSize, stringPool, resourceIDs)
+			if err != nil {
+				return Manifest{}, err
+			}
+
+			switch name {
+			case "manifest":
+				if seenManifest {
+					break
+				}
+				seenManifest = true
+				for _, attribute := range attributes {
+					switch attribute.name {
+					case "package":
+						manifest.PackageName = attribute.value
+					case "versionCode":
+						if n, ok := attribute.int(); ok {
+							manifest.VersionCode = int64(uint32(n))
+						}
+					case "versionName":
+						manifest.VersionName = attribute.value
+					}
+				}
+
+			case "uses-sdk":
+				for _, attribute := range attributes {
+					n, ok := attribute.int()
+					if !ok {
+						continue
+					}
+					switch attribute.name {
+					case "minSdkVersion":
+						manifest.MinSDKVersion = int(n)
+					case "targetSdkVersion":
+						manifest.TargetSDKVersion = int(n)
+					}
+				}
+			}
+		}
+
+		offset += size
+	}
+
+	if !seenManifest || manifest.PackageName == "" {
+		return Manifest{}, fmt.Errorf("%w: no package name", ErrBadManifest)
+	}
+
+	return manifest, nil
+}
+
+func parseStringPool(chunk []byte, headerSize int) ([]string, error) {
+	if headerSize < 28 {
+		return nil, fmt.Errorf("%w: short string pool header", ErrBadManifest)
+	}
+
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	flags := binary.LittleEndian.Uint32(chunk[16:])
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+
+	if headerSize+count*4 > len(chunk) || stringsStart > len(chunk) {
+		return nil, fmt.Errorf("%w: string pool overruns its chunk", ErrBadManifest)
+	}
+
+	pool := make([]string, count)
+	for i := range pool {
+		offset := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+i*4:]))
+
+		var (
+			s   string
+			err error
+		)
+		if flags&stringPoolUTF8 != 0 {
+			s, err = decodeUTF8String(chunk, offset)
+		} else {
+			s, err = decodeUTF16String(chunk, offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pool[i] = s
+	}
+
+	return pool, nil
+}
+
+// decodeUTF8String reads a string stored as its UTF-16 length, its UTF-8 length
+// and the UTF-8 bytes. Lengths above 0x7f take two bytes.
+func decodeUTF8String(chunk []byte, offset int) (string, error) {
+	_, offset, err := decodeUTF8Length(chunk, offset)
+	if err != nil {
+		return "", err
+	}
+
+	length, offset, err := decodeUTF8Length(chunk, offset)
+	if err != nil {
+		return "", err
+	}
+
+	if offset+length > len(chunk) {
+		return "", fmt.Errorf("%w: string overruns the pool", ErrBadManifest)
+	}
+
+	return string(chunk[offset : offset+length]), nil
+}
+
+func decodeUTF8Length(chunk []byte, offset int) (int, int, error) {
+	if offset >= len(chunk) {
+		return 0, 0, fmt.Errorf("%w: string overruns the pool", ErrBadManifest)
+	}
+
+	length := int(chunk[offset])
+	if length&0x80 == 0 {
+		return length, offset + 1, nil
+	}
+
+	if offset+1 >= len(chunk) {
+		return 0, 0, fmt.Errorf("%w: string overruns the pool", ErrBadManifest)
+	}
+
+	return (length&0x7f)<<8 | int(chunk[offset+1]), offset + 2, nil
+}
+
+// decodeUTF16String reads a string stored as its length in code units followed
+// by the UTF-16LE code units. Lengths above 0x7fff take two units.
+func decodeUTF16String(chunk []byte, offset int) (string, error) {
+	if offset+2 > len(chunk) {
+		return "", fmt.Errorf("%w: string overruns the pool", ErrBadManifest)
+	}
+
+	length := int(binary.LittleEndian.Uint16(chunk[offset:]))
+	offset += 2
+
+	if length&0x8000 != 0 {
+		if offset+2 > len(chunk) {
+			return "", fmt.Errorf("%w: string overruns the pool", ErrBadManifest)
+		}
+		length = (length&0x7fff)<<16 | int(binary.LittleEndian.Uint16(chunk[offset:]))
+		offset += 2
+	}
+
+	if offset+length*2 > len(chunk) {
+		return "", fmt.Errorf("%w: string overruns the pool", ErrBadManifest)
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(chunk[offset+i*2:])
+	}
+
+	return string(utf16.Decode(units)), nil
+}
+
+func parseStartElement(chunk []byte, headerSize int, stringPool []string, resourceIDs []uint32) (string, []xmlAttribute, error) {
+	if headerSize+20 > len(chunk) {
+		return "", nil, fmt.Errorf("%w: short start element", ErrBadManifest)
+	}
+
+	ext := chunk[headerSize:]
+	name := lookupString(stringPool, binary.LittleEndian.Uint32(ext[4:]))
+	attributeStart := headerSize + int(binary.LittleEndian.Uint16(ext[8:]))
+	attributeSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attributeCount := int(binary.LittleEndian.Uint16(ext[12:]))
+
+	if attributeSize < 20 || attributeStart+attributeCount*attributeSize > len(chunk) {
+		return "", nil, fmt.Errorf("%w: attributes overrun element %q", ErrBadManifest, name)
+	}
+
+	attributes := make([]xmlAttribute, attributeCount)
+	for i := range attributes {
+		a := chunk[attributeStart+i*attributeSize:]
+		nameIndex := binary.LittleEndian.Uint32(a[4:])
+		rawValue := binary.LittleEndian.Uint32(a[8:])
+
+		attribute := xmlAttribute{
+			name:     lookupString(stringPool, nameIndex),
+			dataType: a[15],
+			data:     binary.LittleEndian.Uint32(a[16:]),
+		}
+
+		if int(nameIndex) < len(resourceIDs) {
+			if known, ok := attrNames[resourceIDs[nameIndex]]; ok {
+				attribute.name = known
+			}
+		}
+
+		switch {
+		case attribute.dataType == typeString:
+			attribute.value = lookupString(stringPool, attribute.data)
+		case rawValue != noIndex:
+			attribute.value = lookupString(stringPool, rawValue)
+		}
+
+		attributes[i] = attribute
+	}
+
+	return name, attributes, nil
+}
+
+func lookupString(stringPool []string, index uint32) string {
+	if index == noIndex || int(index) >= len(stringPool) {
+		return ""
+	}
+
+	return stringPool[index]
+}
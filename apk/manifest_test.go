@@ -0,0 +1,61 @@
+package apk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseManifest(t *testing.T) {
+	expected := Manifest{
+		PackageName:      "com.brunojet.player",
+		VersionCode:      1042,
+		VersionName:      "1.4.2-ção",
+		MinSDKVersion:    24,
+		TargetSDKVersion: 34,
+	}
+
+	for _, tc := range []struct {
+		name     string
+		manifest testManifest
+	}{
+		{"UTF-16 string pool", testManifest{}},
+		{"UTF-8 string pool", testManifest{UTF8: true}},
+		{"Stripped attribute names", testManifest{StripNames: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.manifest
+			m.Package = expected.PackageName
+			m.VersionCode = uint32(expected.VersionCode)
+			m.VersionName = expected.VersionName
+			m.MinSDK = uint32(expected.MinSDKVersion)
+			m.TargetSDK = uint32(expected.TargetSDKVersion)
+
+			manifest, err := ParseManifest(buildManifest(m))
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, manifest)
+		})
+	}
+
+	t.Run("Plain text XML", func(t *testing.T) {
+		_, err := ParseManifest([]byte(`<?xml version="1.0"?><manifest package="com.example"/>`))
+
+		assert.True(t, errors.Is(err, ErrBadManifest))
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		data := buildManifest(testManifest{Package: "com.example"})
+
+		_, err := ParseManifest(data[:len(data)-10])
+
+		assert.True(t, errors.Is(err, ErrBadManifest))
+	})
+
+	t.Run("No package", func(t *testing.T) {
+		_, err := ParseManifest(buildManifest(testManifest{}))
+
+		assert.True(t, errors.Is(err, ErrBadManifest))
+	})
+}
@@ -0,0 +1,327 @@
+package apk
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// IDs of the signature schemes stored in the APK Signing Block.
+const (
+	blockIDSchemeV2 = 0x7109871a
+	blockIDSchemeV3 = 0xf05368c0
+)
+
+const (
+	signingBlockMagic  = "APK Sig Block 42"
+	eocdSignature      = 0x06054b50
+	eocdMinSize        = 22
+	maxCommentSize     = 0xffff
+	maxSigningBlock    = 64 * 1024 * 1024
+	maxSignatureEntry  = 1024 * 1024
+	signingBlockFooter = 24
+)
+
+var errNoSigningBlock = errors.New("no APK Signing Block")
+
+// contentInfo and signedData are the parts of a PKCS #7 SignedData structure
+// needed to reach the certificates of a v1 (JAR) signature.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+func newCertificate(cert *x509.Certificate) Certificate {
+	sha256Sum := sha256.Sum256(cert.Raw)
+	sha1Sum := sha1.Sum(cert.Raw)
+
+	return Certificate{
+		Subject: cert.Subject.String(),
+		SHA256:  hex.EncodeToString(sha256Sum[:]),
+		SHA1:    hex.EncodeToString(sha1Sum[:]),
+	}
+}
+
+// isSignatureFile reports whether name is the PKCS #7 signature of a v1
+// signature, META-INF/<signer>.RSA, .DSA or .EC.
+func isSignatureFile(name string) bool {
+	if path.Dir(name) != "META-INF" {
+		return false
+	}
+
+	switch strings.ToUpper(path.Ext(name)) {
+	case ".RSA", ".DSA", ".EC":
+		return true
+	default:
+		return false
+	}
+}
+
+// v1Certificates returns the certificates of the JAR signatures in files.
+func v1Certificates(files []*zip.File) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for _, file := range files {
+		if !isSignatureFile(file.Name) {
+			continue
+		}
+
+		data, err := readEntry(file, maxSignatureEntry)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := parsePKCS7Certificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file.Name, err)
+		}
+
+		certs = append(certs, parsed...)
+	}
+
+	return certs, nil
+}
+
+func parsePKCS7Certificates(data []byte) ([]*x509.Certificate, error) {
+	var info contentInfo
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid PKCS #7 content: %v", err)
+	}
+
+	var signed signedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signed); err != nil {
+		return nil, fmt.Errorf("invalid PKCS #7 signed data: %v", err)
+	}
+
+	return x509.ParseCertificates(signed.Certificates.Bytes)
+}
+
+// signingBlock returns the ID-value pairs of the APK Signing Block, which sits
+// right before the ZIP central directory.
+func signingBlock(r io.ReaderAt, size int64) (map[uint32][]byte, error) {
+	centralDirectory, err := centralDirectoryOffset(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if centralDirectory < signingBlockFooter+8 {
+		return nil, errNoSigningBlock
+	}
+
+	footer := make([]byte, signingBlockFooter)
+	if _, err := r.ReadAt(footer, centralDirectory-signingBlockFooter); err != nil {
+		return nil, err
+	}
+
+	if string(footer[8:]) != signingBlockMagic {
+		return nil, errNoSigningBlock
+	}
+
+	blockSize := int64(binary.LittleEndian.Uint64(footer))
+	if blockSize < signingBlockFooter || blockSize > maxSigningBlock || blockSize+8 > centralDirectory {
+		return nil, errors.New("invalid APK Signing Block size")
+	}
+
+	block := make([]byte, blockSize+8)
+	if _, err := r.ReadAt(block, centralDirectory-blockSize-8); err != nil {
+		return nil, err
+	}
+
+	if int64(binary.LittleEndian.Uint64(block)) != blockSize {
+		return nil, errors.New("APK Signing Block sizes do not match")
+	}
+
+	pairs := make(map[uint32][]byte)
+	data := block[8 : len(block)-signingBlockFooter]
+
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, errors.New("truncated APK Signing Block entry")
+		}
+
+		length := binary.LittleEndian.Uint64(data)
+		if length < 4 || length > uint64(len(data)-8) {
+			return nil, errors.New("invalid APK Signing Block entry length")
+		}
+
+		pairs[binary.LittleEndian.Uint32(data[8:])] = data[12 : 8+length]
+		data = data[8+length:]
+	}
+
+	return pairs, nil
+}
+
+// centralDirectoryOffset finds the End of Central Directory record in the last
+// 64 KiB of the file and returns the offset of the central directory.
+func centralDirectoryOffset(r io.ReaderAt, size int64) (int64, error) {
+	tailSize := min(size, eocdMinSize+maxCommentSize)
+	tail := make([]byte, tailSize)
+	if _, err := r.ReadAt(tail, size-tailSize); err != nil {
+		return 0, err
+	}
+
+	for i := len(tail) - eocdMinSize; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(tail[i:]) == eocdSignature {
+			return int64(binary.LittleEndian.Uint32(tail[i+16:])), nil
+		}
+	}
+
+	return 0, errors.New("no End of Central Directory record")
+}
+
+// schemeCertificates returns the certificates of every signer in a v2 or v3
+// signature block value. Both schemes share the layout
+// signers > signer > signed data > [digests, certificates], where every level
+// is a sequence of uint32 length-prefixed entries.
+func schemeCertificates(value []byte) ([]*x509.Certificate, error) {
+	signers, _, err := lengthPrefixed(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+
+	for len(signers) > 0 {
+		var signer []byte
+		signer, signers, err = lengthPrefixed(signers)
+		if err != nil {
+			return nil, err
+		}
+
+		signed, _, err := lengthPrefixed(signer)
+		if err != nil {
+			return nil, err
+		}
+
+		_, rest, err := lengthPrefixed(signed)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded, _, err := lengthPrefixed(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		for len(encoded) > 0 {
+			var der []byte
+			der, encoded, err = lengthPrefixed(encoded)
+			if err != nil {
+				return nil, err
+			}
+
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, cert)
+		}
+	}
+
+	return certs, nil
+}
+
+func lengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, errors.New("truncated length-prefixed value")
+	}
+
+	length := binary.LittleEndian.Uint32(data)
+	if uint64(length) > uint64(len(data)-4) {
+		return nil, nil, errors.New("length-prefixed value overruns its container")
+	}
+
+	return data[4 : 4+length], data[4+length:], nil
+}
+
+// embeddedCertificates returns the signature schemes present in the APK and the
+// distinct certificates embedded in all of them, in scheme order. The
+// signatures themselves are not verified.
+func embeddedCertificates(r io.ReaderAt, size int64, files []*zip.File) ([]string, []Certificate, error) {
+	var (
+		schemes []string
+		certs   []Certificate
+		seen    = make(map[string]bool)
+	)
+
+	add := func(scheme string, parsed []*x509.Certificate) {
+		if len(parsed) == 0 {
+			return
+		}
+
+		schemes = append(schemes, scheme)
+
+		for _, cert := range parsed {
+			certificate := newCertificate(cert)
+			if !seen[certificate.SHA256] {
+				seen[certificate.SHA256] = true
+				certs = append(certs, certificate)
+			}
+		}
+	}
+
+	v1, err := v1Certificates(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	add(SchemeV1, v1)
+
+	pairs, err := signingBlock(r, size)
+	if err != nil && !errors.Is(err, errNoSigningBlock) {
+		return nil, nil, err
+	}
+
+	for _, scheme := range []struct {
+		name string
+		id   uint32
+	}{
+		{SchemeV2, blockIDSchemeV2},
+		{SchemeV3, blockIDSchemeV3},
+	} {
+		value, ok := pairs[scheme.id]
+		if !ok {
+			continue
+		}
+
+		parsed, err := schemeCertificates(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s signature: %v", scheme.name, err)
+		}
+		add(scheme.name, parsed)
+	}
+
+	return schemes, certs, nil
+}
+
+func readEntry(file *zip.File, limit int64) ([]byte, error) {
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is larger than %d bytes", file.Name, limit)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(io.LimitReader(rc, limit))
+}
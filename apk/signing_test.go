@@ -0,0 +1,77 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedCertificates(t *testing.T) {
+	release := newTestCertificate(t, "release")
+	rotated := newTestCertificate(t, "rotated")
+	manifest := buildManifest(testManifest{Package: "com.example"})
+
+	embeddedCertificatesOf := func(t *testing.T, data []byte) ([]string, []Certificate, error) {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		assert.NoError(t, err)
+		return embeddedCertificates(bytes.NewReader(data), int64(len(data)), archive.File)
+	}
+
+	t.Run("v1 only", func(t *testing.T) {
+		data := buildAPK(t, map[string][]byte{ManifestName: manifest, "META-INF/CERT.RSA": buildPKCS7(t, release)}, nil)
+
+		schemes, certs, err := embeddedCertificatesOf(t, data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{SchemeV1}, schemes)
+		assert.Equal(t, []Certificate{newCertificate(release)}, certs)
+		assert.Equal(t, "CN=release", certs[0].Subject)
+		assert.Len(t, certs[0].SHA256, 64)
+		assert.Len(t, certs[0].SHA1, 40)
+	})
+
+	t.Run("v2 only", func(t *testing.T) {
+		block := buildSigningBlock(map[uint32][]byte{blockIDSchemeV2: buildSchemeValue(release)})
+		data := buildAPK(t, map[string][]byte{ManifestName: manifest}, block)
+
+		schemes, certs, err := embeddedCertificatesOf(t, data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{SchemeV2}, schemes)
+		assert.Equal(t, []Certificate{newCertificate(release)}, certs)
+	})
+
+	t.Run("All schemes with key rotation", func(t *testing.T) {
+		block := buildSigningBlock(map[uint32][]byte{
+			blockIDSchemeV2: buildSchemeValue(release),
+			blockIDSchemeV3: buildSchemeValue(rotated),
+		})
+		data := buildAPK(t, map[string][]byte{ManifestName: manifest, "META-INF/CERT.RSA": buildPKCS7(t, release)}, block)
+
+		schemes, certs, err := embeddedCertificatesOf(t, data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{SchemeV1, SchemeV2, SchemeV3}, schemes)
+		assert.Equal(t, []Certificate{newCertificate(release), newCertificate(rotated)}, certs)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		data := buildAPK(t, map[string][]byte{ManifestName: manifest}, nil)
+
+		schemes, certs, err := embeddedCertificatesOf(t, data)
+
+		assert.NoError(t, err)
+		assert.Empty(t, schemes)
+		assert.Empty(t, certs)
+	})
+
+	t.Run("Corrupt v1 signature", func(t *testing.T) {
+		data := buildAPK(t, map[string][]byte{ManifestName: manifest, "META-INF/CERT.RSA": []byte("not pkcs7")}, nil)
+
+		_, _, err := embeddedCertificatesOf(t, data)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,61 @@
+package apk
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+const (
+	ManifestName = "AndroidManifest.xml"
+
+	SchemeV1 = "v1"
+	SchemeV2 = "v2"
+	SchemeV3 = "v3"
+)
+
+var (
+	ErrNotAPK      = errors.New("file is not an APK")
+	ErrBadManifest = errors.New("invalid binary AndroidManifest.xml")
+	ErrUnsigned    = errors.New("APK has no signature")
+)
+
+// ObjectOpener opens a stored object for random access. *s3.S3Service
+// implements it; the reader it returns should also implement io.ReaderAt so
+// every read is a single range request.
+type ObjectOpener interface {
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+}
+
+// Artifact is what Inspect learns about an APK. It is meant to be stored next to
+// the upload's Metadata record. SigningSchemes and UnverifiedCertificates are
+// read from the signatures as they are, without checking them: anyone can copy
+// a certificate into an APK, so they must not be used to decide who signed it.
+type Artifact struct {
+	PackageName            string        `json:"packageName" dynamodbav:"packageName"`
+	VersionCode            int64         `json:"versionCode" dynamodbav:"versionCode"`
+	VersionName            string        `json:"versionName,omitempty" dynamodbav:"versionName,omitempty"`
+	MinSDKVersion          int           `json:"minSdkVersion,omitempty" dynamodbav:"minSdkVersion,omitempty"`
+	TargetSDKVersion       int           `json:"targetSdkVersion,omitempty" dynamodbav:"targetSdkVersion,omitempty"`
+	Size                   int64         `json:"size" dynamodbav:"size"`
+	Entries                int           `json:"entries" dynamodbav:"entries"`
+	SigningSchemes         []string      `json:"signingSchemes" dynamodbav:"signingSchemes"`
+	UnverifiedCertificates []Certificate `json:"unverifiedCertificates" dynamodbav:"unverifiedCertificates"`
+}
+
+// Certificate identifies a signing certificate by the digests of its DER
+// encoding, the form shown by apksigner and the Play Console.
+type Certificate struct {
+	Subject string `json:"subject" dynamodbav:"subject"`
+	SHA256  string `json:"sha256" dynamodbav:"sha256"`
+	SHA1    string `json:"sha1" dynamodbav:"sha1"`
+}
+
+// Manifest holds the AndroidManifest.xml attributes Inspect extracts.
+type Manifest struct {
+	PackageName      string
+	VersionCode      int64
+	VersionName      string
+	MinSDKVersion    int
+	TargetSDKVersion int
+}
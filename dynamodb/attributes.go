@@ -0,0 +1,243 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// encodeItem renders an item as a single JSON document, either in the typed
+// DynamoDB JSON layout ({"id":{"S":"1"}}) or as plain JSON ({"id":"1"}).
+func encodeItem(item map[string]*dynamodb.AttributeValue, format string) ([]byte, error) {
+	switch format {
+	case "", DumpFormatDynamoDBJSON:
+		out := make(map[string]interface{}, len(item))
+		for name, av := range item {
+			out[name] = attributeValueToDynamoDBJSON(av)
+		}
+		return json.Marshal(out)
+	case DumpFormatJSON:
+		out := make(map[string]interface{}, len(item))
+		for name, av := range item {
+			out[name] = attributeValueToPlainJSON(av)
+		}
+		return json.Marshal(out)
+	default:
+		return nil, fmt.Errorf("unsupported dump format %q", format)
+	}
+}
+
+// decodeItem parses a document produced by encodeItem back into an item.
+func decodeItem(data []byte, format string) (map[string]*dynamodb.AttributeValue, error) {
+	switch format {
+	case "", DumpFormatDynamoDBJSON:
+		var raw map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+
+		item := make(map[string]*dynamodb.AttributeValue, len(raw))
+		for name, typed := range raw {
+			av, err := attributeValueFromDynamoDBJSON(typed)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s: %v", name, err)
+			}
+			item[name] = av
+		}
+		return item, nil
+	case DumpFormatJSON:
+		var raw map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		item := make(map[string]*dynamodb.AttributeValue, len(raw))
+		for name, value := range raw {
+			av, err := attributeValueFromPlainJSON(value)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s: %v", name, err)
+			}
+			item[name] = av
+		}
+		return item, nil
+	default:
+		return nil, fmt.Errorf("unsupported dump format %q", format)
+	}
+}
+
+func attributeValueToDynamoDBJSON(av *dynamodb.AttributeValue) map[string]interface{} {
+	switch {
+	case av.S != nil:
+		return map[string]interface{}{"S": *av.S}
+	case av.N != nil:
+		return map[string]interface{}{"N": *av.N}
+	case av.B != nil:
+		return map[string]interface{}{"B": av.B}
+	case av.BOOL != nil:
+		return map[string]interface{}{"BOOL": *av.BOOL}
+	case av.NULL != nil:
+		return map[string]interface{}{"NULL": *av.NULL}
+	case av.SS != nil:
+		return map[string]interface{}{"SS": aws.StringValueSlice(av.SS)}
+	case av.NS != nil:
+		return map[string]interface{}{"NS": aws.StringValueSlice(av.NS)}
+	case av.BS != nil:
+		return map[string]interface{}{"BS": av.BS}
+	case av.L != nil:
+		list := make([]interface{}, len(av.L))
+		for i, v := range av.L {
+			list[i] = attributeValueToDynamoDBJSON(v)
+		}
+		return map[string]interface{}{"L": list}
+	default:
+		m := make(map[string]interface{}, len(av.M))
+		for k, v := range av.M {
+			m[k] = attributeValueToDynamoDBJSON(v)
+		}
+		return map[string]interface{}{"M": m}
+	}
+}
+
+func attributeValueFromDynamoDBJSON(typed map[string]json.RawMessage) (*dynamodb.AttributeValue, error) {
+	if len(typed) != 1 {
+		return nil, errors.New("attribute value must have exactly one type descriptor")
+	}
+
+	av := &dynamodb.AttributeValue{}
+	for typ, raw := range typed {
+		var err error
+		switch typ {
+		case "S":
+			err = json.Unmarshal(raw, &av.S)
+		case "N":
+			err = json.Unmarshal(raw, &av.N)
+		case "B":
+			err = json.Unmarshal(raw, &av.B)
+		case "BOOL":
+			err = json.Unmarshal(raw, &av.BOOL)
+		case "NULL":
+			err = json.Unmarshal(raw, &av.NULL)
+		case "SS":
+			err = json.Unmarshal(raw, &av.SS)
+		case "NS":
+			err = json.Unmarshal(raw, &av.NS)
+		case "BS":
+			err = json.Unmarshal(raw, &av.BS)
+		case "L":
+			var list []map[string]json.RawMessage
+			if err = json.Unmarshal(raw, &list); err != nil {
+				break
+			}
+			av.L = make([]*dynamodb.AttributeValue, len(list))
+			for i, v := range list {
+				if av.L[i], err = attributeValueFromDynamoDBJSON(v); err != nil {
+					break
+				}
+			}
+		case "M":
+			var m map[string]map[string]json.RawMessage
+			if err = json.Unmarshal(raw, &m); err != nil {
+				break
+			}
+			av.M = make(map[string]*dynamodb.AttributeValue, len(m))
+			for k, v := range m {
+				if av.M[k], err = attributeValueFromDynamoDBJSON(v); err != nil {
+					break
+				}
+			}
+		default:
+			err = fmt.Errorf("unknown type descriptor %q", typ)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return av, nil
+}
+
+// attributeValueToPlainJSON drops the type descriptors. Numbers keep their exact
+// textual form; binary values become base64 strings and sets become arrays, so
+// they are read back as strings and lists.
+func attributeValueToPlainJSON(av *dynamodb.AttributeValue) interface{} {
+	switch {
+	case av.S != nil:
+		return *av.S
+	case av.N != nil:
+		return json.Number(*av.N)
+	case av.B != nil:
+		return base64.StdEncoding.EncodeToString(av.B)
+	case av.BOOL != nil:
+		return *av.BOOL
+	case av.NULL != nil:
+		return nil
+	case av.SS != nil:
+		return aws.StringValueSlice(av.SS)
+	case av.NS != nil:
+		numbers := make([]json.Number, len(av.NS))
+		for i, n := range av.NS {
+			numbers[i] = json.Number(*n)
+		}
+		return numbers
+	case av.BS != nil:
+		encoded := make([]string, len(av.BS))
+		for i, b := range av.BS {
+			encoded[i] = base64.StdEncoding.EncodeToString(b)
+		}
+		return encoded
+	case av.L != nil:
+		list := make([]interface{}, len(av.L))
+		for i, v := range av.L {
+			list[i] = attributeValueToPlainJSON(v)
+		}
+		return list
+	default:
+		m := make(map[string]interface{}, len(av.M))
+		for k, v := range av.M {
+			m[k] = attributeValueToPlainJSON(v)
+		}
+		return m
+	}
+}
+
+func attributeValueFromPlainJSON(value interface{}) (*dynamodb.AttributeValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}, nil
+	case string:
+		return &dynamodb.AttributeValue{S: aws.String(v)}, nil
+	case json.Number:
+		return &dynamodb.AttributeValue{N: aws.String(v.String())}, nil
+	case bool:
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(v)}, nil
+	case []interface{}:
+		list := make([]*dynamodb.AttributeValue, len(v))
+		for i, elem := range v {
+			av, err := attributeValueFromPlainJSON(elem)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = av
+		}
+		return &dynamodb.AttributeValue{L: list}, nil
+	case map[string]interface{}:
+		m := make(map[string]*dynamodb.AttributeValue, len(v))
+		for k, elem := range v {
+			av, err := attributeValueFromPlainJSON(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = av
+		}
+		return &dynamodb.AttributeValue{M: m}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value of type %T", value)
+	}
+}
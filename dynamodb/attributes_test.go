@@ -0,0 +1,76 @@
+package dynamodb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":      {S: aws.String("123")},
+		"count":   {N: aws.String("12345678901234567890")},
+		"active":  {BOOL: aws.Bool(true)},
+		"nothing": {NULL: aws.Bool(true)},
+		"tags":    {SS: aws.StringSlice([]string{"a", "b"})},
+		"blob":    {B: []byte("hi")},
+		"list":    {L: []*dynamodb.AttributeValue{{S: aws.String("x")}, {N: aws.String("1")}}},
+		"nested":  {M: map[string]*dynamodb.AttributeValue{"inner": {S: aws.String("y")}}},
+	}
+}
+
+func TestEncodeDecodeItem(t *testing.T) {
+	t.Run("DynamoDB JSON round trip", func(t *testing.T) {
+		item := sampleItem()
+
+		data, err := encodeItem(item, DumpFormatDynamoDBJSON)
+		assert.NoError(t, err)
+
+		decoded, err := decodeItem(data, DumpFormatDynamoDBJSON)
+		assert.NoError(t, err)
+		assert.Equal(t, item, decoded)
+	})
+
+	t.Run("Plain JSON keeps number precision", func(t *testing.T) {
+		item := map[string]*dynamodb.AttributeValue{
+			"id":    {S: aws.String("123")},
+			"count": {N: aws.String("12345678901234567890")},
+			"list":  {L: []*dynamodb.AttributeValue{{BOOL: aws.Bool(false)}, {NULL: aws.Bool(true)}}},
+		}
+
+		data, err := encodeItem(item, DumpFormatJSON)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"count":12345678901234567890,"id":"123","list":[false,null]}`, string(data))
+
+		decoded, err := decodeItem(data, DumpFormatJSON)
+		assert.NoError(t, err)
+		assert.Equal(t, item, decoded)
+	})
+
+	t.Run("Plain JSON flattens sets and binary", func(t *testing.T) {
+		data, err := encodeItem(map[string]*dynamodb.AttributeValue{
+			"tags": {SS: aws.StringSlice([]string{"a"})},
+			"blob": {B: []byte("hi")},
+		}, DumpFormatJSON)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"blob":"aGk=","tags":["a"]}`, string(data))
+	})
+
+	t.Run("Unknown type descriptor", func(t *testing.T) {
+		_, err := decodeItem([]byte(`{"id":{"X":"1"}}`), DumpFormatDynamoDBJSON)
+		assert.EqualError(t, err, `attribute id: unknown type descriptor "X"`)
+	})
+
+	t.Run("Multiple type descriptors", func(t *testing.T) {
+		_, err := decodeItem([]byte(`{"id":{"S":"1","N":"1"}}`), DumpFormatDynamoDBJSON)
+		assert.EqualError(t, err, "attribute id: attribute value must have exactly one type descriptor")
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		_, err := encodeItem(sampleItem(), "CSV")
+		assert.EqualError(t, err, `unsupported dump format "CSV"`)
+	})
+}
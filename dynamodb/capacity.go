@@ -0,0 +1,247 @@
+package dynamodb
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"go_aws_services/internal/observe"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	capacityRequestHandler = "go_aws_services.CapacityRequest"
+	capacityRecordHandler  = "go_aws_services.CapacityRecord"
+)
+
+// writeOperations consume write capacity. Every other operation consumes read
+// capacity.
+var writeOperations = map[string]bool{
+	"PutItem":            true,
+	"UpdateItem":         true,
+	"DeleteItem":         true,
+	"BatchWriteItem":     true,
+	"TransactWriteItems": true,
+}
+
+// CapacityCollector adds up the capacity consumed by DynamoDB requests per table,
+// index and operation. It is safe for concurrent use.
+type CapacityCollector struct {
+	mu        sync.Mutex
+	totals    map[CapacityKey]CapacityTotals
+	observers []func(usage []CapacityUsage)
+}
+
+// NewCapacityCollector returns an empty collector.
+func NewCapacityCollector() *CapacityCollector {
+	return &CapacityCollector{totals: make(map[CapacityKey]CapacityTotals)}
+}
+
+// OnRecord registers fn to be called with the usage of every recorded request,
+// e.g. to log it together with its estimated cost. fn must not block.
+func (c *CapacityCollector) OnRecord(fn func(usage []CapacityUsage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.observers = append(c.observers, fn)
+}
+
+// Record adds the capacity one request of operation consumed.
+//
+// Parameters:
+//
+//	operation (string): The DynamoDB operation, e.g. "Query".
+//	consumed (...*dynamodb.ConsumedCapacity): The capacity the response reported, one per table.
+//
+// Returns:
+//
+//	[]CapacityUsage: The usage per table and index that was recorded.
+func (c *CapacityCollector) Record(operation string, consumed ...*dynamodb.ConsumedCapacity) []CapacityUsage {
+	var usage []CapacityUsage
+	for _, capacity := range consumed {
+		usage = append(usage, splitCapacity(operation, capacity)...)
+	}
+
+	if len(usage) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	for _, u := range usage {
+		key := CapacityKey{Table: u.Table, Index: u.Index, Operation: u.Operation}
+		totals := c.totals[key]
+		totals.Requests++
+		totals.ReadUnits += u.ReadUnits
+		totals.WriteUnits += u.WriteUnits
+		c.totals[key] = totals
+	}
+	observers := c.observers
+	c.mu.Unlock()
+
+	for _, fn := range observers {
+		fn(usage)
+	}
+
+	return usage
+}
+
+// Totals returns a copy of the capacity consumed so far.
+func (c *CapacityCollector) Totals() map[CapacityKey]CapacityTotals {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	totals := make(map[CapacityKey]CapacityTotals, len(c.totals))
+	for key, t := range c.totals {
+		totals[key] = t
+	}
+
+	return totals
+}
+
+// Usage returns the capacity consumed so far as one CapacityUsage per key,
+// sorted by table, index and operation.
+func (c *CapacityCollector) Usage() []CapacityUsage {
+	totals := c.Totals()
+
+	usage := make([]CapacityUsage, 0, len(totals))
+	for key, t := range totals {
+		usage = append(usage, CapacityUsage{
+			Operation:  key.Operation,
+			Table:      key.Table,
+			Index:      key.Index,
+			ReadUnits:  t.ReadUnits,
+			WriteUnits: t.WriteUnits,
+		})
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		return a.Operation < b.Operation
+	})
+
+	return usage
+}
+
+// Reset clears the totals.
+func (c *CapacityCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.totals = make(map[CapacityKey]CapacityTotals)
+}
+
+// splitCapacity breaks the capacity of one table down into the table and each of
+// its indexes. Responses to ReturnConsumedCapacity TOTAL carry no breakdown and
+// are attributed to the table.
+func splitCapacity(operation string, consumed *dynamodb.ConsumedCapacity) []CapacityUsage {
+	if consumed == nil {
+		return nil
+	}
+
+	table := aws.StringValue(consumed.TableName)
+	usage := func(index string, capacity *dynamodb.Capacity) CapacityUsage {
+		read, write := splitUnits(operation, capacity.CapacityUnits, capacity.ReadCapacityUnits, capacity.WriteCapacityUnits)
+		return CapacityUsage{Operation: operation, Table: table, Index: index, ReadUnits: read, WriteUnits: write}
+	}
+
+	if consumed.Table == nil && len(consumed.GlobalSecondaryIndexes) == 0 && len(consumed.LocalSecondaryIndexes) == 0 {
+		read, write := splitUnits(operation, consumed.CapacityUnits, consumed.ReadCapacityUnits, consumed.WriteCapacityUnits)
+		return []CapacityUsage{{Operation: operation, Table: table, ReadUnits: read, WriteUnits: write}}
+	}
+
+	var result []CapacityUsage
+	if consumed.Table != nil {
+		result = append(result, usage("", consumed.Table))
+	}
+
+	indexes := make([]string, 0, len(consumed.GlobalSecondaryIndexes)+len(consumed.LocalSecondaryIndexes))
+	byName := make(map[string]*dynamodb.Capacity, cap(indexes))
+	for _, m := range []map[string]*dynamodb.Capacity{consumed.GlobalSecondaryIndexes, consumed.LocalSecondaryIndexes} {
+		for name, capacity := range m {
+			if capacity != nil {
+				indexes = append(indexes, name)
+				byName[name] = capacity
+			}
+		}
+	}
+	sort.Strings(indexes)
+
+	for _, name := range indexes {
+		result = append(result, usage(name, byName[name]))
+	}
+
+	return result
+}
+
+// splitUnits uses the read and write units DynamoDB reported separately, and
+// otherwise attributes the total to reads or writes by operation.
+func splitUnits(operation string, total, read, write *float64) (float64, float64) {
+	if read != nil || write != nil {
+		return aws.Float64Value(read), aws.Float64Value(write)
+	}
+
+	if writeOperations[operation] {
+		return 0, aws.Float64Value(total)
+	}
+
+	return aws.Float64Value(total), 0
+}
+
+// WithCapacityCollector makes every request of this client, including those made
+// on its raw client, ask for ReturnConsumedCapacity INDEXES and records what
+// each one consumed in collector. Passing nil stops collecting. It is not safe
+// to call while the client is in use, and returns the client to allow chaining.
+func (d *DynamoDBClient) WithCapacityCollector(collector *CapacityCollector) *DynamoDBClient {
+	handlers := &d.ownHandle("a capacity collector").Handlers
+
+	handlers.Validate.RemoveByName(capacityRequestHandler)
+	handlers.Complete.RemoveByName(capacityRecordHandler)
+	d.capacity = collector
+
+	if collector == nil {
+		return d
+	}
+
+	handlers.Validate.PushBackNamed(request.NamedHandler{
+		Name: capacityRequestHandler,
+		Fn:   requestConsumedCapacity,
+	})
+	handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: capacityRecordHandler,
+		Fn: func(r *request.Request) {
+			if r.Error == nil {
+				collector.Record(r.Operation.Name, observe.ConsumedCapacity(r.Data)...)
+			}
+		},
+	})
+
+	return d
+}
+
+// CapacityCollector returns the collector set with WithCapacityCollector, or nil.
+func (d *DynamoDBClient) CapacityCollector() *CapacityCollector {
+	return d.capacity
+}
+
+// requestConsumedCapacity sets ReturnConsumedCapacity to INDEXES on inputs that
+// have it and leave it unset.
+func requestConsumedCapacity(r *request.Request) {
+	rv := reflect.ValueOf(r.Params)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+
+	f := rv.Elem().FieldByName("ReturnConsumedCapacity")
+	if f.IsValid() && f.CanSet() && f.Type() == reflect.TypeOf((*string)(nil)) && f.IsNil() {
+		f.Set(reflect.ValueOf(aws.String(dynamodb.ReturnConsumedCapacityIndexes)))
+	}
+}
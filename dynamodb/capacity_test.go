@@ -0,0 +1,180 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		consumed  *dynamodb.ConsumedCapacity
+		want      []CapacityUsage
+	}{
+		{
+			name:      "Indexes breakdown",
+			operation: "PutItem",
+			consumed: &dynamodb.ConsumedCapacity{
+				TableName:     aws.String("users"),
+				CapacityUnits: aws.Float64(4),
+				Table:         &dynamodb.Capacity{CapacityUnits: aws.Float64(2), WriteCapacityUnits: aws.Float64(2)},
+				GlobalSecondaryIndexes: map[string]*dynamodb.Capacity{
+					"by-email": {CapacityUnits: aws.Float64(1), WriteCapacityUnits: aws.Float64(1)},
+				},
+				LocalSecondaryIndexes: map[string]*dynamodb.Capacity{
+					"by-date": {CapacityUnits: aws.Float64(1), WriteCapacityUnits: aws.Float64(1)},
+				},
+			},
+			want: []CapacityUsage{
+				{Operation: "PutItem", Table: "users", WriteUnits: 2},
+				{Operation: "PutItem", Table: "users", Index: "by-date", WriteUnits: 1},
+				{Operation: "PutItem", Table: "users", Index: "by-email", WriteUnits: 1},
+			},
+		},
+		{
+			name:      "Query on an index only",
+			operation: "Query",
+			consumed: &dynamodb.ConsumedCapacity{
+				TableName:              aws.String("users"),
+				CapacityUnits:          aws.Float64(0.5),
+				GlobalSecondaryIndexes: map[string]*dynamodb.Capacity{"by-email": {CapacityUnits: aws.Float64(0.5)}},
+			},
+			want: []CapacityUsage{{Operation: "Query", Table: "users", Index: "by-email", ReadUnits: 0.5}},
+		},
+		{
+			name:      "Total read",
+			operation: "GetItem",
+			consumed:  &dynamodb.ConsumedCapacity{TableName: aws.String("users"), CapacityUnits: aws.Float64(1)},
+			want:      []CapacityUsage{{Operation: "GetItem", Table: "users", ReadUnits: 1}},
+		},
+		{
+			name:      "Total write",
+			operation: "DeleteItem",
+			consumed:  &dynamodb.ConsumedCapacity{TableName: aws.String("users"), CapacityUnits: aws.Float64(1)},
+			want:      []CapacityUsage{{Operation: "DeleteItem", Table: "users", WriteUnits: 1}},
+		},
+		{
+			name:      "Transaction reports reads and writes",
+			operation: "TransactWriteItems",
+			consumed: &dynamodb.ConsumedCapacity{
+				TableName:          aws.String("users"),
+				CapacityUnits:      aws.Float64(6),
+				ReadCapacityUnits:  aws.Float64(2),
+				WriteCapacityUnits: aws.Float64(4),
+			},
+			want: []CapacityUsage{{Operation: "TransactWriteItems", Table: "users", ReadUnits: 2, WriteUnits: 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitCapacity(tt.operation, tt.consumed))
+		})
+	}
+}
+
+func TestCapacityCollector(t *testing.T) {
+	collector := NewCapacityCollector()
+
+	var observed [][]CapacityUsage
+	collector.OnRecord(func(usage []CapacityUsage) {
+		observed = append(observed, usage)
+	})
+
+	getItem := &dynamodb.ConsumedCapacity{TableName: aws.String("users"), CapacityUnits: aws.Float64(0.5)}
+	collector.Record("GetItem", getItem)
+	collector.Record("GetItem", getItem)
+	collector.Record("BatchWriteItem",
+		&dynamodb.ConsumedCapacity{TableName: aws.String("users"), CapacityUnits: aws.Float64(3)},
+		&dynamodb.ConsumedCapacity{TableName: aws.String("orders"), CapacityUnits: aws.Float64(2)})
+	assert.Nil(t, collector.Record("Scan"))
+
+	assert.Equal(t, map[CapacityKey]CapacityTotals{
+		{Table: "users", Operation: "GetItem"}:         {Requests: 2, ReadUnits: 1},
+		{Table: "users", Operation: "BatchWriteItem"}:  {Requests: 1, WriteUnits: 3},
+		{Table: "orders", Operation: "BatchWriteItem"}: {Requests: 1, WriteUnits: 2},
+	}, collector.Totals())
+	assert.Equal(t, []CapacityUsage{
+		{Operation: "BatchWriteItem", Table: "orders", WriteUnits: 2},
+		{Operation: "BatchWriteItem", Table: "users", WriteUnits: 3},
+		{Operation: "GetItem", Table: "users", ReadUnits: 1},
+	}, collector.Usage())
+	assert.Len(t, observed, 3)
+
+	collector.Reset()
+	assert.Empty(t, collector.Totals())
+}
+
+func TestCapacityCollectorConcurrent(t *testing.T) {
+	collector := NewCapacityCollector()
+	consumed := &dynamodb.ConsumedCapacity{TableName: aws.String("users"), CapacityUnits: aws.Float64(1)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				collector.Record("GetItem", consumed)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, CapacityTotals{Requests: 1000, ReadUnits: 1000}, collector.Totals()[CapacityKey{Table: "users", Operation: "GetItem"}])
+}
+
+func TestWithCapacityCollector(t *testing.T) {
+	var requested []string
+	respond := func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ ReturnConsumedCapacity string }
+		raw, _ := io.ReadAll(r.Body)
+		json.Unmarshal(raw, &body)
+		requested = append(requested, body.ReturnConsumedCapacity)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Write([]byte(`{"ConsumedCapacity":{"TableName":"test-table","CapacityUnits":1.5,` +
+			`"Table":{"CapacityUnits":1},"GlobalSecondaryIndexes":{"by-name":{"CapacityUnits":0.5}}}}`))
+	}
+	dynamoClient, _ := newServerClient(t, respond)
+	collector := NewCapacityCollector()
+
+	dynamoClient.WithCapacityCollector(collector)
+	assert.Same(t, collector, dynamoClient.CapacityCollector())
+
+	_, err := dynamoClient.PutItem(map[string]interface{}{"id": "123"})
+	require.NoError(t, err)
+
+	// Calls on the raw client are collected too, and keep an explicit setting.
+	_, err = dynamoClient.client.Query(&dynamodb.QueryInput{
+		TableName:              aws.String("test-table"),
+		KeyConditionExpression: aws.String("id = :id"),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{dynamodb.ReturnConsumedCapacityIndexes, dynamodb.ReturnConsumedCapacityTotal}, requested)
+	assert.Equal(t, map[CapacityKey]CapacityTotals{
+		{Table: "test-table", Operation: "PutItem"}:                   {Requests: 1, WriteUnits: 1},
+		{Table: "test-table", Index: "by-name", Operation: "PutItem"}: {Requests: 1, WriteUnits: 0.5},
+		{Table: "test-table", Operation: "Query"}:                     {Requests: 1, ReadUnits: 1},
+		{Table: "test-table", Index: "by-name", Operation: "Query"}:   {Requests: 1, ReadUnits: 0.5},
+	}, collector.Totals())
+
+	dynamoClient.WithCapacityCollector(nil)
+	_, err = dynamoClient.PutItem(map[string]interface{}{"id": "123"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "", requested[2])
+	assert.Nil(t, dynamoClient.CapacityCollector())
+	assert.Equal(t, int64(1), collector.Totals()[CapacityKey{Table: "test-table", Operation: "PutItem"}].Requests)
+}
@@ -22,13 +22,16 @@ func TestInitAwsDynamoDb(t *testing.T) {
 
 	oldAwsSession := getAwsSession
 	oldNewdynamodb := newDynamodb
+	oldDynamoClient := dynamoClient
 
 	getAwsSession = mockSession.GetAWSSession
 	newDynamodb = mockDynamoDB.New
+	dynamoClient = nil
 
 	defer func() {
 		getAwsSession = oldAwsSession
 		newDynamodb = oldNewdynamodb
+		dynamoClient = oldDynamoClient
 	}()
 
 	t.Run("Valid dynamodb", func(t *testing.T) {
@@ -0,0 +1,85 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Prices in us-east-1 at the time of writing, in dollars.
+const (
+	onDemandReadPricePerMillion  = 0.125
+	onDemandWritePricePerMillion = 0.625
+	provisionedRCUHourPrice      = 0.00013
+	provisionedWCUHourPrice      = 0.00065
+)
+
+// OnDemandPricing returns on-demand pricing from the price of a million read
+// request units and a million write request units.
+func OnDemandPricing(readPerMillion float64, writePerMillion float64) Pricing {
+	return Pricing{Mode: BillingModeOnDemand, ReadPrice: readPerMillion / 1e6, WritePrice: writePerMillion / 1e6}
+}
+
+// ProvisionedPricing returns provisioned pricing from the hourly price of one
+// RCU and one WCU.
+func ProvisionedPricing(rcuHour float64, wcuHour float64) Pricing {
+	return Pricing{Mode: BillingModeProvisioned, ReadPrice: rcuHour, WritePrice: wcuHour}
+}
+
+// DefaultOnDemandPricing returns the us-east-1 on-demand prices.
+func DefaultOnDemandPricing() Pricing {
+	return OnDemandPricing(onDemandReadPricePerMillion, onDemandWritePricePerMillion)
+}
+
+// DefaultProvisionedPricing returns the us-east-1 provisioned prices.
+func DefaultProvisionedPricing() Pricing {
+	return ProvisionedPricing(provisionedRCUHourPrice, provisionedWCUHourPrice)
+}
+
+// Validate reports prices that cannot be used.
+func (p Pricing) Validate() error {
+	if p.Mode != BillingModeOnDemand && p.Mode != BillingModeProvisioned {
+		return fmt.Errorf("billing mode must be one of %s or %s", BillingModeOnDemand, BillingModeProvisioned)
+	}
+
+	if p.ReadPrice < 0 || p.WritePrice < 0 {
+		return errors.New("prices cannot be negative")
+	}
+
+	return nil
+}
+
+// Cost estimates what consuming readUnits and writeUnits costs, in dollars.
+// With on-demand billing that is the price of the request units. Provisioned
+// capacity is paid by the hour whether it is used or not, so the estimate is the
+// share of the hourly price one unit-second of capacity costs, which is what the
+// consumption costs on a table provisioned exactly for its load.
+//
+// Parameters:
+//
+//	readUnits (float64): The read capacity or request units consumed.
+//	writeUnits (float64): The write capacity or request units consumed.
+//
+// Returns:
+//
+//	float64: The estimated cost in dollars.
+func (p Pricing) Cost(readUnits float64, writeUnits float64) float64 {
+	cost := readUnits*p.ReadPrice + writeUnits*p.WritePrice
+
+	if p.Mode == BillingModeProvisioned {
+		return cost / time.Hour.Seconds()
+	}
+
+	return cost
+}
+
+// UsageCost estimates what usage costs, in dollars.
+func (p Pricing) UsageCost(usage ...CapacityUsage) float64 {
+	var read, write float64
+	for _, u := range usage {
+		read += u.ReadUnits
+		write += u.WriteUnits
+	}
+
+	return p.Cost(read, write)
+}
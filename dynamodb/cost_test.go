@@ -0,0 +1,43 @@
+package dynamodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPricingCost(t *testing.T) {
+	t.Run("On-demand", func(t *testing.T) {
+		pricing := DefaultOnDemandPricing()
+
+		assert.NoError(t, pricing.Validate())
+		assert.InDelta(t, 0.125, pricing.Cost(1e6, 0), 1e-12)
+		assert.InDelta(t, 0.625, pricing.Cost(0, 1e6), 1e-12)
+		assert.InDelta(t, 0.75, pricing.Cost(1e6, 1e6), 1e-12)
+	})
+
+	t.Run("Provisioned", func(t *testing.T) {
+		pricing := DefaultProvisionedPricing()
+
+		assert.NoError(t, pricing.Validate())
+		// A unit consumed every second for an hour costs the hourly price.
+		assert.InDelta(t, 0.00013, pricing.Cost(3600, 0), 1e-12)
+		assert.InDelta(t, 0.00065, pricing.Cost(0, 3600), 1e-12)
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		pricing := OnDemandPricing(1e6, 2e6)
+
+		cost := pricing.UsageCost(
+			CapacityUsage{Table: "users", ReadUnits: 1},
+			CapacityUsage{Table: "users", Index: "by-email", ReadUnits: 0.5, WriteUnits: 2},
+		)
+
+		assert.InDelta(t, 5.5, cost, 1e-12)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.EqualError(t, Pricing{}.Validate(), "billing mode must be one of PAY_PER_REQUEST or PROVISIONED")
+		assert.EqualError(t, OnDemandPricing(-1, 1).Validate(), "prices cannot be negative")
+	})
+}
@@ -0,0 +1,136 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrDeletionProtected  = errors.New("table has deletion protection enabled")
+	ErrProductionTable    = errors.New("table name matches the production pattern")
+	ErrItemCountExceeded  = errors.New("table item count exceeds the delete threshold")
+	errBackupNotAvailable = errors.New("backup did not become available")
+)
+
+// WithDeletionProtection makes CreateTable and CreateTableAsync create the table
+// with deletion protection enabled. It returns the client to allow chaining.
+func (d *DynamoDBClient) WithDeletionProtection(enabled bool) *DynamoDBClient {
+	d.deletionProtection = enabled
+	return d
+}
+
+// UpdateDeletionProtection turns deletion protection on or off for an existing table.
+func (d *DynamoDBClient) UpdateDeletionProtection(ctx context.Context, enabled bool) (*dynamodb.UpdateTableOutput, error) {
+	output, err := d.client.UpdateTableWithContext(ctx, &dynamodb.UpdateTableInput{
+		TableName:                 aws.String(d.tableName),
+		DeletionProtectionEnabled: aws.Bool(enabled),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.deletionProtection = enabled
+
+	return output, nil
+}
+
+// SafeDeleteTable deletes the table only after every check in guard passes.
+// It refuses to delete when the table name matches guard.ProductionPattern, when
+// deletion protection is enabled or when the table holds more than
+// guard.MaxItemCount items. The item count reported by DynamoDB is refreshed
+// roughly every six hours, so the threshold is approximate.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the checks and the optional backup.
+//	guard (DeleteGuard): The checks to run and whether to back up the table first.
+//
+// Returns:
+//
+//	(*dynamodb.DeleteTableOutput, error): The output from the DeleteTable operation, or the reason the table was not deleted.
+func (d *DynamoDBClient) SafeDeleteTable(ctx context.Context, guard DeleteGuard) (*dynamodb.DeleteTableOutput, error) {
+	if guard.ProductionPattern != "" {
+		pattern, err := regexp.Compile(guard.ProductionPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid production pattern: %v", err)
+		}
+		if pattern.MatchString(d.tableName) {
+			return nil, fmt.Errorf("refusing to delete %s: %w", d.tableName, ErrProductionTable)
+		}
+	}
+
+	table, err := d.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(d.tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.BoolValue(table.Table.DeletionProtectionEnabled) {
+		return nil, fmt.Errorf("refusing to delete %s: %w", d.tableName, ErrDeletionProtected)
+	}
+
+	if guard.MaxItemCount > 0 && aws.Int64Value(table.Table.ItemCount) > guard.MaxItemCount {
+		return nil, fmt.Errorf("refusing to delete %s with %d items: %w", d.tableName, aws.Int64Value(table.Table.ItemCount), ErrItemCountExceeded)
+	}
+
+	if guard.BackupBeforeDelete {
+		if _, err := d.backupTable(ctx, guard.BackupName, guard.Wait); err != nil {
+			return nil, err
+		}
+	}
+
+	return d.DeleteTableWithContext(ctx, guard.Wait)
+}
+
+// backupTable creates an on-demand backup and waits until it is AVAILABLE.
+func (d *DynamoDBClient) backupTable(ctx context.Context, backupName string, opts WaitOptions) (*dynamodb.BackupDetails, error) {
+	if backupName == "" {
+		backupName = fmt.Sprintf("%s-predelete-%d", d.tableName, time.Now().Unix())
+	}
+
+	output, err := d.client.CreateBackupWithContext(ctx, &dynamodb.CreateBackupInput{
+		TableName:  aws.String(d.tableName),
+		BackupName: aws.String(backupName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	details := output.BackupDetails
+
+	err = pollUntil(ctx, opts, func(ctx context.Context) (bool, error) {
+		if aws.StringValue(details.BackupStatus) == dynamodb.BackupStatusAvailable {
+			return true, nil
+		}
+
+		described, err := d.client.DescribeBackupWithContext(ctx, &dynamodb.DescribeBackupInput{
+			BackupArn: details.BackupArn,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		details = described.BackupDescription.BackupDetails
+
+		switch aws.StringValue(details.BackupStatus) {
+		case dynamodb.BackupStatusAvailable:
+			return true, nil
+		case dynamodb.BackupStatusDeleted:
+			return false, fmt.Errorf("%s: %w", backupName, errBackupNotAvailable)
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
@@ -0,0 +1,156 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockDescribeTable(mockClient *mockDynamoDBClient, table *dynamodb.TableDescription) {
+	mockClient.On("DescribeTableWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.DescribeTableInput")).Return(&dynamodb.DescribeTableOutput{Table: table}, nil).Once()
+}
+
+func TestWithDeletionProtection(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+	mockClient.On("CreateTable", mock.MatchedBy(func(input *dynamodb.CreateTableInput) bool {
+		return aws.BoolValue(input.DeletionProtectionEnabled)
+	})).Return(&dynamodb.CreateTableOutput{}, nil)
+
+	output, err := dynamoClient.WithDeletionProtection(true).CreateTableAsync()
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	mockClient.AssertExpectations(t)
+}
+
+func TestUpdateDeletionProtection(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+	mockClient.On("UpdateTableWithContext", mock.Anything, &dynamodb.UpdateTableInput{
+		TableName:                 aws.String("test-table"),
+		DeletionProtectionEnabled: aws.Bool(true),
+	}).Return(&dynamodb.UpdateTableOutput{}, nil)
+
+	output, err := dynamoClient.UpdateDeletionProtection(context.Background(), true)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	assert.True(t, dynamoClient.deletionProtection)
+	mockClient.AssertExpectations(t)
+}
+
+func TestSafeDeleteTable(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+
+	t.Run("Deletes when all guards pass", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("dev-table", keySchemaInput, nil)
+		mockDescribeTable(mockClient, &dynamodb.TableDescription{ItemCount: aws.Int64(10)})
+		mockDeleteTable(mockClient)
+
+		output, err := dynamoClient.SafeDeleteTable(context.Background(), DeleteGuard{
+			ProductionPattern: "^prod-",
+			MaxItemCount:      100,
+		})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, output)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Production table", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("prod-table", keySchemaInput, nil)
+
+		output, err := dynamoClient.SafeDeleteTable(context.Background(), DeleteGuard{ProductionPattern: "^prod-"})
+
+		assert.Nil(t, output)
+		assert.True(t, errors.Is(err, ErrProductionTable))
+		mockClient.AssertNotCalled(t, "DeleteTableWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid production pattern", func(t *testing.T) {
+		dynamoClient, _, _ := mockNewDynamoDBClient("dev-table", keySchemaInput, nil)
+
+		_, err := dynamoClient.SafeDeleteTable(context.Background(), DeleteGuard{ProductionPattern: "("})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid production pattern")
+	})
+
+	t.Run("Deletion protection enabled", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("dev-table", keySchemaInput, nil)
+		mockDescribeTable(mockClient, &dynamodb.TableDescription{DeletionProtectionEnabled: aws.Bool(true)})
+
+		output, err := dynamoClient.SafeDeleteTable(context.Background(), DeleteGuard{})
+
+		assert.Nil(t, output)
+		assert.True(t, errors.Is(err, ErrDeletionProtected))
+		mockClient.AssertNotCalled(t, "DeleteTableWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Item count exceeded", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("dev-table", keySchemaInput, nil)
+		mockDescribeTable(mockClient, &dynamodb.TableDescription{ItemCount: aws.Int64(1000)})
+
+		output, err := dynamoClient.SafeDeleteTable(context.Background(), DeleteGuard{MaxItemCount: 100})
+
+		assert.Nil(t, output)
+		assert.True(t, errors.Is(err, ErrItemCountExceeded))
+		mockClient.AssertNotCalled(t, "DeleteTableWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Backup before delete", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("dev-table", keySchemaInput, nil)
+		mockDescribeTable(mockClient, &dynamodb.TableDescription{})
+		mockClient.On("CreateBackupWithContext", mock.Anything, &dynamodb.CreateBackupInput{
+			TableName:  aws.String("dev-table"),
+			BackupName: aws.String("dev-table-final"),
+		}).Return(&dynamodb.CreateBackupOutput{
+			BackupDetails: &dynamodb.BackupDetails{
+				BackupArn:    aws.String("backup-arn"),
+				BackupStatus: aws.String(dynamodb.BackupStatusCreating),
+			},
+		}, nil)
+		mockClient.On("DescribeBackupWithContext", mock.Anything, &dynamodb.DescribeBackupInput{
+			BackupArn: aws.String("backup-arn"),
+		}).Return(&dynamodb.DescribeBackupOutput{
+			BackupDescription: &dynamodb.BackupDescription{
+				BackupDetails: &dynamodb.BackupDetails{
+					BackupArn:    aws.String("backup-arn"),
+					BackupStatus: aws.String(dynamodb.BackupStatusAvailable),
+				},
+			},
+		}, nil)
+		mockDeleteTable(mockClient)
+
+		output, err := dynamoClient.SafeDeleteTable(context.Background(), DeleteGuard{
+			BackupBeforeDelete: true,
+			BackupName:         "dev-table-final",
+			Wait:               WaitOptions{PollInterval: time.Millisecond},
+		})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, output)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Backup failure keeps the table", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("dev-table", keySchemaInput, nil)
+		mockDescribeTable(mockClient, &dynamodb.TableDescription{})
+		mockClient.On("CreateBackupWithContext", mock.Anything, mock.Anything).Return((*dynamodb.CreateBackupOutput)(nil), errors.New("backup limit"))
+
+		output, err := dynamoClient.SafeDeleteTable(context.Background(), DeleteGuard{BackupBeforeDelete: true})
+
+		assert.Nil(t, output)
+		assert.EqualError(t, err, "backup limit")
+		mockClient.AssertNotCalled(t, "DeleteTableWithContext", mock.Anything, mock.Anything)
+	})
+}
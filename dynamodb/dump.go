@@ -0,0 +1,221 @@
+package dynamodb
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	maxLoadLineSize         = 1024 * 1024
+	maxUnprocessedRetries   = 8
+	unprocessedRetryBackoff = 50 * time.Millisecond
+)
+
+// Dump streams every item of the table to w as NDJSON, one item per line.
+// The table is read with a parallel scan, so lines are not written in key order.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the scan.
+//	w (io.Writer): The destination of the NDJSON stream.
+//	opts (DumpOptions): The number of scan segments, the line format and a progress callback.
+//
+// Returns:
+//
+//	(int64, error): The number of items written, or an error if the scan or the write failed.
+func (d *DynamoDBClient) Dump(ctx context.Context, w io.Writer, opts DumpOptions) (int64, error) {
+	segments := opts.Segments
+	if segments <= 0 {
+		segments = DefaultDumpSegments
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		written int64
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		dumpErr error
+	)
+
+	fail := func(err error) {
+		errOnce.Do(func() {
+			dumpErr = err
+			cancel()
+		})
+	}
+
+	for segment := 0; segment < segments; segment++ {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+
+			input := &dynamodb.ScanInput{
+				TableName: aws.String(d.tableName),
+			}
+			if segments > 1 {
+				input.Segment = aws.Int64(int64(segment))
+				input.TotalSegments = aws.Int64(int64(segments))
+			}
+
+			err := d.client.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+				for _, item := range page.Items {
+					line, err := encodeItem(item, opts.Format)
+					if err != nil {
+						fail(err)
+						return false
+					}
+
+					// Progress is called under the lock so that the callback
+					// runs on one goroutine at a time with increasing counts.
+					mu.Lock()
+					_, err = w.Write(append(line, '\n'))
+					if err == nil {
+						written++
+						if opts.Progress != nil {
+							opts.Progress(written)
+						}
+					}
+					mu.Unlock()
+
+					if err != nil {
+						fail(err)
+						return false
+					}
+				}
+				return true
+			})
+			if err != nil {
+				fail(err)
+			}
+		}(segment)
+	}
+
+	wg.Wait()
+
+	return written, dumpErr
+}
+
+// Load reads an NDJSON stream produced by Dump and writes the items to the table
+// using batched writes. Progress is reported with the number of lines consumed
+// from r so far (including skipped ones), which is a safe Offset to resume from.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the writes.
+//	r (io.Reader): The NDJSON stream to read.
+//	opts (LoadOptions): The line format, resume offset, rate limit and a progress callback.
+//
+// Returns:
+//
+//	(int64, error): The number of lines consumed, or an error if decoding or writing failed.
+func (d *DynamoDBClient) Load(ctx context.Context, r io.Reader, opts LoadOptions) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLoadLineSize)
+
+	limiter := newRateLimiter(opts.ItemsPerSecond)
+
+	var (
+		line     int64
+		consumed int64
+		batch    []*dynamodb.WriteRequest
+	)
+
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := limiter.Wait(ctx, len(batch)); err != nil {
+				return err
+			}
+			if err := d.batchWrite(ctx, batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+
+		consumed = line
+		if opts.Progress != nil {
+			opts.Progress(consumed)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		line++
+		if line <= opts.Offset {
+			continue
+		}
+
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		item, err := decodeItem(data, opts.Format)
+		if err != nil {
+			return consumed, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		batch = append(batch, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{Item: item},
+		})
+
+		if len(batch) == MaxBatchWriteItems {
+			if err := flush(); err != nil {
+				return consumed, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return consumed, err
+	}
+
+	if err := flush(); err != nil {
+		return consumed, err
+	}
+
+	return consumed, nil
+}
+
+// batchWrite writes up to MaxBatchWriteItems requests, retrying unprocessed
+// items with a growing backoff.
+func (d *DynamoDBClient) batchWrite(ctx context.Context, requests []*dynamodb.WriteRequest) error {
+	pending := requests
+	backoff := unprocessedRetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		output, err := d.client.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{
+				d.tableName: pending,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		pending = output.UnprocessedItems[d.tableName]
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if attempt == maxUnprocessedRetries {
+			return fmt.Errorf("%d items still unprocessed after %d retries", len(pending), maxUnprocessedRetries)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
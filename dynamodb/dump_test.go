@@ -0,0 +1,175 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockScanSegments(mockClient *mockDynamoDBClient, pages map[int64][]map[string]*dynamodb.AttributeValue) {
+	mockClient.On("ScanPagesWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.ScanInput"), mock.Anything).Run(func(args mock.Arguments) {
+		input := args.Get(1).(*dynamodb.ScanInput)
+		fn := args.Get(2).(func(*dynamodb.ScanOutput, bool) bool)
+		fn(&dynamodb.ScanOutput{Items: pages[aws.Int64Value(input.Segment)]}, true)
+	}).Return(nil)
+}
+
+func TestDump(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+
+	t.Run("Parallel scan in DynamoDB JSON", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+		mockScanSegments(mockClient, map[int64][]map[string]*dynamodb.AttributeValue{
+			0: {{"id": {S: aws.String("1")}}},
+			1: {{"id": {S: aws.String("2")}, "n": {N: aws.String("10")}}},
+		})
+
+		var buf bytes.Buffer
+		var progress []int64
+		count, err := dynamoClient.Dump(context.Background(), &buf, DumpOptions{
+			Segments: 2,
+			Progress: func(items int64) { progress = append(progress, items) },
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		sort.Strings(lines)
+		assert.Equal(t, []string{`{"id":{"S":"1"}}`, `{"id":{"S":"2"},"n":{"N":"10"}}`}, lines)
+		assert.Equal(t, []int64{1, 2}, progress)
+		mockClient.AssertNumberOfCalls(t, "ScanPagesWithContext", 2)
+	})
+
+	t.Run("Single segment in plain JSON", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+		mockScanSegments(mockClient, map[int64][]map[string]*dynamodb.AttributeValue{
+			0: {{"id": {S: aws.String("1")}, "n": {N: aws.String("10")}}},
+		})
+
+		var buf bytes.Buffer
+		count, err := dynamoClient.Dump(context.Background(), &buf, DumpOptions{Segments: 1, Format: DumpFormatJSON})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		assert.Equal(t, "{\"id\":\"1\",\"n\":10}\n", buf.String())
+	})
+
+	t.Run("Scan error", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+		mockClient.On("ScanPagesWithContext", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("scan failed"))
+
+		count, err := dynamoClient.Dump(context.Background(), &bytes.Buffer{}, DumpOptions{Segments: 1})
+
+		assert.EqualError(t, err, "scan failed")
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("Write error", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+		mockScanSegments(mockClient, map[int64][]map[string]*dynamodb.AttributeValue{
+			0: {{"id": {S: aws.String("1")}}},
+		})
+
+		var progress []int64
+		count, err := dynamoClient.Dump(context.Background(), failingWriter{}, DumpOptions{
+			Segments: 1,
+			Progress: func(items int64) { progress = append(progress, items) },
+		})
+
+		assert.EqualError(t, err, "disk full")
+		assert.Equal(t, int64(0), count)
+		assert.Empty(t, progress)
+	})
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestLoad(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+
+	t.Run("Batches items and reports progress", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		var batches [][]*dynamodb.WriteRequest
+		mockClient.On("BatchWriteItemWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.BatchWriteItemInput")).Run(func(args mock.Arguments) {
+			input := args.Get(1).(*dynamodb.BatchWriteItemInput)
+			batches = append(batches, input.RequestItems["test-table"])
+		}).Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+		var input strings.Builder
+		for i := 0; i < 30; i++ {
+			input.WriteString(`{"id":{"S":"x"}}` + "\n")
+		}
+
+		var progress []int64
+		lines, err := dynamoClient.Load(context.Background(), strings.NewReader(input.String()), LoadOptions{
+			Progress: func(lines int64) { progress = append(progress, lines) },
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(30), lines)
+		assert.Len(t, batches, 2)
+		assert.Len(t, batches[0], MaxBatchWriteItems)
+		assert.Len(t, batches[1], 5)
+		assert.Equal(t, []int64{25, 30}, progress)
+	})
+
+	t.Run("Resumes from offset", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		var written []*dynamodb.WriteRequest
+		mockClient.On("BatchWriteItemWithContext", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			written = append(written, args.Get(1).(*dynamodb.BatchWriteItemInput).RequestItems["test-table"]...)
+		}).Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+		input := "{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"3\"}\n"
+		lines, err := dynamoClient.Load(context.Background(), strings.NewReader(input), LoadOptions{Format: DumpFormatJSON, Offset: 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), lines)
+		assert.Len(t, written, 1)
+		assert.Equal(t, "3", *written[0].PutRequest.Item["id"].S)
+	})
+
+	t.Run("Retries unprocessed items", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		unprocessed := []*dynamodb.WriteRequest{{PutRequest: &dynamodb.PutRequest{Item: map[string]*dynamodb.AttributeValue{"id": {S: aws.String("2")}}}}}
+		mockClient.On("BatchWriteItemWithContext", mock.Anything, mock.Anything).Return(&dynamodb.BatchWriteItemOutput{
+			UnprocessedItems: map[string][]*dynamodb.WriteRequest{"test-table": unprocessed},
+		}, nil).Once()
+		mockClient.On("BatchWriteItemWithContext", mock.Anything, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{"test-table": unprocessed},
+		}).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+
+		lines, err := dynamoClient.Load(context.Background(), strings.NewReader("{\"id\":\"1\"}\n{\"id\":\"2\"}\n"), LoadOptions{Format: DumpFormatJSON})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), lines)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Invalid line", func(t *testing.T) {
+		dynamoClient, _, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		lines, err := dynamoClient.Load(context.Background(), strings.NewReader("not json\n"), LoadOptions{})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "line 1:")
+		assert.Equal(t, int64(0), lines)
+	})
+}
@@ -0,0 +1,330 @@
+package dynamodbtest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Limits of the batch and transaction operations.
+const (
+	maxBatchGetKeys     = 100
+	maxBatchWriteItems  = 25
+	maxTransactionItems = 100
+)
+
+// itemID identifies an item across tables, to find requests for the same item.
+func itemID(t *table, key Item) string {
+	return aws.StringValue(t.description.TableName) + "\x00" + t.storageKey(key)
+}
+
+// tableNames returns the keys of a RequestItems map in order, so batches are
+// processed deterministically.
+func tableNames[V any](requests map[string]V) []string {
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// BatchGetItem reads up to 100 items from one or more tables. Every key is
+// processed, so UnprocessedKeys is always empty.
+func (f *Fake) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	total := 0
+	for _, keys := range input.RequestItems {
+		total += len(keys.Keys)
+	}
+	if total == 0 || total > maxBatchGetKeys {
+		return nil, validationError("Too many items requested for the BatchGetItem call: the batch must read between 1 and %d items", maxBatchGetKeys)
+	}
+
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+
+	seen := map[string]bool{}
+	for _, name := range tableNames(input.RequestItems) {
+		keys := input.RequestItems[name]
+		items := []Item{}
+
+		for _, key := range keys.Keys {
+			item, err := f.get(aws.String(name), key, keys.ProjectionExpression, keys.ExpressionAttributeNames, keys.AttributesToGet != nil)
+			if err != nil {
+				return nil, err
+			}
+
+			id := itemID(f.tables[name], key)
+			if seen[id] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[id] = true
+
+			if item != nil {
+				items = append(items, item)
+			}
+		}
+
+		output.Responses[name] = items
+	}
+
+	return output, nil
+}
+
+func (f *Fake) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput, _ ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.BatchGetItem(input)
+}
+
+// BatchWriteItem puts or deletes up to 25 items in one or more tables. The whole
+// batch is validated before any item is written, and every item is processed,
+// so UnprocessedItems is always empty.
+func (f *Fake) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	total := 0
+	for _, requests := range input.RequestItems {
+		total += len(requests)
+	}
+	if total == 0 || total > maxBatchWriteItems {
+		return nil, validationError("Too many items requested for the BatchWriteItem call: the batch must write between 1 and %d items", maxBatchWriteItems)
+	}
+
+	var changes []*change
+	seen := map[string]bool{}
+
+	for _, name := range tableNames(input.RequestItems) {
+		for _, w := range input.RequestItems[name] {
+			var c *change
+			var err error
+
+			switch {
+			case w.PutRequest != nil && w.DeleteRequest == nil:
+				c, err = f.preparePut(&dynamodb.PutItemInput{TableName: aws.String(name), Item: w.PutRequest.Item})
+			case w.DeleteRequest != nil && w.PutRequest == nil:
+				c, err = f.prepareDelete(&dynamodb.DeleteItemInput{TableName: aws.String(name), Key: w.DeleteRequest.Key})
+			default:
+				err = validationError("A WriteRequest must contain exactly one of PutRequest or DeleteRequest")
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			id := itemID(c.table, c.key)
+			if seen[id] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[id] = true
+
+			changes = append(changes, c)
+		}
+	}
+
+	for _, c := range changes {
+		c.apply()
+	}
+
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}, nil
+}
+
+func (f *Fake) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, _ ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.BatchWriteItem(input)
+}
+
+// TransactGetItems reads up to 100 items from one or more tables in a single
+// consistent snapshot.
+func (f *Fake) TransactGetItems(input *dynamodb.TransactGetItemsInput) (*dynamodb.TransactGetItemsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(input.TransactItems) == 0 || len(input.TransactItems) > maxTransactionItems {
+		return nil, validationError("Member must have length between 1 and %d: TransactItems", maxTransactionItems)
+	}
+
+	output := &dynamodb.TransactGetItemsOutput{}
+	for _, ti := range input.TransactItems {
+		if ti.Get == nil {
+			return nil, validationError("Member must not be null: TransactItems.Get")
+		}
+
+		item, err := f.get(ti.Get.TableName, ti.Get.Key, ti.Get.ProjectionExpression, ti.Get.ExpressionAttributeNames, false)
+		if err != nil {
+			return nil, err
+		}
+
+		output.Responses = append(output.Responses, &dynamodb.ItemResponse{Item: item})
+	}
+
+	return output, nil
+}
+
+func (f *Fake) TransactGetItemsWithContext(ctx aws.Context, input *dynamodb.TransactGetItemsInput, _ ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.TransactGetItems(input)
+}
+
+// TransactWriteItems applies up to 100 puts, updates, deletes and condition
+// checks atomically: if any condition fails, none of them is applied and a
+// TransactionCanceledException lists the reason for each item.
+func (f *Fake) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(input.TransactItems) == 0 || len(input.TransactItems) > maxTransactionItems {
+		return nil, validationError("Member must have length between 1 and %d: TransactItems", maxTransactionItems)
+	}
+
+	changes := make([]*change, 0, len(input.TransactItems))
+	seen := map[string]bool{}
+	failed := false
+
+	for _, ti := range input.TransactItems {
+		c, err := f.prepareTransactWrite(ti)
+		if err != nil {
+			return nil, err
+		}
+
+		id := itemID(c.table, c.key)
+		if seen[id] {
+			return nil, validationError("Transaction request cannot include multiple operations on one item")
+		}
+		seen[id] = true
+
+		changes = append(changes, c)
+		failed = failed || c.failed
+	}
+
+	if failed {
+		return nil, transactionCanceled(changes)
+	}
+
+	for _, c := range changes {
+		c.apply()
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (f *Fake) TransactWriteItemsWithContext(ctx aws.Context, input *dynamodb.TransactWriteItemsInput, _ ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.TransactWriteItems(input)
+}
+
+// prepareTransactWrite works out the change of one item of a transaction,
+// which must hold exactly one action.
+func (f *Fake) prepareTransactWrite(ti *dynamodb.TransactWriteItem) (*change, error) {
+	actions := 0
+	for _, set := range []bool{ti.ConditionCheck != nil, ti.Put != nil, ti.Update != nil, ti.Delete != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return nil, validationError("TransactItems can only contain one of Check, Put, Update or Delete")
+	}
+
+	switch {
+	case ti.Put != nil:
+		return f.preparePut(&dynamodb.PutItemInput{
+			TableName:                           ti.Put.TableName,
+			Item:                                ti.Put.Item,
+			ConditionExpression:                 ti.Put.ConditionExpression,
+			ExpressionAttributeNames:            ti.Put.ExpressionAttributeNames,
+			ExpressionAttributeValues:           ti.Put.ExpressionAttributeValues,
+			ReturnValuesOnConditionCheckFailure: ti.Put.ReturnValuesOnConditionCheckFailure,
+		})
+	case ti.Update != nil:
+		c, _, err := f.prepareUpdate(&dynamodb.UpdateItemInput{
+			TableName:                           ti.Update.TableName,
+			Key:                                 ti.Update.Key,
+			UpdateExpression:                    ti.Update.UpdateExpression,
+			ConditionExpression:                 ti.Update.ConditionExpression,
+			ExpressionAttributeNames:            ti.Update.ExpressionAttributeNames,
+			ExpressionAttributeValues:           ti.Update.ExpressionAttributeValues,
+			ReturnValuesOnConditionCheckFailure: ti.Update.ReturnValuesOnConditionCheckFailure,
+		})
+		return c, err
+	case ti.Delete != nil:
+		return f.prepareDelete(&dynamodb.DeleteItemInput{
+			TableName:                           ti.Delete.TableName,
+			Key:                                 ti.Delete.Key,
+			ConditionExpression:                 ti.Delete.ConditionExpression,
+			ExpressionAttributeNames:            ti.Delete.ExpressionAttributeNames,
+			ExpressionAttributeValues:           ti.Delete.ExpressionAttributeValues,
+			ReturnValuesOnConditionCheckFailure: ti.Delete.ReturnValuesOnConditionCheckFailure,
+		})
+	}
+
+	check := ti.ConditionCheck
+	if check.ConditionExpression == nil {
+		return nil, validationError("The ConditionExpression of a ConditionCheck must be specified")
+	}
+
+	// A condition check is a delete that is never applied.
+	c, err := f.prepareDelete(&dynamodb.DeleteItemInput{
+		TableName:                           check.TableName,
+		Key:                                 check.Key,
+		ConditionExpression:                 check.ConditionExpression,
+		ExpressionAttributeNames:            check.ExpressionAttributeNames,
+		ExpressionAttributeValues:           check.ExpressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: check.ReturnValuesOnConditionCheckFailure,
+	})
+	if c != nil {
+		c.remove = false
+	}
+
+	return c, err
+}
+
+func transactionCanceled(changes []*change) error {
+	reasons := make([]*dynamodb.CancellationReason, len(changes))
+	codes := make([]string, len(changes))
+
+	for i, c := range changes {
+		if !c.failed {
+			reasons[i] = &dynamodb.CancellationReason{Code: aws.String("None")}
+			codes[i] = "None"
+			continue
+		}
+
+		reasons[i] = &dynamodb.CancellationReason{
+			Code:    aws.String("ConditionalCheckFailed"),
+			Message: aws.String("The conditional request failed"),
+		}
+		if c.returnOld && c.old != nil {
+			reasons[i].Item = cloneItem(c.old)
+		}
+		codes[i] = "ConditionalCheckFailed"
+	}
+
+	return &dynamodb.TransactionCanceledException{
+		RespMetadata:        protocol.ResponseMetadata{StatusCode: http.StatusBadRequest},
+		Message_:            aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons [" + strings.Join(codes, ", ") + "]"),
+		CancellationReasons: reasons,
+	}
+}
@@ -0,0 +1,136 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tables "go_aws_services/dynamodb"
+)
+
+var fastWait = tables.WaitOptions{PollInterval: time.Millisecond, MaxWait: time.Second}
+
+func newUsersClient(t *testing.T, f *Fake) *tables.DynamoDBClient {
+	t.Helper()
+
+	client, err := tables.NewDynamoDBClientWithAPI(f, "users",
+		tables.KeySchemaInput{HashKey: "id", ReadCapacityUnits: 5, WriteCapacityUnits: 5},
+		[]*tables.GsiKeySchemaInput{{
+			KeySchemaInput: tables.KeySchemaInput{HashKey: "team", RangeKey: "name", ReadCapacityUnits: 5, WriteCapacityUnits: 5},
+			IndexName:      "by-team",
+			ProjectionType: dynamodb.ProjectionTypeAll,
+		}},
+	)
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestDynamoDBClient(t *testing.T) {
+	f := New()
+	client := newUsersClient(t, f)
+	ctx := context.Background()
+
+	_, err := client.CreateTableWithContext(ctx, fastWait)
+	require.NoError(t, err)
+
+	for _, user := range []map[string]interface{}{
+		{"id": "1", "team": "core", "name": "carol", "age": 41},
+		{"id": "2", "team": "core", "name": "alice", "age": 29},
+		{"id": "3", "team": "web", "name": "bob", "age": 35},
+	} {
+		_, err := client.PutItem(user)
+		require.NoError(t, err)
+	}
+
+	got, err := client.GetItem(map[string]interface{}{"id": "3"})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", aws.StringValue(got.Item["name"].S))
+	assert.Equal(t, "35", aws.StringValue(got.Item["age"].N))
+
+	queried, err := client.QueryItem(map[string]interface{}{"team": "core"}, "by-team")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "carol"}, values(queried.Items, "name"))
+
+	_, err = client.DeleteItem(map[string]interface{}{"id": "2"})
+	require.NoError(t, err)
+
+	got, err = client.GetItem(map[string]interface{}{"id": "2"})
+	require.NoError(t, err)
+	assert.Nil(t, got.Item)
+
+	var dump bytes.Buffer
+	dumped, err := client.Dump(ctx, &dump, tables.DumpOptions{Segments: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), dumped)
+
+	_, err = client.DeleteTableWithContext(ctx, fastWait)
+	require.NoError(t, err)
+	_, err = client.GetItem(map[string]interface{}{"id": "1"})
+	assertCode(t, err, dynamodb.ErrCodeResourceNotFoundException)
+
+	// Loading the dump into a new table restores its items.
+	_, err = client.CreateTableWithContext(ctx, fastWait)
+	require.NoError(t, err)
+
+	loaded, err := client.Load(ctx, &dump, tables.LoadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), loaded)
+
+	scanned, err := f.Scan(&dynamodb.ScanInput{TableName: aws.String("users")})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "3"}, values(scanned.Items, "id"))
+}
+
+func TestDynamoDBClientDeletionProtection(t *testing.T) {
+	f := New()
+	client := newUsersClient(t, f).WithDeletionProtection(true)
+	ctx := context.Background()
+
+	_, err := client.CreateTableWithContext(ctx, fastWait)
+	require.NoError(t, err)
+
+	_, err = client.SafeDeleteTable(ctx, tables.DeleteGuard{Wait: fastWait})
+	assert.ErrorIs(t, err, tables.ErrDeletionProtected)
+
+	_, err = client.UpdateDeletionProtection(ctx, false)
+	require.NoError(t, err)
+
+	_, err = client.PutItem(map[string]interface{}{"id": "1", "team": "core", "name": "carol"})
+	require.NoError(t, err)
+
+	_, err = client.SafeDeleteTable(ctx, tables.DeleteGuard{Wait: fastWait})
+	require.NoError(t, err)
+
+	_, err = f.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("users")})
+	assertCode(t, err, dynamodb.ErrCodeResourceNotFoundException)
+}
+
+func TestDynamoDBClientEncryption(t *testing.T) {
+	f := New()
+	provider, err := tables.NewStaticKeyProvider("local", bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	client := newUsersClient(t, f).WithEncryption(provider, "ssn")
+	_, err = client.CreateTableWithContext(context.Background(), fastWait)
+	require.NoError(t, err)
+
+	_, err = client.PutItem(map[string]interface{}{"id": "1", "team": "core", "name": "carol", "ssn": "123-45-6789"})
+	require.NoError(t, err)
+
+	// The table holds the ciphertext; the client reads the plaintext.
+	stored, err := f.GetItem(&dynamodb.GetItemInput{TableName: aws.String("users"), Key: Item{"id": s("1")}})
+	require.NoError(t, err)
+	assert.Nil(t, stored.Item["ssn"].S)
+	assert.NotEmpty(t, stored.Item["ssn"].B)
+
+	got, err := client.GetItem(map[string]interface{}{"id": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "123-45-6789", aws.StringValue(got.Item["ssn"].S))
+}
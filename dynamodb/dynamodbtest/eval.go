@@ -0,0 +1,489 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// resolve returns the value at p in item, or nil if there is none.
+func resolve(item Item, p path) *dynamodb.AttributeValue {
+	current := &dynamodb.AttributeValue{M: item}
+
+	for _, e := range p {
+		switch {
+		case current == nil:
+			return nil
+		case e.index >= 0:
+			if current.L == nil || e.index >= len(current.L) {
+				return nil
+			}
+			current = current.L[e.index]
+		default:
+			if current.M == nil {
+				return nil
+			}
+			current = current.M[e.name]
+		}
+	}
+
+	return current
+}
+
+// evaluate returns the value of o for item, or nil if it refers to a missing
+// attribute.
+func evaluate(item Item, o operand) (*dynamodb.AttributeValue, error) {
+	switch o := o.(type) {
+	case *pathOperand:
+		return resolve(item, o.path), nil
+	case *valueOperand:
+		return o.value, nil
+	case *sizeOperand:
+		return size(resolve(item, o.path)), nil
+	case *ifNotExistsOperand:
+		if v := resolve(item, o.path); v != nil {
+			return v, nil
+		}
+		return evaluate(item, o.fallback)
+	case *listAppendOperand:
+		first, err := evaluate(item, o.first)
+		if err != nil {
+			return nil, err
+		}
+		second, err := evaluate(item, o.second)
+		if err != nil {
+			return nil, err
+		}
+		if typeOf(first) != typeL || typeOf(second) != typeL {
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		}
+		return &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, first.L...), second.L...)}, nil
+	case *arithmeticOperand:
+		left, err := evaluate(item, o.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := evaluate(item, o.right)
+		if err != nil {
+			return nil, err
+		}
+		if left == nil || right == nil {
+			return nil, validationError("The provided expression refers to an attribute that does not exist in the item")
+		}
+		if typeOf(left) != typeN || typeOf(right) != typeN {
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		}
+		l, _ := parseNumber(*left.N)
+		r, _ := parseNumber(*right.N)
+		if o.op == "+" {
+			return &dynamodb.AttributeValue{N: aws.String(formatNumber(l.Add(l, r)))}, nil
+		}
+		return &dynamodb.AttributeValue{N: aws.String(formatNumber(l.Sub(l, r)))}, nil
+	}
+
+	return nil, nil
+}
+
+// size implements the size function: the length of a string or binary and the
+// number of elements of a set, list or map. Other values have no size.
+func size(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	var n int
+	switch typeOf(av) {
+	case typeS:
+		n = utf8.RuneCountInString(*av.S)
+	case typeB:
+		n = len(av.B)
+	case typeSS, typeNS, typeBS:
+		n = len(setMembers(av))
+	case typeL:
+		n = len(av.L)
+	case typeM:
+		n = len(av.M)
+	default:
+		return nil
+	}
+
+	return &dynamodb.AttributeValue{N: aws.String(big.NewInt(int64(n)).String())}
+}
+
+// matches reports whether item satisfies c. A nil condition always holds.
+func matches(item Item, c condition) (bool, error) {
+	switch c := c.(type) {
+	case nil:
+		return true, nil
+	case *andCondition:
+		ok, err := matches(item, c.left)
+		if err != nil || !ok {
+			return false, err
+		}
+		return matches(item, c.right)
+	case *orCondition:
+		ok, err := matches(item, c.left)
+		if err != nil || ok {
+			return ok, err
+		}
+		return matches(item, c.right)
+	case *notCondition:
+		ok, err := matches(item, c.condition)
+		return !ok, err
+	case *comparison:
+		left, err := evaluate(item, c.left)
+		if err != nil {
+			return false, err
+		}
+		right, err := evaluate(item, c.right)
+		if err != nil {
+			return false, err
+		}
+		return compareWith(c.op, left, right), nil
+	case *betweenCondition:
+		value, err := evaluate(item, c.value)
+		if err != nil {
+			return false, err
+		}
+		low, err := evaluate(item, c.low)
+		if err != nil {
+			return false, err
+		}
+		high, err := evaluate(item, c.high)
+		if err != nil {
+			return false, err
+		}
+		if order, ok := compare(low, high); ok && order > 0 {
+			return false, validationError("Invalid expression: The BETWEEN operator requires upper bound to be greater than or equal to lower bound")
+		}
+		return compareWith(">=", value, low) && compareWith("<=", value, high), nil
+	case *inCondition:
+		value, err := evaluate(item, c.value)
+		if err != nil {
+			return false, err
+		}
+		for _, o := range c.list {
+			candidate, err := evaluate(item, o)
+			if err != nil {
+				return false, err
+			}
+			if equal(value, candidate) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *functionCondition:
+		return call(item, c)
+	}
+
+	return false, nil
+}
+
+// compareWith applies a comparator. Missing values and values of different
+// types are never equal and cannot be ordered, so <> holds for them.
+func compareWith(op string, left, right *dynamodb.AttributeValue) bool {
+	switch op {
+	case "=":
+		return equal(left, right)
+	case "<>":
+		return !equal(left, right)
+	}
+
+	order, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+
+	switch op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+
+	return false
+}
+
+func call(item Item, f *functionCondition) (bool, error) {
+	target := resolve(item, f.args[0].(*pathOperand).path)
+
+	var arg *dynamodb.AttributeValue
+	if len(f.args) > 1 {
+		var err error
+		if arg, err = evaluate(item, f.args[1]); err != nil {
+			return false, err
+		}
+	}
+
+	switch f.name {
+	case "attribute_exists":
+		return target != nil, nil
+	case "attribute_not_exists":
+		return target == nil, nil
+	case "attribute_type":
+		if typeOf(arg) != typeS {
+			return false, validationError("Invalid ConditionExpression: Incorrect operand type for operator or function; operator or function: attribute_type, operand type: %s", typeOf(arg))
+		}
+		return target != nil && typeOf(target) == *arg.S, nil
+	case "begins_with":
+		switch {
+		case typeOf(target) == typeS && typeOf(arg) == typeS:
+			return strings.HasPrefix(*target.S, *arg.S), nil
+		case typeOf(target) == typeB && typeOf(arg) == typeB:
+			return bytes.HasPrefix(target.B, arg.B), nil
+		}
+		return false, nil
+	case "contains":
+		switch typeOf(target) {
+		case typeS:
+			return typeOf(arg) == typeS && strings.Contains(*target.S, *arg.S), nil
+		case typeB:
+			return typeOf(arg) == typeB && bytes.Contains(target.B, arg.B), nil
+		case typeSS, typeNS, typeBS:
+			for _, m := range setMembers(target) {
+				if equal(m, arg) {
+					return true, nil
+				}
+			}
+		case typeL:
+			for _, v := range target.L {
+				if equal(v, arg) {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// apply runs u against a copy of item and returns the updated copy. Every value
+// is computed from item as it was before the update, as DynamoDB does.
+func apply(item Item, u *update) (Item, error) {
+	updated := cloneItem(item)
+
+	for _, a := range u.set {
+		value, err := evaluate(item, a.value)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, validationError("The provided expression refers to an attribute that does not exist in the item")
+		}
+		if err := assign(updated, a.path, clone(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Removing list elements from the last to the first keeps the indexes of
+	// the elements still to be removed valid.
+	removals := append([]path{}, u.remove...)
+	sort.SliceStable(removals, func(i, j int) bool {
+		a, b := removals[i], removals[j]
+		return a[len(a)-1].index > b[len(b)-1].index
+	})
+	for _, p := range removals {
+		unassign(updated, p)
+	}
+
+	for _, a := range u.add {
+		value := a.value.(*valueOperand).value
+		current := resolve(item, a.path)
+
+		switch {
+		case typeOf(value) == typeN && current == nil:
+			if err := assign(updated, a.path, clone(value)); err != nil {
+				return nil, err
+			}
+		case typeOf(value) == typeN && typeOf(current) == typeN:
+			l, _ := parseNumber(*current.N)
+			r, _ := parseNumber(*value.N)
+			if err := assign(updated, a.path, &dynamodb.AttributeValue{N: aws.String(formatNumber(l.Add(l, r)))}); err != nil {
+				return nil, err
+			}
+		case isSet(value) && current == nil:
+			if err := assign(updated, a.path, clone(value)); err != nil {
+				return nil, err
+			}
+		case isSet(value) && typeOf(current) == typeOf(value):
+			if err := assign(updated, a.path, union(current, value)); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		}
+	}
+
+	for _, a := range u.delete {
+		value := a.value.(*valueOperand).value
+		current := resolve(item, a.path)
+
+		switch {
+		case !isSet(value):
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		case current == nil:
+		case typeOf(current) != typeOf(value):
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		default:
+			if remaining := difference(current, value); remaining != nil {
+				if err := assign(updated, a.path, remaining); err != nil {
+					return nil, err
+				}
+			} else {
+				unassign(updated, a.path)
+			}
+		}
+	}
+
+	return updated, nil
+}
+
+func isSet(av *dynamodb.AttributeValue) bool {
+	switch typeOf(av) {
+	case typeSS, typeNS, typeBS:
+		return true
+	}
+
+	return false
+}
+
+func union(a, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	members := setMembers(clone(a))
+	seen := make(map[string]bool, len(members))
+	for _, m := range members {
+		seen[encode(m)] = true
+	}
+
+	for _, m := range setMembers(clone(b)) {
+		if !seen[encode(m)] {
+			seen[encode(m)] = true
+			members = append(members, m)
+		}
+	}
+
+	return newSet(typeOf(a), members)
+}
+
+// difference returns the members of a that are not in b, or nil if none are
+// left, since DynamoDB has no empty sets.
+func difference(a, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	remove := make(map[string]bool)
+	for _, m := range setMembers(b) {
+		remove[encode(m)] = true
+	}
+
+	var members []*dynamodb.AttributeValue
+	for _, m := range setMembers(clone(a)) {
+		if !remove[encode(m)] {
+			members = append(members, m)
+		}
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	return newSet(typeOf(a), members)
+}
+
+// assign sets the value at p. The parent of p must exist. Assigning past the end
+// of a list appends to it.
+func assign(item Item, p path, value *dynamodb.AttributeValue) error {
+	parent := &dynamodb.AttributeValue{M: item}
+	if len(p) > 1 {
+		parent = resolve(item, p[:len(p)-1])
+	}
+
+	last := p[len(p)-1]
+	switch {
+	case last.index >= 0 && parent != nil && parent.L != nil:
+		if last.index >= len(parent.L) {
+			parent.L = append(parent.L, value)
+		} else {
+			parent.L[last.index] = value
+		}
+		return nil
+	case last.index < 0 && parent != nil && parent.M != nil:
+		parent.M[last.name] = value
+		return nil
+	}
+
+	return validationError("The document path provided in the update expression is invalid for update")
+}
+
+// unassign removes the value at p, if there is one.
+func unassign(item Item, p path) {
+	if len(p) == 1 {
+		delete(item, p[0].name)
+		return
+	}
+
+	parent := resolve(item, p[:len(p)-1])
+	last := p[len(p)-1]
+
+	switch {
+	case parent == nil:
+	case last.index >= 0 && last.index < len(parent.L):
+		parent.L = append(parent.L[:last.index], parent.L[last.index+1:]...)
+	case last.index < 0 && parent.M != nil:
+		delete(parent.M, last.name)
+	}
+}
+
+// project returns the attributes of item at paths, keeping their nesting. No
+// paths returns the whole item.
+func project(item Item, paths []path) Item {
+	if len(paths) == 0 {
+		return cloneItem(item)
+	}
+
+	projected := Item{}
+	for _, p := range paths {
+		value := resolve(item, p)
+		if value == nil {
+			continue
+		}
+
+		// Build the containers down to p, then place the value. Elements picked
+		// out of a list are appended in the order they are projected.
+		current := &dynamodb.AttributeValue{M: projected}
+		for i, e := range p {
+			last := i == len(p)-1
+			var next *dynamodb.AttributeValue
+
+			if e.index >= 0 {
+				if last {
+					current.L = append(current.L, clone(value))
+					break
+				}
+				next = &dynamodb.AttributeValue{}
+				current.L = append(current.L, next)
+			} else {
+				if last {
+					current.M[e.name] = clone(value)
+					break
+				}
+				if next = current.M[e.name]; next == nil {
+					next = &dynamodb.AttributeValue{}
+					current.M[e.name] = next
+				}
+			}
+
+			if p[i+1].index >= 0 {
+				if next.L == nil {
+					next.L = []*dynamodb.AttributeValue{}
+				}
+			} else if next.M == nil {
+				next.M = Item{}
+			}
+			current = next
+		}
+	}
+
+	return projected
+}
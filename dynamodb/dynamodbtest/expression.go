@@ -0,0 +1,751 @@
+package dynamodbtest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// pathElement is one step of a document path: an attribute or map key, or a list
+// index when index is not negative.
+type pathElement struct {
+	name  string
+	index int
+}
+
+// path is a document path such as a.b[2].c, with its placeholders resolved.
+type path []pathElement
+
+func (p path) String() string {
+	var b strings.Builder
+	for i, e := range p {
+		switch {
+		case e.index >= 0:
+			b.WriteString("[" + strconv.Itoa(e.index) + "]")
+		case i > 0:
+			b.WriteString("." + e.name)
+		default:
+			b.WriteString(e.name)
+		}
+	}
+
+	return b.String()
+}
+
+// overlaps reports whether one path is the other or one of its ancestors.
+func (p path) overlaps(other path) bool {
+	n := min(len(p), len(other))
+	for i := 0; i < n; i++ {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// operand is something a condition or update compares or assigns: a path, a
+// value placeholder or a function of them.
+type operand interface{}
+
+type pathOperand struct{ path path }
+
+type valueOperand struct{ value *dynamodb.AttributeValue }
+
+type sizeOperand struct{ path path }
+
+type ifNotExistsOperand struct {
+	path     path
+	fallback operand
+}
+
+type listAppendOperand struct{ first, second operand }
+
+type arithmeticOperand struct {
+	op          string
+	left, right operand
+}
+
+// condition is a parsed condition, filter or key condition expression.
+type condition interface{}
+
+type comparison struct {
+	op          string
+	left, right operand
+}
+
+type betweenCondition struct{ value, low, high operand }
+
+type inCondition struct {
+	value operand
+	list  []operand
+}
+
+type functionCondition struct {
+	name string
+	args []operand
+}
+
+type andCondition struct{ left, right condition }
+
+type orCondition struct{ left, right condition }
+
+type notCondition struct{ condition condition }
+
+// update is a parsed update expression.
+type update struct {
+	set    []setAction
+	remove []path
+	add    []setAction
+	delete []setAction
+}
+
+type setAction struct {
+	path  path
+	value operand
+}
+
+// paths returns every path the update writes.
+func (u *update) paths() []path {
+	var paths []path
+	for _, a := range u.set {
+		paths = append(paths, a.path)
+	}
+	paths = append(paths, u.remove...)
+	for _, a := range u.add {
+		paths = append(paths, a.path)
+	}
+	for _, a := range u.delete {
+		paths = append(paths, a.path)
+	}
+
+	return paths
+}
+
+// expressions resolves the placeholders of the expressions of one request and
+// tracks which of them were used, since DynamoDB rejects unused ones.
+type expressions struct {
+	names      map[string]*string
+	values     map[string]*dynamodb.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+func newExpressions(names map[string]*string, values map[string]*dynamodb.AttributeValue) (*expressions, error) {
+	for _, value := range values {
+		if err := validateValue(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return &expressions{names: names, values: values, usedNames: map[string]bool{}, usedValues: map[string]bool{}}, nil
+}
+
+// checkUnused reports placeholders that none of the expressions used.
+func (e *expressions) checkUnused() error {
+	if unused := unusedKeys(e.names, e.usedNames); len(unused) > 0 {
+		return validationError("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", strings.Join(unused, ", "))
+	}
+
+	if unused := unusedKeys(e.values, e.usedValues); len(unused) > 0 {
+		return validationError("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", strings.Join(unused, ", "))
+	}
+
+	return nil
+}
+
+func unusedKeys[V any](m map[string]V, used map[string]bool) []string {
+	var unused []string
+	for k := range m {
+		if !used[k] {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+
+	return unused
+}
+
+// parseCondition parses the condition expression s of the request parameter
+// named kind, e.g. "FilterExpression". A nil s parses to a nil condition.
+func (e *expressions) parseCondition(kind string, s *string) (condition, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	p, err := e.newParser(kind, *s)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, p.end()
+}
+
+// parseUpdate parses an update expression. A nil s parses to a nil update.
+func (e *expressions) parseUpdate(s *string) (*update, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	p, err := e.newParser("UpdateExpression", *s)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &update{}
+	seen := map[string]bool{}
+
+	for !p.done() {
+		clause := strings.ToUpper(p.peek().text)
+		if p.peek().kind != tokenName || seen[clause] {
+			return nil, p.syntaxError()
+		}
+		seen[clause] = true
+		p.next()
+
+		for {
+			switch clause {
+			case "SET":
+				a, err := p.setAction()
+				if err != nil {
+					return nil, err
+				}
+				u.set = append(u.set, a)
+			case "REMOVE":
+				path, err := p.path()
+				if err != nil {
+					return nil, err
+				}
+				u.remove = append(u.remove, path)
+			case "ADD", "DELETE":
+				path, err := p.path()
+				if err != nil {
+					return nil, err
+				}
+				value, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				a := setAction{path: path, value: value}
+				if clause == "ADD" {
+					u.add = append(u.add, a)
+				} else {
+					u.delete = append(u.delete, a)
+				}
+			default:
+				return nil, p.syntaxError()
+			}
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil, validationError("Invalid UpdateExpression: The expression can not be empty;")
+	}
+
+	paths := u.paths()
+	for i := range paths {
+		for j := i + 1; j < len(paths); j++ {
+			if paths[i].overlaps(paths[j]) {
+				return nil, validationError("Invalid UpdateExpression: Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]", paths[i], paths[j])
+			}
+		}
+	}
+
+	return u, nil
+}
+
+// parseProjection parses a projection expression. A nil s parses to no paths,
+// which projects every attribute.
+func (e *expressions) parseProjection(s *string) ([]path, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	p, err := e.newParser("ProjectionExpression", *s)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []path
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	return paths, p.end()
+}
+
+const (
+	tokenName = iota
+	tokenNamePlaceholder
+	tokenValuePlaceholder
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+type parser struct {
+	e      *expressions
+	kind   string
+	tokens []token
+	pos    int
+}
+
+func (e *expressions) newParser(kind string, s string) (*parser, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, validationError("Invalid %s: The expression can not be empty;", kind)
+	}
+
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, validationError("Invalid %s: %v", kind, err)
+	}
+
+	return &parser{e: e, kind: kind, tokens: tokens}, nil
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+
+	isNameRune := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' || r == ':':
+			j := i + 1
+			for j < len(runes) && isNameRune(runes[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("Syntax error; token: %q", string(r))
+			}
+			kind := tokenNamePlaceholder
+			if r == ':' {
+				kind = tokenValuePlaceholder
+			}
+			tokens = append(tokens, token{kind: kind, text: string(runes[i:j])})
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:j])})
+			i = j
+		case isNameRune(r):
+			j := i
+			for j < len(runes) && isNameRune(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: string(runes[i:j])})
+			i = j
+		default:
+			symbol := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "<>", "<=", ">=":
+					symbol = two
+				}
+			}
+			if !strings.Contains("()[],.=<>+-", string(r)) {
+				return nil, fmt.Errorf("Invalid token: %q", symbol)
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol})
+			i += len([]rune(symbol))
+		}
+	}
+
+	return tokens, nil
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: -1, text: "<EOF>"}
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+
+	return t
+}
+
+// accept consumes the next token if it is the symbol or keyword s.
+func (p *parser) accept(s string) bool {
+	t := p.peek()
+	if (t.kind == tokenSymbol && t.text == s) || (t.kind == tokenName && strings.EqualFold(t.text, s)) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.syntaxError()
+	}
+
+	return nil
+}
+
+func (p *parser) end() error {
+	if !p.done() {
+		return p.syntaxError()
+	}
+
+	return nil
+}
+
+func (p *parser) syntaxError() error {
+	return validationError("Invalid %s: Syntax error; token: %q", p.kind, p.peek().text)
+}
+
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &orCondition{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (condition, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &andCondition{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if p.accept("NOT") {
+		c, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &notCondition{condition: c}, nil
+	}
+
+	return p.predicate()
+}
+
+var comparators = map[string]bool{"=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true}
+
+var conditionFunctions = map[string]int{
+	"attribute_exists":     1,
+	"attribute_not_exists": 1,
+	"attribute_type":       2,
+	"begins_with":          2,
+	"contains":             2,
+}
+
+func (p *parser) predicate() (condition, error) {
+	if p.accept("(") {
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+
+	if t := p.peek(); t.kind == tokenName {
+		if arity, ok := conditionFunctions[t.text]; ok {
+			return p.function(t.text, arity)
+		}
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := p.peek(); {
+	case t.kind == tokenSymbol && comparators[t.text]:
+		p.next()
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return &comparison{op: t.text, left: left, right: right}, nil
+	case p.accept("BETWEEN"):
+		low, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return &betweenCondition{value: left, low: low, high: high}, nil
+	case p.accept("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		in := &inCondition{value: left}
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			in.list = append(in.list, o)
+			if !p.accept(",") {
+				break
+			}
+		}
+		return in, p.expect(")")
+	}
+
+	return nil, p.syntaxError()
+}
+
+func (p *parser) function(name string, arity int) (condition, error) {
+	p.next()
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	f := &functionCondition{name: name}
+	for i := 0; i < arity; i++ {
+		if i > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		o, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		f.args = append(f.args, o)
+	}
+
+	if _, ok := f.args[0].(*pathOperand); !ok {
+		return nil, validationError("Invalid %s: Incorrect operand type for operator or function; operator or function: %s", p.kind, name)
+	}
+
+	return f, p.expect(")")
+}
+
+// operand parses a path, a value placeholder or size(path).
+func (p *parser) operand() (operand, error) {
+	if t := p.peek(); t.kind == tokenName && t.text == "size" && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "(" {
+		p.pos += 2
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return &sizeOperand{path: path}, p.expect(")")
+	}
+
+	if p.peek().kind == tokenValuePlaceholder {
+		return p.value()
+	}
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pathOperand{path: path}, nil
+}
+
+func (p *parser) value() (*valueOperand, error) {
+	t := p.next()
+	if t.kind != tokenValuePlaceholder {
+		p.pos--
+		return nil, p.syntaxError()
+	}
+
+	value, ok := p.e.values[t.text]
+	if !ok {
+		return nil, validationError("Invalid %s: An expression attribute value used in expression is not defined; attribute value: %s", p.kind, t.text)
+	}
+	p.e.usedValues[t.text] = true
+
+	return &valueOperand{value: value}, nil
+}
+
+func (p *parser) path() (path, error) {
+	var result path
+
+	for {
+		t := p.next()
+		switch t.kind {
+		case tokenName:
+			result = append(result, pathElement{name: t.text, index: -1})
+		case tokenNamePlaceholder:
+			name, ok := p.e.names[t.text]
+			if !ok || name == nil {
+				return nil, validationError("Invalid %s: An expression attribute name used in the document path is not defined; attribute name: %s", p.kind, t.text)
+			}
+			p.e.usedNames[t.text] = true
+			result = append(result, pathElement{name: *name, index: -1})
+		default:
+			p.pos--
+			return nil, p.syntaxError()
+		}
+
+		for p.accept("[") {
+			t := p.next()
+			index, err := strconv.Atoi(t.text)
+			if t.kind != tokenNumber || err != nil {
+				p.pos--
+				return nil, p.syntaxError()
+			}
+			result = append(result, pathElement{index: index})
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+
+		if !p.accept(".") {
+			return result, nil
+		}
+	}
+}
+
+func (p *parser) setAction() (setAction, error) {
+	path, err := p.path()
+	if err != nil {
+		return setAction{}, err
+	}
+
+	if err := p.expect("="); err != nil {
+		return setAction{}, err
+	}
+
+	value, err := p.setValue()
+	if err != nil {
+		return setAction{}, err
+	}
+
+	return setAction{path: path, value: value}, nil
+}
+
+// setValue parses the right-hand side of a SET action: an operand, or the sum or
+// difference of two.
+func (p *parser) setValue() (operand, error) {
+	left, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"+", "-"} {
+		if p.accept(op) {
+			right, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &arithmeticOperand{op: op, left: left, right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) setOperand() (operand, error) {
+	t := p.peek()
+	if t.kind == tokenName && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "(" {
+		switch t.text {
+		case "if_not_exists":
+			p.pos += 2
+			path, err := p.path()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			fallback, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &ifNotExistsOperand{path: path, fallback: fallback}, p.expect(")")
+		case "list_append":
+			p.pos += 2
+			first, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			second, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &listAppendOperand{first: first, second: second}, p.expect(")")
+		default:
+			return nil, validationError("Invalid UpdateExpression: Invalid function name; function: %s", t.text)
+		}
+	}
+
+	if t.kind == tokenValuePlaceholder {
+		return p.value()
+	}
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pathOperand{path: path}, nil
+}
@@ -0,0 +1,294 @@
+package dynamodbtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionExpressions(t *testing.T) {
+	item := Item{
+		"id":     s("user-1"),
+		"age":    n("42"),
+		"name":   s("Alice"),
+		"tags":   {SS: aws.StringSlice([]string{"admin", "ops"})},
+		"scores": {NS: aws.StringSlice([]string{"1", "2.50"})},
+		"items":  {L: []*dynamodb.AttributeValue{s("a"), n("7")}},
+		"nested": {M: Item{"level": {M: Item{"deep": n("3")}}, "list": {L: []*dynamodb.AttributeValue{s("first")}}}},
+		"flag":   {BOOL: aws.Bool(true)},
+		"nil":    {NULL: aws.Bool(true)},
+		"a.b":    s("dotted"),
+	}
+	names := map[string]*string{"#n": aws.String("name"), "#ab": aws.String("a.b")}
+	values := map[string]*dynamodb.AttributeValue{
+		":age":    n("42.0"),
+		":young":  n("18"),
+		":old":    n("65"),
+		":alice":  s("Alice"),
+		":al":     s("Al"),
+		":admin":  s("admin"),
+		":two":    n("2.5"),
+		":seven":  n("7"),
+		":three":  n("3"),
+		":len":    n("2"),
+		":type":   s("SS"),
+		":dotted": s("dotted"),
+		":first":  s("first"),
+		":true":   {BOOL: aws.Bool(true)},
+		":set":    {SS: aws.StringSlice([]string{"ops", "admin"})},
+	}
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"age = :age", true},
+		{"age <> :age", false},
+		{"age BETWEEN :young AND :old", true},
+		{"age < :young OR age > :old", false},
+		{"NOT (age < :young)", true},
+		{"#n = :alice AND age >= :age", true},
+		{"#n > :al", true},
+		{"#n IN (:al, :alice)", true},
+		{"age = :alice", false},
+		{"missing = :alice", false},
+		{"missing <> :alice", true},
+		{"begins_with(#n, :al)", true},
+		{"contains(tags, :admin)", true},
+		{"contains(scores, :two)", true},
+		{"contains(items, :seven)", true},
+		{"contains(#n, :al)", true},
+		{"tags = :set", true},
+		{"attribute_exists(nested.level.deep)", true},
+		{"attribute_not_exists(nested.level.other)", true},
+		{"attribute_type(tags, :type)", true},
+		{"nested.level.deep = :three", true},
+		{"nested.list[0] = :first", true},
+		{"items[5] = :first", false},
+		{"size(tags) = :len", true},
+		{"size(#n) > :len", true},
+		{"#ab = :dotted", true},
+		{"attribute_exists(a.b)", false},
+		{"flag = :true AND attribute_type(nil, :type)", false},
+		{"age = :age AND (#n = :al OR size(items) = :len)", true},
+		{"NOT age = :age OR NOT flag = :true", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			e, err := newExpressions(names, values)
+			require.NoError(t, err)
+
+			cond, err := e.parseCondition("ConditionExpression", aws.String(tt.expression))
+			require.NoError(t, err)
+
+			got, err := matches(item, cond)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInvalidExpressions(t *testing.T) {
+	names := map[string]*string{"#n": aws.String("name")}
+	values := map[string]*dynamodb.AttributeValue{":v": s("v"), ":w": s("w")}
+
+	conditions := []string{
+		"",
+		"#n =",
+		"#n = :missing",
+		"#missing = :v",
+		"#n == :v",
+		"#n = :v AND",
+		"(#n = :v",
+		"#n = :v)",
+		"unknown(#n)",
+		"begins_with(#n)",
+		"size(#n)",
+		"#n BETWEEN :v",
+		"#n IN ()",
+		"#n[x] = :v",
+		"#n = :v extra",
+		"#n = 'v'",
+	}
+
+	for _, expression := range conditions {
+		t.Run(expression, func(t *testing.T) {
+			e, err := newExpressions(names, values)
+			require.NoError(t, err)
+
+			_, err = e.parseCondition("ConditionExpression", aws.String(expression))
+			assertCode(t, err, ErrCodeValidationException)
+		})
+	}
+
+	updates := []string{
+		"",
+		"SET",
+		"SET #n",
+		"SET #n = :v SET #n = :w",
+		"SET #n = :v, #n = :w",
+		"SET #n.child = :v REMOVE #n",
+		"REMOVE #n = :v",
+		"ADD #n",
+		"SET #n = size(#n)",
+		"UPDATE #n = :v",
+	}
+
+	for _, expression := range updates {
+		t.Run(expression, func(t *testing.T) {
+			e, err := newExpressions(names, values)
+			require.NoError(t, err)
+
+			_, err = e.parseUpdate(aws.String(expression))
+			assertCode(t, err, ErrCodeValidationException)
+		})
+	}
+}
+
+func TestUnusedExpressionValues(t *testing.T) {
+	e, err := newExpressions(map[string]*string{"#n": aws.String("name")}, map[string]*dynamodb.AttributeValue{":v": s("v")})
+	require.NoError(t, err)
+
+	_, err = e.parseCondition("ConditionExpression", aws.String("#n = :v"))
+	require.NoError(t, err)
+	assert.NoError(t, e.checkUnused())
+
+	e, err = newExpressions(map[string]*string{"#n": aws.String("name"), "#x": aws.String("x")}, nil)
+	require.NoError(t, err)
+
+	_, err = e.parseProjection(aws.String("#n"))
+	require.NoError(t, err)
+	assertCode(t, e.checkUnused(), ErrCodeValidationException)
+
+	_, err = newExpressions(nil, map[string]*dynamodb.AttributeValue{":v": {}})
+	assertCode(t, err, ErrCodeValidationException)
+}
+
+func TestApplyUpdate(t *testing.T) {
+	item := Item{
+		"id":    s("1"),
+		"count": n("5"),
+		"list":  {L: []*dynamodb.AttributeValue{s("a"), s("b"), s("c")}},
+		"tags":  {SS: aws.StringSlice([]string{"x", "y"})},
+		"map":   {M: Item{"inner": s("old")}},
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":one":   n("1"),
+		":ten":   n("10"),
+		":x":     {SS: aws.StringSlice([]string{"x"})},
+		":new":   s("new"),
+		":front": {L: []*dynamodb.AttributeValue{s("0")}},
+	}
+
+	e, err := newExpressions(nil, values)
+	require.NoError(t, err)
+
+	u, err := e.parseUpdate(aws.String("SET #c = count + :one, total = if_not_exists(total, :ten) - count, list = list_append(:front, list), map.inner = :new, list[9] = :new REMOVE list[0], list[2] DELETE tags :x"))
+	assertCode(t, err, ErrCodeValidationException)
+	assert.Nil(t, u)
+
+	u, err = e.parseUpdate(aws.String("SET count = count + :one, total = if_not_exists(total, :ten) - count, copy = list, map.inner = :new, list[9] = :new DELETE tags :x"))
+	require.NoError(t, err)
+
+	updated, err := apply(item, u)
+	require.NoError(t, err)
+
+	assert.Equal(t, "6", aws.StringValue(updated["count"].N))
+	// Values are computed from the item before the update.
+	assert.Equal(t, "5", aws.StringValue(updated["total"].N))
+	assert.Len(t, updated["copy"].L, 3)
+	assert.Equal(t, "new", aws.StringValue(updated["map"].M["inner"].S))
+	// Setting past the end of a list appends.
+	assert.Equal(t, "new", aws.StringValue(updated["list"].L[3].S))
+	assert.ElementsMatch(t, []string{"y"}, aws.StringValueSlice(updated["tags"].SS))
+
+	// The original item is unchanged.
+	assert.Equal(t, "5", aws.StringValue(item["count"].N))
+	assert.Equal(t, "old", aws.StringValue(item["map"].M["inner"].S))
+	assert.Len(t, item["list"].L, 3)
+
+	u, err = e.parseUpdate(aws.String("REMOVE list[0], list[2], missing"))
+	require.NoError(t, err)
+
+	updated, err = apply(item, u)
+	require.NoError(t, err)
+	assert.Equal(t, []*dynamodb.AttributeValue{s("b")}, updated["list"].L)
+
+	u, err = e.parseUpdate(aws.String("SET count = list + :one"))
+	require.NoError(t, err)
+	_, err = apply(item, u)
+	assertCode(t, err, ErrCodeValidationException)
+}
+
+func TestProjection(t *testing.T) {
+	item := Item{
+		"id":   s("1"),
+		"list": {L: []*dynamodb.AttributeValue{s("a"), {M: Item{"x": s("x"), "y": s("y")}}}},
+		"map":  {M: Item{"a": s("a"), "b": s("b")}},
+	}
+
+	e, err := newExpressions(nil, nil)
+	require.NoError(t, err)
+
+	paths, err := e.parseProjection(aws.String("id, list[1].y, map.b, missing"))
+	require.NoError(t, err)
+
+	assert.Equal(t, Item{
+		"id":   s("1"),
+		"list": {L: []*dynamodb.AttributeValue{{M: Item{"y": s("y")}}}},
+		"map":  {M: Item{"b": s("b")}},
+	}, project(item, paths))
+}
+
+func TestNumberComparison(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1", "1.0", 0},
+		{"-5", "3", -1},
+		{"10", "9", 1},
+		{"1e2", "100", 0},
+		{"0.1", "0.10000000000000000000000000000000000001", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			got, ok := compare(n(tt.a), n(tt.b))
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want == 0, equal(n(tt.a), n(tt.b)))
+		})
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		n, want string
+	}{
+		{"1.50", "1.5"},
+		{"1e2", "100"},
+		{"+0.50", "0.5"},
+		{"-0", "0"},
+		{"1E-130", "0." + strings.Repeat("0", 129) + "1"},
+		{"12345678901234567890.123456789012345678", "12345678901234567890.123456789012345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			r, ok := parseNumber(tt.n)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, formatNumber(r))
+		})
+	}
+
+	for _, invalid := range []string{"1/2", "0x10", "0b1", "1_000", ".", "e5", "Inf", "NaN"} {
+		_, ok := parseNumber(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
@@ -0,0 +1,413 @@
+// Package dynamodbtest provides an in-memory DynamoDB for tests, so code that
+// talks to DynamoDB can be exercised end-to-end without a network or Docker.
+package dynamodbtest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// ErrCodeValidationException is the code of the errors DynamoDB returns for
+// invalid requests. The SDK declares no constant for it.
+const ErrCodeValidationException = "ValidationException"
+
+const (
+	region    = "us-east-1"
+	accountID = "000000000000"
+)
+
+// Fake is an in-memory implementation of dynamodbiface.DynamoDBAPI. It keeps
+// tables and items in memory and evaluates key conditions, filters, conditions,
+// update and projection expressions the way DynamoDB does, returning the same
+// error codes. Tables and indexes are ACTIVE as soon as they are created.
+//
+// It implements table management, the item operations, Query and Scan on
+// tables and secondary indexes, and the batch and transaction operations. It
+// does not enforce throughput, item size or response size limits, and never
+// returns unprocessed items. Calling an operation it does not implement panics.
+//
+// A Fake is safe for concurrent use; every operation is atomic.
+type Fake struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+var _ dynamodbiface.DynamoDBAPI = (*Fake)(nil)
+
+// New returns a Fake without tables.
+func New() *Fake {
+	return &Fake{tables: make(map[string]*table)}
+}
+
+func validationError(format string, args ...interface{}) error {
+	return awserr.NewRequestFailure(awserr.New(ErrCodeValidationException, fmt.Sprintf(format, args...), nil), http.StatusBadRequest, "")
+}
+
+func resourceNotFound() error {
+	return &dynamodb.ResourceNotFoundException{
+		RespMetadata: protocol.ResponseMetadata{StatusCode: http.StatusBadRequest},
+		Message_:     aws.String("Requested resource not found"),
+	}
+}
+
+func resourceInUse(message string) error {
+	return &dynamodb.ResourceInUseException{
+		RespMetadata: protocol.ResponseMetadata{StatusCode: http.StatusBadRequest},
+		Message_:     aws.String(message),
+	}
+}
+
+// contextError reports a cancelled context the way the SDK does.
+func contextError(ctx aws.Context) error {
+	if err := ctx.Err(); err != nil {
+		return awserr.New(request.CanceledErrorCode, "request context canceled", err)
+	}
+
+	return nil
+}
+
+// table returns the named table. It must be called with f.mu held.
+func (f *Fake) table(name *string) (*table, error) {
+	t, ok := f.tables[aws.StringValue(name)]
+	if !ok {
+		return nil, resourceNotFound()
+	}
+
+	return t, nil
+}
+
+// describe returns a copy of the description of t with its current item count.
+func describe(t *table) *dynamodb.TableDescription {
+	description := awsutil.CopyOf(t.description).(*dynamodb.TableDescription)
+	description.ItemCount = aws.Int64(int64(len(t.items)))
+
+	return description
+}
+
+func (f *Fake) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(input.TableName)
+	if len(name) < 3 {
+		return nil, validationError("TableName must be at least 3 characters long")
+	}
+
+	if _, ok := f.tables[name]; ok {
+		return nil, resourceInUse("Table already exists: " + name)
+	}
+
+	t, err := newTable(input)
+	if err != nil {
+		return nil, err
+	}
+	f.tables[name] = t
+
+	return &dynamodb.CreateTableOutput{TableDescription: describe(t)}, nil
+}
+
+func (f *Fake) CreateTableWithContext(ctx aws.Context, input *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.CreateTable(input)
+}
+
+// newTable validates the definition of a table and builds its description.
+func newTable(input *dynamodb.CreateTableInput) (*table, error) {
+	input = awsutil.CopyOf(input).(*dynamodb.CreateTableInput)
+	name := aws.StringValue(input.TableName)
+
+	attributeTypes := make(map[string]string, len(input.AttributeDefinitions))
+	for _, d := range input.AttributeDefinitions {
+		switch typ := aws.StringValue(d.AttributeType); typ {
+		case typeS, typeN, typeB:
+			attributeTypes[aws.StringValue(d.AttributeName)] = typ
+		default:
+			return nil, validationError("Member must satisfy enum value set: [B, N, S]")
+		}
+	}
+
+	used := map[string]bool{}
+	useKey := func(elements []*dynamodb.KeySchemaElement) (keySchema, error) {
+		key := newKeySchema(elements)
+		if key.hash == "" || len(elements) != len(key.names()) {
+			return key, validationError("Invalid KeySchema: The first KeySchemaElement is not a HASH key type")
+		}
+		for _, attr := range key.names() {
+			if _, ok := attributeTypes[attr]; !ok {
+				return key, validationError("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s]", attr)
+			}
+			used[attr] = true
+		}
+		return key, nil
+	}
+
+	key, err := useKey(input.KeySchema)
+	if err != nil {
+		return nil, err
+	}
+
+	billingMode := aws.StringValue(input.BillingMode)
+	if billingMode == "" {
+		billingMode = dynamodb.BillingModeProvisioned
+	}
+	if billingMode == dynamodb.BillingModeProvisioned && input.ProvisionedThroughput == nil {
+		return nil, validationError("One or more parameter values were invalid: ReadCapacityUnits and WriteCapacityUnits must both be specified when BillingMode is PROVISIONED")
+	}
+	if billingMode == dynamodb.BillingModePayPerRequest && input.ProvisionedThroughput != nil {
+		return nil, validationError("One or more parameter values were invalid: Neither ReadCapacityUnits nor WriteCapacityUnits can be specified when BillingMode is PAY_PER_REQUEST")
+	}
+
+	now := time.Now()
+	arn := fmt.Sprintf("arn:aws:dynamodb:%s:%s:table/%s", region, accountID, name)
+
+	t := &table{
+		key:            key,
+		attributeTypes: attributeTypes,
+		indexes:        map[string]*index{},
+		items:          map[string]Item{},
+		description: &dynamodb.TableDescription{
+			TableName:                 aws.String(name),
+			TableArn:                  aws.String(arn),
+			TableId:                   aws.String(fmt.Sprintf("%x", now.UnixNano())),
+			TableStatus:               aws.String(dynamodb.TableStatusActive),
+			CreationDateTime:          aws.Time(now),
+			AttributeDefinitions:      input.AttributeDefinitions,
+			KeySchema:                 input.KeySchema,
+			BillingModeSummary:        &dynamodb.BillingModeSummary{BillingMode: aws.String(billingMode)},
+			ProvisionedThroughput:     throughputDescription(input.ProvisionedThroughput),
+			DeletionProtectionEnabled: aws.Bool(aws.BoolValue(input.DeletionProtectionEnabled)),
+			TableSizeBytes:            aws.Int64(0),
+		},
+	}
+
+	addIndex := func(name string, global bool, elements []*dynamodb.KeySchemaElement, projection *dynamodb.Projection) error {
+		if _, ok := t.indexes[name]; ok || name == "" {
+			return validationError("One or more parameter values were invalid: Duplicate index name: %s", name)
+		}
+		if projection == nil || projection.ProjectionType == nil {
+			return validationError("One or more parameter values were invalid: Projection must be specified for index: %s", name)
+		}
+		key, err := useKey(elements)
+		if err != nil {
+			return err
+		}
+		if !global && (key.hash != t.key.hash || key.sortKey == "" || t.key.sortKey == "") {
+			return validationError("One or more parameter values were invalid: Index KeySchema does not have the same leading hash key as table KeySchema for index: %s", name)
+		}
+		t.indexes[name] = &index{name: name, global: global, key: key, projection: projection}
+		return nil
+	}
+
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		name := aws.StringValue(gsi.IndexName)
+		if err := addIndex(name, true, gsi.KeySchema, gsi.Projection); err != nil {
+			return nil, err
+		}
+		t.description.GlobalSecondaryIndexes = append(t.description.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:             aws.String(name),
+			IndexArn:              aws.String(arn + "/index/" + name),
+			IndexStatus:           aws.String(dynamodb.IndexStatusActive),
+			KeySchema:             gsi.KeySchema,
+			Projection:            gsi.Projection,
+			ProvisionedThroughput: throughputDescription(gsi.ProvisionedThroughput),
+		})
+	}
+
+	for _, lsi := range input.LocalSecondaryIndexes {
+		name := aws.StringValue(lsi.IndexName)
+		if err := addIndex(name, false, lsi.KeySchema, lsi.Projection); err != nil {
+			return nil, err
+		}
+		t.description.LocalSecondaryIndexes = append(t.description.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndexDescription{
+			IndexName:  aws.String(name),
+			IndexArn:   aws.String(arn + "/index/" + name),
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+		})
+	}
+
+	if len(used) != len(attributeTypes) {
+		return nil, validationError("One or more parameter values were invalid: Number of attributes in KeySchema does not exactly match number of attributes defined in AttributeDefinitions")
+	}
+
+	return t, nil
+}
+
+func throughputDescription(throughput *dynamodb.ProvisionedThroughput) *dynamodb.ProvisionedThroughputDescription {
+	if throughput == nil {
+		return &dynamodb.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(0), WriteCapacityUnits: aws.Int64(0)}
+	}
+
+	return &dynamodb.ProvisionedThroughputDescription{
+		ReadCapacityUnits:  aws.Int64(aws.Int64Value(throughput.ReadCapacityUnits)),
+		WriteCapacityUnits: aws.Int64(aws.Int64Value(throughput.WriteCapacityUnits)),
+	}
+}
+
+func (f *Fake) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: describe(t)}, nil
+}
+
+func (f *Fake) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.DescribeTable(input)
+}
+
+// DeleteTable deletes the table at once. Like DynamoDB, it refuses to delete a
+// table with deletion protection enabled.
+func (f *Fake) DeleteTable(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.BoolValue(t.description.DeletionProtectionEnabled) {
+		return nil, validationError("Resource cannot be deleted as it is currently protected against deletion. Disable deletion protection first.")
+	}
+
+	delete(f.tables, aws.StringValue(input.TableName))
+
+	description := describe(t)
+	description.TableStatus = aws.String(dynamodb.TableStatusDeleting)
+
+	return &dynamodb.DeleteTableOutput{TableDescription: description}, nil
+}
+
+func (f *Fake) DeleteTableWithContext(ctx aws.Context, input *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.DeleteTable(input)
+}
+
+// UpdateTable changes the billing mode, provisioned throughput and deletion
+// protection of a table. Other changes are rejected.
+func (f *Fake) UpdateTable(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.GlobalSecondaryIndexUpdates != nil || input.ReplicaUpdates != nil || input.StreamSpecification != nil ||
+		input.SSESpecification != nil || input.AttributeDefinitions != nil || input.TableClass != nil {
+		return nil, validationError("dynamodbtest only supports updating BillingMode, ProvisionedThroughput and DeletionProtectionEnabled")
+	}
+
+	if input.BillingMode != nil {
+		t.description.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: aws.String(*input.BillingMode)}
+	}
+	if input.ProvisionedThroughput != nil {
+		t.description.ProvisionedThroughput = throughputDescription(input.ProvisionedThroughput)
+	}
+	if input.DeletionProtectionEnabled != nil {
+		t.description.DeletionProtectionEnabled = aws.Bool(*input.DeletionProtectionEnabled)
+	}
+
+	return &dynamodb.UpdateTableOutput{TableDescription: describe(t)}, nil
+}
+
+func (f *Fake) UpdateTableWithContext(ctx aws.Context, input *dynamodb.UpdateTableInput, _ ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.UpdateTable(input)
+}
+
+func (f *Fake) ListTables(input *dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	limit := int(aws.Int64Value(input.Limit))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	names := make([]string, 0, len(f.tables))
+	for name := range f.tables {
+		if input.ExclusiveStartTableName == nil || name > *input.ExclusiveStartTableName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	output := &dynamodb.ListTablesOutput{TableNames: aws.StringSlice(names)}
+	if len(names) > limit {
+		output.TableNames = output.TableNames[:limit]
+		output.LastEvaluatedTableName = aws.String(names[limit-1])
+	}
+
+	return output, nil
+}
+
+func (f *Fake) ListTablesWithContext(ctx aws.Context, input *dynamodb.ListTablesInput, _ ...request.Option) (*dynamodb.ListTablesOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.ListTables(input)
+}
+
+// WaitUntilTableExists returns at once, since tables are ACTIVE as soon as they
+// are created, or the error of DescribeTable if the table does not exist.
+func (f *Fake) WaitUntilTableExists(input *dynamodb.DescribeTableInput) error {
+	_, err := f.DescribeTable(input)
+	return err
+}
+
+func (f *Fake) WaitUntilTableExistsWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, _ ...request.WaiterOption) error {
+	_, err := f.DescribeTableWithContext(ctx, input)
+	return err
+}
+
+// WaitUntilTableNotExists returns at once, since tables are gone as soon as
+// they are deleted.
+func (f *Fake) WaitUntilTableNotExists(input *dynamodb.DescribeTableInput) error {
+	return f.WaitUntilTableNotExistsWithContext(aws.BackgroundContext(), input)
+}
+
+func (f *Fake) WaitUntilTableNotExistsWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, _ ...request.WaiterOption) error {
+	_, err := f.DescribeTableWithContext(ctx, input)
+	if err == nil {
+		return awserr.New(request.WaiterResourceNotReadyErrorCode, "table still exists", nil)
+	}
+	if _, ok := err.(*dynamodb.ResourceNotFoundException); ok {
+		return nil
+	}
+
+	return err
+}
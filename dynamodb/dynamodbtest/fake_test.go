@@ -0,0 +1,717 @@
+package dynamodbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func s(v string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(v)} }
+
+func n(v string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(v)} }
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var aerr awserr.Error
+	require.True(t, errors.As(err, &aerr), "expected an AWS error, got %v", err)
+	assert.Equal(t, code, aerr.Code(), aerr.Message())
+}
+
+// newOrders returns a fake with an "orders" table keyed by customer and order,
+// with a global index by status and total and a local index by date.
+func newOrders(t *testing.T) *Fake {
+	t.Helper()
+
+	f := New()
+	_, err := f.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("orders"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("customer"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("order"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("status"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("total"), AttributeType: aws.String("N")},
+			{AttributeName: aws.String("date"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("customer"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("order"), KeyType: aws.String("RANGE")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName: aws.String("by-status"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("status"), KeyType: aws.String("HASH")},
+				{AttributeName: aws.String("total"), KeyType: aws.String("RANGE")},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+		}},
+		LocalSecondaryIndexes: []*dynamodb.LocalSecondaryIndex{{
+			IndexName: aws.String("by-date"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("customer"), KeyType: aws.String("HASH")},
+				{AttributeName: aws.String("date"), KeyType: aws.String("RANGE")},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		}},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	})
+	require.NoError(t, err)
+
+	return f
+}
+
+func putOrder(t *testing.T, f *Fake, customer, order, status, total, date string) {
+	t.Helper()
+
+	_, err := f.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("orders"),
+		Item: Item{
+			"customer": s(customer),
+			"order":    s(order),
+			"status":   s(status),
+			"total":    n(total),
+			"date":     s(date),
+			"note":     s("order " + order),
+		},
+	})
+	require.NoError(t, err)
+}
+
+func values(items []Item, name string) []string {
+	var result []string
+	for _, item := range items {
+		if av := item[name]; av != nil && av.S != nil {
+			result = append(result, *av.S)
+		} else if av != nil && av.N != nil {
+			result = append(result, *av.N)
+		}
+	}
+	return result
+}
+
+func TestTableLifecycle(t *testing.T) {
+	f := newOrders(t)
+
+	described, err := f.DescribeTableWithContext(context.Background(), &dynamodb.DescribeTableInput{TableName: aws.String("orders")})
+	require.NoError(t, err)
+	assert.Equal(t, dynamodb.TableStatusActive, aws.StringValue(described.Table.TableStatus))
+	assert.Equal(t, dynamodb.IndexStatusActive, aws.StringValue(described.Table.GlobalSecondaryIndexes[0].IndexStatus))
+	assert.Equal(t, "arn:aws:dynamodb:us-east-1:000000000000:table/orders", aws.StringValue(described.Table.TableArn))
+	assert.Equal(t, int64(0), aws.Int64Value(described.Table.ItemCount))
+
+	_, err = f.CreateTable(&dynamodb.CreateTableInput{
+		TableName:             aws.String("orders"),
+		AttributeDefinitions:  []*dynamodb.AttributeDefinition{{AttributeName: aws.String("id"), AttributeType: aws.String("S")}},
+		KeySchema:             []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(1), WriteCapacityUnits: aws.Int64(1)},
+	})
+	assertCode(t, err, dynamodb.ErrCodeResourceInUseException)
+
+	listed, err := f.ListTables(&dynamodb.ListTablesInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders"}, aws.StringValueSlice(listed.TableNames))
+
+	_, err = f.UpdateTable(&dynamodb.UpdateTableInput{TableName: aws.String("orders"), DeletionProtectionEnabled: aws.Bool(true)})
+	require.NoError(t, err)
+	_, err = f.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String("orders")})
+	assertCode(t, err, ErrCodeValidationException)
+
+	_, err = f.UpdateTable(&dynamodb.UpdateTableInput{TableName: aws.String("orders"), DeletionProtectionEnabled: aws.Bool(false)})
+	require.NoError(t, err)
+	deleted, err := f.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String("orders")})
+	require.NoError(t, err)
+	assert.Equal(t, dynamodb.TableStatusDeleting, aws.StringValue(deleted.TableDescription.TableStatus))
+	assert.NoError(t, f.WaitUntilTableNotExists(&dynamodb.DescribeTableInput{TableName: aws.String("orders")}))
+
+	_, err = f.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("orders")})
+	assertCode(t, err, dynamodb.ErrCodeResourceNotFoundException)
+}
+
+func TestCreateTableValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *dynamodb.CreateTableInput
+	}{
+		{
+			name: "undefined key attribute",
+			input: &dynamodb.CreateTableInput{
+				KeySchema:   []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}},
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+			},
+		},
+		{
+			name: "unused attribute definition",
+			input: &dynamodb.CreateTableInput{
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{
+					{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+					{AttributeName: aws.String("other"), AttributeType: aws.String("S")},
+				},
+				KeySchema:   []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}},
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+			},
+		},
+		{
+			name: "missing throughput",
+			input: &dynamodb.CreateTableInput{
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("id"), AttributeType: aws.String("S")}},
+				KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.TableName = aws.String("things")
+			_, err := New().CreateTable(tt.input)
+			assertCode(t, err, ErrCodeValidationException)
+		})
+	}
+}
+
+func TestPutGetDelete(t *testing.T) {
+	f := newOrders(t)
+	putOrder(t, f, "alice", "o1", "open", "10", "2024-01-01")
+
+	key := Item{"customer": s("alice"), "order": s("o1")}
+
+	got, err := f.GetItem(&dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	require.NoError(t, err)
+	assert.Equal(t, "open", aws.StringValue(got.Item["status"].S))
+
+	// Items are copies: changing one does not change the table.
+	got.Item["status"] = s("changed")
+	got, err = f.GetItem(&dynamodb.GetItemInput{
+		TableName:                aws.String("orders"),
+		Key:                      key,
+		ProjectionExpression:     aws.String("#s, note"),
+		ExpressionAttributeNames: map[string]*string{"#s": aws.String("status")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Item{"status": s("open"), "note": s("order o1")}, got.Item)
+
+	_, err = f.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String("orders"),
+		Item:                Item{"customer": s("alice"), "order": s("o1")},
+		ConditionExpression: aws.String("attribute_not_exists(customer)"),
+	})
+	assertCode(t, err, dynamodb.ErrCodeConditionalCheckFailedException)
+
+	replaced, err := f.PutItem(&dynamodb.PutItemInput{
+		TableName:    aws.String("orders"),
+		Item:         Item{"customer": s("alice"), "order": s("o1"), "status": s("paid")},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "open", aws.StringValue(replaced.Attributes["status"].S))
+
+	_, err = f.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                 aws.String("orders"),
+		Key:                       key,
+		ConditionExpression:       aws.String("#s = :open"),
+		ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":open": s("open")},
+	})
+	var ccf *dynamodb.ConditionalCheckFailedException
+	require.ErrorAs(t, err, &ccf)
+
+	_, err = f.DeleteItemWithContext(context.Background(), &dynamodb.DeleteItemInput{TableName: aws.String("orders"), Key: key})
+	require.NoError(t, err)
+
+	got, err = f.GetItem(&dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	require.NoError(t, err)
+	assert.Nil(t, got.Item)
+}
+
+func TestNumbersAreNormalized(t *testing.T) {
+	f := newOrders(t)
+	key := Item{"customer": s("alice"), "order": s("o1")}
+	addend := &dynamodb.AttributeValue{NS: aws.StringSlice([]string{"2.50"})}
+
+	_, err := f.PutItem(&dynamodb.PutItemInput{TableName: aws.String("orders"), Item: Item{
+		"customer": s("alice"),
+		"order":    s("o1"),
+		"status":   s("open"),
+		"total":    n("1.50"),
+		"limits":   {NS: aws.StringSlice([]string{"1e2", "+0.50"})},
+		"nested":   {L: []*dynamodb.AttributeValue{{M: Item{"tiny": n("1E-3")}}}},
+	}})
+	require.NoError(t, err)
+
+	updated, err := f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("orders"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET price = :price ADD limits :more"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":price": n("-007.0"), ":more": addend},
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "-7", aws.StringValue(updated.Attributes["price"].N))
+	// The request is left as it was sent.
+	assert.Equal(t, "2.50", aws.StringValue(addend.NS[0]))
+
+	got, err := f.GetItem(&dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", aws.StringValue(got.Item["total"].N))
+	assert.ElementsMatch(t, []string{"100", "0.5", "2.5"}, aws.StringValueSlice(got.Item["limits"].NS))
+	assert.Equal(t, "0.001", aws.StringValue(got.Item["nested"].L[0].M["tiny"].N))
+	assert.Equal(t, "-7", aws.StringValue(got.Item["price"].N))
+
+	// Keys given in another form still find the item.
+	queried, err := f.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("orders"),
+		IndexName:                 aws.String("by-status"),
+		KeyConditionExpression:    aws.String("#s = :open AND total = :total"),
+		ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":open": s("open"), ":total": n("15e-1")},
+	})
+	require.NoError(t, err)
+	require.Len(t, queried.Items, 1)
+	assert.Equal(t, "1.5", aws.StringValue(queried.Items[0]["total"].N))
+}
+
+func TestItemValidation(t *testing.T) {
+	f := newOrders(t)
+
+	tests := []struct {
+		name string
+		item Item
+	}{
+		{name: "missing sort key", item: Item{"customer": s("alice")}},
+		{name: "wrong key type", item: Item{"customer": s("alice"), "order": n("1")}},
+		{name: "empty key", item: Item{"customer": s(""), "order": s("o1")}},
+		{name: "wrong index key type", item: Item{"customer": s("alice"), "order": s("o1"), "total": s("ten")}},
+		{name: "empty set", item: Item{"customer": s("alice"), "order": s("o1"), "tags": {SS: []*string{}}}},
+		{name: "invalid number", item: Item{"customer": s("alice"), "order": s("o1"), "count": n("ten")}},
+		{name: "fraction", item: Item{"customer": s("alice"), "order": s("o1"), "count": n("1/2")}},
+		{name: "hex number", item: Item{"customer": s("alice"), "order": s("o1"), "count": n("0x10")}},
+		{name: "invalid number in set", item: Item{"customer": s("alice"), "order": s("o1"), "counts": {NS: aws.StringSlice([]string{"1", "1e"})}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.PutItem(&dynamodb.PutItemInput{TableName: aws.String("orders"), Item: tt.item})
+			assertCode(t, err, ErrCodeValidationException)
+		})
+	}
+
+	_, err := f.GetItem(&dynamodb.GetItemInput{TableName: aws.String("orders"), Key: Item{"customer": s("alice")}})
+	assertCode(t, err, ErrCodeValidationException)
+
+	_, err = f.PutItem(&dynamodb.PutItemInput{
+		TableName:                 aws.String("orders"),
+		Item:                      Item{"customer": s("alice"), "order": s("o1")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":unused": s("x")},
+	})
+	assertCode(t, err, ErrCodeValidationException)
+
+	_, err = f.PutItem(&dynamodb.PutItemInput{TableName: aws.String("missing"), Item: Item{"id": s("1")}})
+	assertCode(t, err, dynamodb.ErrCodeResourceNotFoundException)
+}
+
+func TestUpdateItem(t *testing.T) {
+	f := newOrders(t)
+	key := Item{"customer": s("alice"), "order": s("o1")}
+
+	// Updating a missing item creates it.
+	created, err := f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String("orders"),
+		Key:              key,
+		UpdateExpression: aws.String("SET visits = if_not_exists(visits, :zero) + :one, tags = :tags, lines = :lines"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":zero":  n("0"),
+			":one":   n("1"),
+			":tags":  {SS: aws.StringSlice([]string{"a", "b"})},
+			":lines": {L: []*dynamodb.AttributeValue{s("x")}},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(customer)"),
+		ReturnValues:        aws.String(dynamodb.ReturnValueAllNew),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1", aws.StringValue(created.Attributes["visits"].N))
+	assert.Equal(t, "alice", aws.StringValue(created.Attributes["customer"].S))
+
+	updated, err := f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String("orders"),
+		Key:              key,
+		UpdateExpression: aws.String("SET lines = list_append(lines, :more), meta = :meta ADD visits :two, tags :c DELETE tags :a REMOVE lines[0]"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":more": {L: []*dynamodb.AttributeValue{s("y")}},
+			":meta": {M: Item{"source": s("web")}},
+			":two":  n("2.5"),
+			":c":    {SS: aws.StringSlice([]string{"c"})},
+			":a":    {SS: aws.StringSlice([]string{"a"})},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	assertCode(t, err, ErrCodeValidationException)
+	assert.Nil(t, updated)
+
+	updated, err = f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String("orders"),
+		Key:              key,
+		UpdateExpression: aws.String("SET lines = list_append(lines, :more), meta = :meta ADD visits :two, tags :c"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":more": {L: []*dynamodb.AttributeValue{s("y")}},
+			":meta": {M: Item{"source": s("web")}},
+			":two":  n("2.5"),
+			":c":    {SS: aws.StringSlice([]string{"c"})},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "3.5", aws.StringValue(updated.Attributes["visits"].N))
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, aws.StringValueSlice(updated.Attributes["tags"].SS))
+	assert.Equal(t, []string{"x", "y"}, values(itemsOf(updated.Attributes["lines"].L), ""))
+	assert.NotContains(t, updated.Attributes, "customer")
+
+	old, err := f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("orders"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET meta.source = :app, meta.seen = :true REMOVE lines[0] DELETE tags :ab"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":app": s("app"), ":true": {BOOL: aws.Bool(true)}, ":ab": {SS: aws.StringSlice([]string{"a", "b"})}},
+		ReturnValues:              aws.String(dynamodb.ReturnValueUpdatedOld),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "web", aws.StringValue(old.Attributes["meta"].M["source"].S))
+
+	got, err := f.GetItem(&dynamodb.GetItemInput{TableName: aws.String("orders"), Key: key})
+	require.NoError(t, err)
+	assert.Equal(t, "app", aws.StringValue(got.Item["meta"].M["source"].S))
+	assert.True(t, aws.BoolValue(got.Item["meta"].M["seen"].BOOL))
+	assert.Equal(t, []string{"c"}, aws.StringValueSlice(got.Item["tags"].SS))
+	assert.Len(t, got.Item["lines"].L, 1)
+	assert.Equal(t, "y", aws.StringValue(got.Item["lines"].L[0].S))
+
+	_, err = f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("orders"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET customer = :bob"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":bob": s("bob")},
+	})
+	assertCode(t, err, ErrCodeValidationException)
+
+	_, err = f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("orders"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET missing.child = :v"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":v": s("v")},
+	})
+	assertCode(t, err, ErrCodeValidationException)
+
+	_, err = f.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                           aws.String("orders"),
+		Key:                                 key,
+		UpdateExpression:                    aws.String("SET visits = visits + :one"),
+		ConditionExpression:                 aws.String("visits > :limit"),
+		ExpressionAttributeValues:           map[string]*dynamodb.AttributeValue{":one": n("1"), ":limit": n("100")},
+		ReturnValuesOnConditionCheckFailure: aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld),
+	})
+	var ccf *dynamodb.ConditionalCheckFailedException
+	require.ErrorAs(t, err, &ccf)
+	assert.Equal(t, "3.5", aws.StringValue(ccf.Item["visits"].N))
+}
+
+func itemsOf(list []*dynamodb.AttributeValue) []Item {
+	items := make([]Item, len(list))
+	for i, av := range list {
+		items[i] = Item{"": av}
+	}
+	return items
+}
+
+func TestQuery(t *testing.T) {
+	f := newOrders(t)
+	putOrder(t, f, "alice", "o3", "open", "30", "2024-03-01")
+	putOrder(t, f, "alice", "o1", "paid", "10", "2024-01-01")
+	putOrder(t, f, "alice", "o2", "open", "20", "2024-02-01")
+	putOrder(t, f, "bob", "o4", "open", "5", "2024-01-15")
+
+	query := func(input *dynamodb.QueryInput) *dynamodb.QueryOutput {
+		t.Helper()
+		input.TableName = aws.String("orders")
+		output, err := f.Query(input)
+		require.NoError(t, err)
+		return output
+	}
+
+	output := query(&dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String("customer = :c"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": s("alice")},
+	})
+	assert.Equal(t, []string{"o1", "o2", "o3"}, values(output.Items, "order"))
+
+	output = query(&dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String("#o BETWEEN :a AND :b AND customer = :c"),
+		ExpressionAttributeNames:  map[string]*string{"#o": aws.String("order")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": s("alice"), ":a": s("o2"), ":b": s("o9")},
+		ScanIndexForward:          aws.Bool(false),
+	})
+	assert.Equal(t, []string{"o3", "o2"}, values(output.Items, "order"))
+
+	output = query(&dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String("customer = :c AND begins_with(#o, :p)"),
+		FilterExpression:          aws.String("#s = :open"),
+		ExpressionAttributeNames:  map[string]*string{"#o": aws.String("order"), "#s": aws.String("status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": s("alice"), ":p": s("o"), ":open": s("open")},
+	})
+	assert.Equal(t, []string{"o2", "o3"}, values(output.Items, "order"))
+	assert.Equal(t, int64(2), aws.Int64Value(output.Count))
+	assert.Equal(t, int64(3), aws.Int64Value(output.ScannedCount))
+
+	// The global index holds the keys only, ordered by total.
+	output = query(&dynamodb.QueryInput{
+		IndexName:                 aws.String("by-status"),
+		KeyConditionExpression:    aws.String("#s = :open AND total >= :min"),
+		ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":open": s("open"), ":min": n("6")},
+	})
+	assert.Equal(t, []string{"o2", "o3"}, values(output.Items, "order"))
+	assert.NotContains(t, output.Items[0], "note")
+
+	output = query(&dynamodb.QueryInput{
+		IndexName:                 aws.String("by-date"),
+		KeyConditionExpression:    aws.String("customer = :c AND #d < :d"),
+		ExpressionAttributeNames:  map[string]*string{"#d": aws.String("date")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": s("alice"), ":d": s("2024-02-15")},
+		Select:                    aws.String(dynamodb.SelectCount),
+	})
+	assert.Equal(t, int64(2), aws.Int64Value(output.Count))
+	assert.Empty(t, output.Items)
+
+	tests := []struct {
+		name  string
+		input *dynamodb.QueryInput
+	}{
+		{name: "no partition key", input: &dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("#o = :o"),
+			ExpressionAttributeNames:  map[string]*string{"#o": aws.String("order")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":o": s("o1")},
+		}},
+		{name: "non-key attribute", input: &dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("customer = :c AND note = :n"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": s("alice"), ":n": s("x")},
+		}},
+		{name: "or", input: &dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("customer = :c OR customer = :d"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": s("alice"), ":d": s("bob")},
+		}},
+		{name: "consistent read on global index", input: &dynamodb.QueryInput{
+			IndexName:                 aws.String("by-status"),
+			ConsistentRead:            aws.Bool(true),
+			KeyConditionExpression:    aws.String("#s = :open"),
+			ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":open": s("open")},
+		}},
+		{name: "unknown index", input: &dynamodb.QueryInput{
+			IndexName:                 aws.String("missing"),
+			KeyConditionExpression:    aws.String("customer = :c"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": s("alice")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.TableName = aws.String("orders")
+			_, err := f.Query(tt.input)
+			assertCode(t, err, ErrCodeValidationException)
+		})
+	}
+}
+
+func TestQueryPages(t *testing.T) {
+	f := newOrders(t)
+	for i := 0; i < 5; i++ {
+		putOrder(t, f, "alice", fmt.Sprintf("o%d", i), "open", fmt.Sprint(i), "2024-01-01")
+	}
+
+	var pages [][]string
+	err := f.QueryPagesWithContext(context.Background(), &dynamodb.QueryInput{
+		TableName:                 aws.String("orders"),
+		IndexName:                 aws.String("by-status"),
+		KeyConditionExpression:    aws.String("#s = :open"),
+		ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":open": s("open")},
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int64(2),
+	}, func(page *dynamodb.QueryOutput, last bool) bool {
+		pages = append(pages, values(page.Items, "total"))
+		if !last {
+			assert.Equal(t, []string{"customer", "order", "status", "total"}, sortedKeys(page.LastEvaluatedKey))
+		}
+		return true
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"4", "3"}, {"2", "1"}, {"0"}}, pages)
+}
+
+func sortedKeys(item Item) []string {
+	keys := tableNames(item)
+	return keys
+}
+
+func TestScan(t *testing.T) {
+	f := newOrders(t)
+	for i := 0; i < 20; i++ {
+		putOrder(t, f, fmt.Sprintf("c%02d", i), "o1", "open", fmt.Sprint(i), "2024-01-01")
+	}
+
+	var all []string
+	for segment := int64(0); segment < 3; segment++ {
+		err := f.ScanPages(&dynamodb.ScanInput{
+			TableName:     aws.String("orders"),
+			Segment:       aws.Int64(segment),
+			TotalSegments: aws.Int64(3),
+			Limit:         aws.Int64(4),
+		}, func(page *dynamodb.ScanOutput, _ bool) bool {
+			all = append(all, values(page.Items, "customer")...)
+			return true
+		})
+		require.NoError(t, err)
+	}
+	assert.Len(t, all, 20)
+	assert.ElementsMatch(t, values(scanAll(t, f), "customer"), all)
+
+	filtered, err := f.ScanWithContext(context.Background(), &dynamodb.ScanInput{
+		TableName:                 aws.String("orders"),
+		FilterExpression:          aws.String("total IN (:a, :b) OR size(customer) > :len"),
+		ProjectionExpression:      aws.String("customer"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":a": n("3"), ":b": n("7.0"), ":len": n("3")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c03", "c07"}, values(filtered.Items, "customer"))
+	assert.Equal(t, Item{"customer": s("c03")}, filtered.Items[0])
+
+	_, err = f.Scan(&dynamodb.ScanInput{TableName: aws.String("orders"), Segment: aws.Int64(3), TotalSegments: aws.Int64(3)})
+	assertCode(t, err, ErrCodeValidationException)
+}
+
+func scanAll(t *testing.T, f *Fake) []Item {
+	t.Helper()
+
+	output, err := f.Scan(&dynamodb.ScanInput{TableName: aws.String("orders")})
+	require.NoError(t, err)
+	return output.Items
+}
+
+func TestBatch(t *testing.T) {
+	f := newOrders(t)
+	putOrder(t, f, "alice", "o1", "open", "10", "2024-01-01")
+
+	_, err := f.BatchWriteItemWithContext(context.Background(), &dynamodb.BatchWriteItemInput{RequestItems: map[string][]*dynamodb.WriteRequest{
+		"orders": {
+			{PutRequest: &dynamodb.PutRequest{Item: Item{"customer": s("bob"), "order": s("o2")}}},
+			{DeleteRequest: &dynamodb.DeleteRequest{Key: Item{"customer": s("alice"), "order": s("o1")}}},
+		},
+	}})
+	require.NoError(t, err)
+
+	got, err := f.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: map[string]*dynamodb.KeysAndAttributes{
+		"orders": {Keys: []map[string]*dynamodb.AttributeValue{
+			{"customer": s("alice"), "order": s("o1")},
+			{"customer": s("bob"), "order": s("o2")},
+		}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, values(got.Responses["orders"], "customer"))
+	assert.Empty(t, got.UnprocessedKeys)
+
+	// A batch that fails validation writes nothing.
+	_, err = f.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: map[string][]*dynamodb.WriteRequest{
+		"orders": {
+			{PutRequest: &dynamodb.PutRequest{Item: Item{"customer": s("carol"), "order": s("o3")}}},
+			{PutRequest: &dynamodb.PutRequest{Item: Item{"customer": s("dave")}}},
+		},
+	}})
+	assertCode(t, err, ErrCodeValidationException)
+	assert.Len(t, scanAll(t, f), 1)
+
+	_, err = f.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: map[string][]*dynamodb.WriteRequest{
+		"orders": {
+			{PutRequest: &dynamodb.PutRequest{Item: Item{"customer": s("carol"), "order": s("o3")}}},
+			{DeleteRequest: &dynamodb.DeleteRequest{Key: Item{"customer": s("carol"), "order": s("o3")}}},
+		},
+	}})
+	assertCode(t, err, ErrCodeValidationException)
+
+	requests := make([]*dynamodb.WriteRequest, 26)
+	for i := range requests {
+		requests[i] = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: Item{"customer": s("c"), "order": s(fmt.Sprint(i))}}}
+	}
+	_, err = f.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: map[string][]*dynamodb.WriteRequest{"orders": requests}})
+	assertCode(t, err, ErrCodeValidationException)
+}
+
+func TestTransactions(t *testing.T) {
+	f := newOrders(t)
+	putOrder(t, f, "alice", "o1", "open", "10", "2024-01-01")
+
+	pay := func(status string) *dynamodb.TransactWriteItemsInput {
+		return &dynamodb.TransactWriteItemsInput{TransactItems: []*dynamodb.TransactWriteItem{
+			{ConditionCheck: &dynamodb.ConditionCheck{
+				TableName:                 aws.String("orders"),
+				Key:                       Item{"customer": s("alice"), "order": s("o1")},
+				ConditionExpression:       aws.String("#s = :status"),
+				ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":status": s(status)},
+			}},
+			{Put: &dynamodb.Put{
+				TableName:           aws.String("orders"),
+				Item:                Item{"customer": s("alice"), "order": s("o2"), "status": s("paid"), "total": n("10")},
+				ConditionExpression: aws.String("attribute_not_exists(#o)"),
+				ExpressionAttributeNames: map[string]*string{
+					"#o": aws.String("order"),
+				},
+			}},
+			{Update: &dynamodb.Update{
+				TableName:                 aws.String("orders"),
+				Key:                       Item{"customer": s("bob"), "order": s("o9")},
+				UpdateExpression:          aws.String("ADD credits :n"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":n": n("1")},
+			}},
+		}}
+	}
+
+	_, err := f.TransactWriteItems(pay("paid"))
+	var canceled *dynamodb.TransactionCanceledException
+	require.ErrorAs(t, err, &canceled)
+	require.Len(t, canceled.CancellationReasons, 3)
+	assert.Equal(t, "ConditionalCheckFailed", aws.StringValue(canceled.CancellationReasons[0].Code))
+	assert.Equal(t, "None", aws.StringValue(canceled.CancellationReasons[1].Code))
+	assert.Len(t, scanAll(t, f), 1)
+
+	_, err = f.TransactWriteItemsWithContext(context.Background(), pay("open"))
+	require.NoError(t, err)
+	assert.Len(t, scanAll(t, f), 3)
+
+	got, err := f.TransactGetItems(&dynamodb.TransactGetItemsInput{TransactItems: []*dynamodb.TransactGetItem{
+		{Get: &dynamodb.Get{TableName: aws.String("orders"), Key: Item{"customer": s("bob"), "order": s("o9")}}},
+		{Get: &dynamodb.Get{TableName: aws.String("orders"), Key: Item{"customer": s("zed"), "order": s("o0")}}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "1", aws.StringValue(got.Responses[0].Item["credits"].N))
+	assert.Nil(t, got.Responses[1].Item)
+
+	_, err = f.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: []*dynamodb.TransactWriteItem{
+		{Delete: &dynamodb.Delete{TableName: aws.String("orders"), Key: Item{"customer": s("alice"), "order": s("o1")}}},
+		{Delete: &dynamodb.Delete{TableName: aws.String("orders"), Key: Item{"customer": s("alice"), "order": s("o1")}}},
+	}})
+	assertCode(t, err, ErrCodeValidationException)
+}
+
+func TestCanceledContext(t *testing.T) {
+	f := newOrders(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := f.GetItemWithContext(ctx, &dynamodb.GetItemInput{TableName: aws.String("orders"), Key: Item{"customer": s("a"), "order": s("b")}})
+	assertCode(t, err, request.CanceledErrorCode)
+}
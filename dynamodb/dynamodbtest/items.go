@@ -0,0 +1,417 @@
+package dynamodbtest
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// change is the effect of one write, worked out before it is applied so that a
+// transaction can check all of its writes before making any of them.
+type change struct {
+	table *table
+	key   Item
+	old   Item
+	new   Item
+	// remove deletes the item instead of writing new. A change with neither
+	// only checks a condition.
+	remove bool
+	// failed is set when the condition of the write does not hold.
+	failed    bool
+	returnOld bool
+}
+
+// check evaluates the condition of the write against the item it replaces.
+func (c *change) check(cond condition) error {
+	current := c.old
+	if current == nil {
+		current = Item{}
+	}
+
+	ok, err := matches(current, cond)
+	c.failed = !ok
+
+	return err
+}
+
+func (c *change) apply() {
+	switch {
+	case c.remove:
+		c.table.remove(c.key)
+	case c.new != nil:
+		// Set updates can share members with the request, so the numbers are
+		// normalized in a copy.
+		c.new = cloneItem(c.new)
+		for _, av := range c.new {
+			normalizeNumbers(av)
+		}
+		c.table.put(c.new)
+	}
+}
+
+func (c *change) conditionFailed() error {
+	err := &dynamodb.ConditionalCheckFailedException{
+		RespMetadata: protocol.ResponseMetadata{StatusCode: http.StatusBadRequest},
+		Message_:     aws.String("The conditional request failed"),
+	}
+	if c.returnOld && c.old != nil {
+		err.Item = cloneItem(c.old)
+	}
+
+	return err
+}
+
+func legacyParameter(name string) error {
+	return validationError("dynamodbtest does not support the legacy parameter %s; use expressions instead", name)
+}
+
+func validateReturnValues(value *string, allowed ...string) error {
+	if value == nil {
+		return nil
+	}
+
+	for _, a := range allowed {
+		if *value == a {
+			return nil
+		}
+	}
+
+	return validationError("ReturnValues can only be %v on this operation", allowed)
+}
+
+func returnsOld(value *string) bool {
+	return aws.StringValue(value) == dynamodb.ReturnValuesOnConditionCheckFailureAllOld
+}
+
+func (f *Fake) preparePut(input *dynamodb.PutItemInput) (*change, error) {
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Expected != nil || input.ConditionalOperator != nil {
+		return nil, legacyParameter("Expected")
+	}
+
+	if err := validateReturnValues(input.ReturnValues, dynamodb.ReturnValueNone, dynamodb.ReturnValueAllOld); err != nil {
+		return nil, err
+	}
+
+	e, err := newExpressions(input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := e.parseCondition("ConditionExpression", input.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	item := cloneItem(input.Item)
+	if err := validateItem(item); err != nil {
+		return nil, err
+	}
+	key, err := t.primaryKey(item)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateIndexKeys(item); err != nil {
+		return nil, err
+	}
+
+	c := &change{table: t, key: key, old: t.get(key), new: item, returnOld: returnsOld(input.ReturnValuesOnConditionCheckFailure)}
+
+	return c, c.check(cond)
+}
+
+// PutItem writes an item, replacing any item with the same key, if its
+// ConditionExpression holds.
+func (f *Fake) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.preparePut(input)
+	if err != nil {
+		return nil, err
+	}
+	if c.failed {
+		return nil, c.conditionFailed()
+	}
+	c.apply()
+
+	output := &dynamodb.PutItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld && c.old != nil {
+		output.Attributes = cloneItem(c.old)
+	}
+
+	return output, nil
+}
+
+func (f *Fake) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.PutItem(input)
+}
+
+// GetItem returns the item with the key, projected by its ProjectionExpression,
+// or no item.
+func (f *Fake) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	item, err := f.get(input.TableName, input.Key, input.ProjectionExpression, input.ExpressionAttributeNames, input.AttributesToGet != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (f *Fake) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.GetItem(input)
+}
+
+// get reads one item for GetItem, BatchGetItem and TransactGetItems.
+func (f *Fake) get(tableName *string, key Item, projection *string, names map[string]*string, legacy bool) (Item, error) {
+	t, err := f.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if legacy {
+		return nil, legacyParameter("AttributesToGet")
+	}
+
+	e, err := newExpressions(names, nil)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := e.parseProjection(projection)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	if err := t.validateKey(key); err != nil {
+		return nil, err
+	}
+
+	item := t.get(key)
+	if item == nil {
+		return nil, nil
+	}
+
+	return project(item, paths), nil
+}
+
+func (f *Fake) prepareDelete(input *dynamodb.DeleteItemInput) (*change, error) {
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Expected != nil || input.ConditionalOperator != nil {
+		return nil, legacyParameter("Expected")
+	}
+
+	if err := validateReturnValues(input.ReturnValues, dynamodb.ReturnValueNone, dynamodb.ReturnValueAllOld); err != nil {
+		return nil, err
+	}
+
+	e, err := newExpressions(input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := e.parseCondition("ConditionExpression", input.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	if err := t.validateKey(input.Key); err != nil {
+		return nil, err
+	}
+	key := cloneItem(input.Key)
+
+	c := &change{table: t, key: key, old: t.get(key), remove: true, returnOld: returnsOld(input.ReturnValuesOnConditionCheckFailure)}
+
+	return c, c.check(cond)
+}
+
+// DeleteItem deletes the item with the key, if its ConditionExpression holds.
+// Deleting an item that does not exist succeeds.
+func (f *Fake) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.prepareDelete(input)
+	if err != nil {
+		return nil, err
+	}
+	if c.failed {
+		return nil, c.conditionFailed()
+	}
+	c.apply()
+
+	output := &dynamodb.DeleteItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld && c.old != nil {
+		output.Attributes = cloneItem(c.old)
+	}
+
+	return output, nil
+}
+
+func (f *Fake) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.DeleteItem(input)
+}
+
+// prepareUpdate also returns the parsed update, which UpdateItem needs to work
+// out the UPDATED_OLD and UPDATED_NEW return values.
+func (f *Fake) prepareUpdate(input *dynamodb.UpdateItemInput) (*change, *update, error) {
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if input.AttributeUpdates != nil {
+		return nil, nil, legacyParameter("AttributeUpdates")
+	}
+	if input.Expected != nil || input.ConditionalOperator != nil {
+		return nil, nil, legacyParameter("Expected")
+	}
+
+	if err := validateReturnValues(input.ReturnValues, dynamodb.ReturnValueNone, dynamodb.ReturnValueAllOld,
+		dynamodb.ReturnValueUpdatedOld, dynamodb.ReturnValueAllNew, dynamodb.ReturnValueUpdatedNew); err != nil {
+		return nil, nil, err
+	}
+
+	e, err := newExpressions(input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := e.parseUpdate(input.UpdateExpression)
+	if err != nil {
+		return nil, nil, err
+	}
+	cond, err := e.parseCondition("ConditionExpression", input.ConditionExpression)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.checkUnused(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := t.validateKey(input.Key); err != nil {
+		return nil, nil, err
+	}
+	key := cloneItem(input.Key)
+
+	if u != nil {
+		for _, p := range u.paths() {
+			if _, ok := key[p[0].name]; ok {
+				return nil, nil, validationError("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", p[0].name)
+			}
+		}
+	}
+
+	c := &change{table: t, key: key, old: t.get(key), returnOld: returnsOld(input.ReturnValuesOnConditionCheckFailure)}
+	if err := c.check(cond); err != nil || c.failed {
+		return c, u, err
+	}
+
+	// Updating an item that does not exist creates it from its key.
+	base := c.old
+	if base == nil {
+		base = key
+	}
+
+	c.new = cloneItem(base)
+	if u != nil {
+		if c.new, err = apply(base, u); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := t.validateIndexKeys(c.new); err != nil {
+		return nil, nil, err
+	}
+
+	return c, u, nil
+}
+
+// UpdateItem applies an UpdateExpression to the item with the key, creating the
+// item if it does not exist, if its ConditionExpression holds.
+func (f *Fake) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, u, err := f.prepareUpdate(input)
+	if err != nil {
+		return nil, err
+	}
+	if c.failed {
+		return nil, c.conditionFailed()
+	}
+	c.apply()
+
+	output := &dynamodb.UpdateItemOutput{}
+	switch aws.StringValue(input.ReturnValues) {
+	case dynamodb.ReturnValueAllOld:
+		output.Attributes = cloneItem(c.old)
+	case dynamodb.ReturnValueAllNew:
+		output.Attributes = cloneItem(c.new)
+	case dynamodb.ReturnValueUpdatedOld:
+		output.Attributes = updatedAttributes(c.old, u)
+	case dynamodb.ReturnValueUpdatedNew:
+		output.Attributes = updatedAttributes(c.new, u)
+	}
+
+	return output, nil
+}
+
+func (f *Fake) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.UpdateItem(input)
+}
+
+// updatedAttributes returns the top-level attributes of item that u writes.
+func updatedAttributes(item Item, u *update) Item {
+	if item == nil || u == nil {
+		return nil
+	}
+
+	attributes := Item{}
+	for _, p := range u.paths() {
+		if av, ok := item[p[0].name]; ok {
+			attributes[p[0].name] = clone(av)
+		}
+	}
+
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	return attributes
+}
@@ -0,0 +1,413 @@
+package dynamodbtest
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// read is what Query and Scan have in common: reading a page of items in key
+// order from a start key, then filtering and projecting them.
+type read struct {
+	table      *table
+	index      *index
+	items      []Item
+	forward    bool
+	start      Item
+	limit      *int64
+	filter     condition
+	projection []path
+	selection  string
+}
+
+type page struct {
+	items   []Item
+	count   int64
+	scanned int64
+	lastKey Item
+}
+
+func (r *read) run() (*page, error) {
+	items := r.items
+	if r.start != nil {
+		if err := r.table.validateStartKey(r.start, r.index); err != nil {
+			return nil, err
+		}
+
+		keys := r.table.positionKeys(r.index)
+		for len(items) > 0 {
+			order := compareItems(items[0], r.start, keys)
+			if (r.forward && order > 0) || (!r.forward && order < 0) {
+				break
+			}
+			items = items[1:]
+		}
+	}
+
+	limit := int(aws.Int64Value(r.limit))
+	p := &page{}
+
+	for i, item := range items {
+		if limit > 0 && i == limit {
+			p.lastKey = r.table.positionOf(items[i-1], r.index)
+			break
+		}
+		p.scanned++
+
+		ok, err := matches(item, r.filter)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		p.count++
+		switch r.selection {
+		case dynamodb.SelectCount:
+		case dynamodb.SelectAllAttributes:
+			p.items = append(p.items, cloneItem(item))
+		default:
+			p.items = append(p.items, project(r.table.project(item, r.index), r.projection))
+		}
+	}
+
+	if p.items == nil && r.selection != dynamodb.SelectCount {
+		p.items = []Item{}
+	}
+
+	return p, nil
+}
+
+// readIndex looks up the index a Query or Scan reads, or nil for the table, and
+// checks that the read options suit it.
+func readIndex(t *table, name *string, consistent *bool, selection *string, projection *string, limit *int64) (*index, error) {
+	var idx *index
+	if name != nil {
+		var ok bool
+		if idx, ok = t.indexes[*name]; !ok {
+			return nil, validationError("The table does not have the specified index: %s", *name)
+		}
+		if idx.global && aws.BoolValue(consistent) {
+			return nil, validationError("Consistent reads are not supported on global secondary indexes")
+		}
+	}
+
+	if limit != nil && *limit < 1 {
+		return nil, validationError("1 validation error detected: Value '%d' at 'limit' failed to satisfy constraint: Member must have value greater than or equal to 1", *limit)
+	}
+
+	switch aws.StringValue(selection) {
+	case "", dynamodb.SelectAllProjectedAttributes:
+	case dynamodb.SelectAllAttributes:
+		if idx != nil && idx.global && aws.StringValue(idx.projection.ProjectionType) != dynamodb.ProjectionTypeAll {
+			return nil, validationError("One or more parameter values were invalid: Select type ALL_ATTRIBUTES is not supported for global secondary index %s because its projection type is not ALL", idx.name)
+		}
+	case dynamodb.SelectCount:
+		if projection != nil {
+			return nil, validationError("Cannot specify the ProjectionExpression when choosing to get only the COUNT")
+		}
+	case dynamodb.SelectSpecificAttributes:
+		if projection == nil {
+			return nil, validationError("Must specify the ProjectionExpression when choosing to get SPECIFIC_ATTRIBUTES")
+		}
+	default:
+		return nil, validationError("Member must satisfy enum value set: %v", dynamodb.Select_Values())
+	}
+
+	return idx, nil
+}
+
+// Query reads the items of a table or secondary index whose keys satisfy the
+// KeyConditionExpression, in sort key order.
+func (f *Fake) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case input.KeyConditions != nil:
+		return nil, legacyParameter("KeyConditions")
+	case input.QueryFilter != nil || input.ConditionalOperator != nil:
+		return nil, legacyParameter("QueryFilter")
+	case input.AttributesToGet != nil:
+		return nil, legacyParameter("AttributesToGet")
+	case input.KeyConditionExpression == nil:
+		return nil, validationError("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+
+	idx, err := readIndex(t, input.IndexName, input.ConsistentRead, input.Select, input.ProjectionExpression, input.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := newExpressions(input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	keyCondition, err := e.parseCondition("KeyConditionExpression", input.KeyConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := e.parseCondition("FilterExpression", input.FilterExpression)
+	if err != nil {
+		return nil, err
+	}
+	projection, err := e.parseProjection(input.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	key := t.key
+	if idx != nil {
+		key = idx.key
+	}
+	if err := validateKeyCondition(keyCondition, key); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, item := range t.sorted(idx) {
+		ok, err := matches(item, keyCondition)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+
+	forward := input.ScanIndexForward == nil || *input.ScanIndexForward
+	if !forward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	p, err := (&read{
+		table:      t,
+		index:      idx,
+		items:      items,
+		forward:    forward,
+		start:      input.ExclusiveStartKey,
+		limit:      input.Limit,
+		filter:     filter,
+		projection: projection,
+		selection:  aws.StringValue(input.Select),
+	}).run()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.QueryOutput{
+		Items:            p.items,
+		Count:            aws.Int64(p.count),
+		ScannedCount:     aws.Int64(p.scanned),
+		LastEvaluatedKey: p.lastKey,
+	}, nil
+}
+
+func (f *Fake) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput, _ ...request.Option) (*dynamodb.QueryOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.Query(input)
+}
+
+func (f *Fake) QueryPages(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	return f.QueryPagesWithContext(aws.BackgroundContext(), input, fn)
+}
+
+func (f *Fake) QueryPagesWithContext(ctx aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, _ ...request.Option) error {
+	next := *input
+	for {
+		output, err := f.QueryWithContext(ctx, &next)
+		if err != nil {
+			return err
+		}
+
+		last := len(output.LastEvaluatedKey) == 0
+		if !fn(output, last) || last {
+			return nil
+		}
+		next.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// validateKeyCondition checks that c is a key condition DynamoDB accepts: an
+// equality on the partition key, optionally AND one condition on the sort key.
+func validateKeyCondition(c condition, key keySchema) error {
+	parts := []condition{c}
+	if and, ok := c.(*andCondition); ok {
+		parts = []condition{and.left, and.right}
+	}
+
+	var partition, sort bool
+	for _, part := range parts {
+		name, op, ok := keyConditionPart(part)
+		switch {
+		case !ok:
+			return validationError("Invalid operator used in KeyConditionExpression: the key condition must be a comparison, BETWEEN or begins_with on a key attribute")
+		case name == key.hash && op == "=" && !partition:
+			partition = true
+		case name == key.sortKey && key.sortKey != "" && !sort:
+			sort = true
+		default:
+			return validationError("Query key condition not supported")
+		}
+	}
+
+	if !partition {
+		return validationError("Query condition missed key schema element: %s", key.hash)
+	}
+
+	return nil
+}
+
+// keyConditionPart returns the attribute and operator of one condition of a
+// key condition, which must compare a key attribute with values.
+func keyConditionPart(c condition) (name string, op string, ok bool) {
+	isValue := func(o operand) bool {
+		_, ok := o.(*valueOperand)
+		return ok
+	}
+
+	switch c := c.(type) {
+	case *comparison:
+		name, ok := attributeName(c.left)
+		return name, c.op, ok && c.op != "<>" && isValue(c.right)
+	case *betweenCondition:
+		name, ok := attributeName(c.value)
+		return name, "BETWEEN", ok && isValue(c.low) && isValue(c.high)
+	case *functionCondition:
+		name, ok := attributeName(c.args[0])
+		return name, c.name, ok && c.name == "begins_with" && isValue(c.args[1])
+	}
+
+	return "", "", false
+}
+
+// attributeName returns the name of a top-level attribute operand.
+func attributeName(o operand) (string, bool) {
+	p, ok := o.(*pathOperand)
+	if !ok || len(p.path) != 1 {
+		return "", false
+	}
+
+	return p.path[0].name, true
+}
+
+// Scan reads every item of a table or secondary index, or of one segment of it
+// in a parallel scan.
+func (f *Fake) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case input.ScanFilter != nil || input.ConditionalOperator != nil:
+		return nil, legacyParameter("ScanFilter")
+	case input.AttributesToGet != nil:
+		return nil, legacyParameter("AttributesToGet")
+	case (input.Segment == nil) != (input.TotalSegments == nil):
+		return nil, validationError("The TotalSegments parameter is required but was not present in the request when Segment parameter is present")
+	case input.TotalSegments != nil && (*input.TotalSegments < 1 || *input.TotalSegments > 1000000):
+		return nil, validationError("TotalSegments must be between 1 and 1000000")
+	case input.Segment != nil && (*input.Segment < 0 || *input.Segment >= *input.TotalSegments):
+		return nil, validationError("The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: %d is not less than TotalSegments: %d", *input.Segment, *input.TotalSegments)
+	}
+
+	idx, err := readIndex(t, input.IndexName, input.ConsistentRead, input.Select, input.ProjectionExpression, input.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := newExpressions(input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := e.parseCondition("FilterExpression", input.FilterExpression)
+	if err != nil {
+		return nil, err
+	}
+	projection, err := e.parseProjection(input.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	items := t.sorted(idx)
+	if input.TotalSegments != nil {
+		var segment []Item
+		for _, item := range items {
+			if t.segment(item, *input.TotalSegments) == *input.Segment {
+				segment = append(segment, item)
+			}
+		}
+		items = segment
+	}
+
+	p, err := (&read{
+		table:      t,
+		index:      idx,
+		items:      items,
+		forward:    true,
+		start:      input.ExclusiveStartKey,
+		limit:      input.Limit,
+		filter:     filter,
+		projection: projection,
+		selection:  aws.StringValue(input.Select),
+	}).run()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ScanOutput{
+		Items:            p.items,
+		Count:            aws.Int64(p.count),
+		ScannedCount:     aws.Int64(p.scanned),
+		LastEvaluatedKey: p.lastKey,
+	}, nil
+}
+
+func (f *Fake) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	return f.Scan(input)
+}
+
+func (f *Fake) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	return f.ScanPagesWithContext(aws.BackgroundContext(), input, fn)
+}
+
+func (f *Fake) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	next := *input
+	for {
+		output, err := f.ScanWithContext(ctx, &next)
+		if err != nil {
+			return err
+		}
+
+		last := len(output.LastEvaluatedKey) == 0
+		if !fn(output, last) || last {
+			return nil
+		}
+		next.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
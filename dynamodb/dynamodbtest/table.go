@@ -0,0 +1,248 @@
+package dynamodbtest
+
+import (
+	"hash/fnv"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// keySchema names the partition key and, for composite keys, the sort key.
+type keySchema struct {
+	hash    string
+	sortKey string
+}
+
+func newKeySchema(elements []*dynamodb.KeySchemaElement) keySchema {
+	var k keySchema
+	for _, e := range elements {
+		switch aws.StringValue(e.KeyType) {
+		case dynamodb.KeyTypeHash:
+			k.hash = aws.StringValue(e.AttributeName)
+		case dynamodb.KeyTypeRange:
+			k.sortKey = aws.StringValue(e.AttributeName)
+		}
+	}
+
+	return k
+}
+
+func (k keySchema) names() []string {
+	if k.sortKey == "" {
+		return []string{k.hash}
+	}
+
+	return []string{k.hash, k.sortKey}
+}
+
+// index is a global or local secondary index.
+type index struct {
+	name       string
+	global     bool
+	key        keySchema
+	projection *dynamodb.Projection
+}
+
+type table struct {
+	description    *dynamodb.TableDescription
+	key            keySchema
+	attributeTypes map[string]string
+	indexes        map[string]*index
+	items          map[string]Item
+}
+
+// primaryKey returns the key attributes of item, checking that they are there
+// and have the declared types.
+func (t *table) primaryKey(item Item) (Item, error) {
+	key := Item{}
+	for _, name := range t.key.names() {
+		av, ok := item[name]
+		if !ok {
+			return nil, validationError("One or more parameter values were invalid: Missing the key %s in the item", name)
+		}
+		if actual := typeOf(av); actual != t.attributeTypes[name] {
+			return nil, validationError("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s", name, t.attributeTypes[name], actual)
+		}
+		if (av.S != nil && *av.S == "") || (av.B != nil && len(av.B) == 0) {
+			return nil, validationError("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+		}
+		key[name] = av
+	}
+
+	return key, nil
+}
+
+// validateKey checks that key holds exactly the key attributes of the table.
+func (t *table) validateKey(key Item) error {
+	if len(key) != len(t.key.names()) {
+		return validationError("The provided key element does not match the schema")
+	}
+
+	if _, err := t.primaryKey(key); err != nil {
+		return validationError("The provided key element does not match the schema")
+	}
+
+	return nil
+}
+
+// validateIndexKeys checks that the index key attributes item has are of the
+// declared types. Items without them are simply left out of the index.
+func (t *table) validateIndexKeys(item Item) error {
+	for _, idx := range t.sortedIndexes() {
+		for _, name := range idx.key.names() {
+			av, ok := item[name]
+			if !ok {
+				continue
+			}
+			if actual := typeOf(av); actual != t.attributeTypes[name] {
+				return validationError("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s IndexName: %s", name, t.attributeTypes[name], actual, idx.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t *table) sortedIndexes() []*index {
+	indexes := make([]*index, 0, len(t.indexes))
+	for _, idx := range t.indexes {
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].name < indexes[j].name })
+
+	return indexes
+}
+
+// storageKey identifies the item with key in t.items.
+func (t *table) storageKey(key Item) string {
+	s := encode(key[t.key.hash])
+	if t.key.sortKey != "" {
+		s += "\x00" + encode(key[t.key.sortKey])
+	}
+
+	return s
+}
+
+func (t *table) get(key Item) Item {
+	return t.items[t.storageKey(key)]
+}
+
+func (t *table) put(item Item) {
+	key, _ := t.primaryKey(item)
+	t.items[t.storageKey(key)] = item
+}
+
+func (t *table) remove(key Item) {
+	delete(t.items, t.storageKey(key))
+}
+
+// positionKeys returns the attributes that order the items of idx, or of the
+// table when idx is nil, and make up their LastEvaluatedKey.
+func (t *table) positionKeys(idx *index) []string {
+	if idx == nil {
+		return t.key.names()
+	}
+
+	names := idx.key.names()
+	for _, name := range t.key.names() {
+		if name != idx.key.hash && name != idx.key.sortKey {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// sorted returns the items of idx, or of the table when idx is nil, in key
+// order. Items without the index key attributes are not in the index.
+func (t *table) sorted(idx *index) []Item {
+	keys := t.positionKeys(idx)
+
+	items := make([]Item, 0, len(t.items))
+	for _, item := range t.items {
+		if idx != nil && !hasAll(item, idx.key.names()) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return compareItems(items[i], items[j], keys) < 0
+	})
+
+	return items
+}
+
+func hasAll(item Item, names []string) bool {
+	for _, name := range names {
+		if _, ok := item[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compareItems orders two items by the named attributes, in turn.
+func compareItems(a, b Item, names []string) int {
+	for _, name := range names {
+		if order, ok := compare(a[name], b[name]); ok && order != 0 {
+			return order
+		}
+	}
+
+	return 0
+}
+
+// positionOf returns the attributes of item that make up its LastEvaluatedKey.
+func (t *table) positionOf(item Item, idx *index) Item {
+	position := Item{}
+	for _, name := range t.positionKeys(idx) {
+		position[name] = clone(item[name])
+	}
+
+	return position
+}
+
+// validateStartKey checks that an ExclusiveStartKey holds the attributes of a
+// LastEvaluatedKey of idx.
+func (t *table) validateStartKey(start Item, idx *index) error {
+	keys := t.positionKeys(idx)
+	if len(start) != len(keys) || !hasAll(start, keys) {
+		return validationError("The provided starting key is invalid: The provided key element does not match the schema")
+	}
+
+	return nil
+}
+
+// project returns the attributes of item that idx holds, or the whole item when
+// idx is nil.
+func (t *table) project(item Item, idx *index) Item {
+	if idx == nil || aws.StringValue(idx.projection.ProjectionType) == dynamodb.ProjectionTypeAll {
+		return cloneItem(item)
+	}
+
+	projected := Item{}
+	names := append(t.key.names(), idx.key.names()...)
+	if aws.StringValue(idx.projection.ProjectionType) == dynamodb.ProjectionTypeInclude {
+		names = append(names, aws.StringValueSlice(idx.projection.NonKeyAttributes)...)
+	}
+
+	for _, name := range names {
+		if av, ok := item[name]; ok {
+			projected[name] = clone(av)
+		}
+	}
+
+	return projected
+}
+
+// segment returns the parallel scan segment item belongs to, by its partition
+// key.
+func (t *table) segment(item Item, total int64) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(encode(item[t.key.hash])))
+
+	return int64(h.Sum32()) % total
+}
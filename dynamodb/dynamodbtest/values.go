@@ -0,0 +1,397 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Item is a DynamoDB item as the API sends and returns it.
+type Item = map[string]*dynamodb.AttributeValue
+
+// Attribute type names, as used by attribute_type and AttributeDefinitions.
+const (
+	typeS    = "S"
+	typeN    = "N"
+	typeB    = "B"
+	typeBOOL = "BOOL"
+	typeNULL = "NULL"
+	typeSS   = "SS"
+	typeNS   = "NS"
+	typeBS   = "BS"
+	typeL    = "L"
+	typeM    = "M"
+)
+
+// typeOf returns the type of av, or "" if it sets no type or more than one.
+func typeOf(av *dynamodb.AttributeValue) string {
+	if av == nil {
+		return ""
+	}
+
+	var types []string
+	if av.S != nil {
+		types = append(types, typeS)
+	}
+	if av.N != nil {
+		types = append(types, typeN)
+	}
+	if av.B != nil {
+		types = append(types, typeB)
+	}
+	if av.BOOL != nil {
+		types = append(types, typeBOOL)
+	}
+	if av.NULL != nil {
+		types = append(types, typeNULL)
+	}
+	if av.SS != nil {
+		types = append(types, typeSS)
+	}
+	if av.NS != nil {
+		types = append(types, typeNS)
+	}
+	if av.BS != nil {
+		types = append(types, typeBS)
+	}
+	if av.L != nil {
+		types = append(types, typeL)
+	}
+	if av.M != nil {
+		types = append(types, typeM)
+	}
+
+	if len(types) != 1 {
+		return ""
+	}
+
+	return types[0]
+}
+
+// validateValue reports values DynamoDB rejects: values with no type or more
+// than one, numbers that do not parse, empty or duplicated sets and NULL false.
+func validateValue(av *dynamodb.AttributeValue) error {
+	switch typeOf(av) {
+	case "":
+		return validationError("Supplied AttributeValue must contain exactly one of the supported datatypes")
+	case typeN:
+		if _, ok := parseNumber(*av.N); !ok {
+			return validationError("The parameter cannot be converted to a numeric value: %s", *av.N)
+		}
+	case typeNULL:
+		if !*av.NULL {
+			return validationError("One or more parameter values were invalid: Null attribute value types must have the value of true")
+		}
+	case typeSS, typeNS, typeBS:
+		members := setMembers(av)
+		if len(members) == 0 {
+			return validationError("One or more parameter values were invalid: A set may not be empty")
+		}
+		seen := make(map[string]bool, len(members))
+		for _, m := range members {
+			if err := validateValue(m); err != nil {
+				return err
+			}
+			k := encode(m)
+			if seen[k] {
+				return validationError("One or more parameter values were invalid: Input collection contains duplicates")
+			}
+			seen[k] = true
+		}
+	case typeL:
+		for _, v := range av.L {
+			if err := validateValue(v); err != nil {
+				return err
+			}
+		}
+	case typeM:
+		for _, v := range av.M {
+			if err := validateValue(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateItem(item Item) error {
+	for _, av := range item {
+		if err := validateValue(av); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// numberPattern is the number syntax DynamoDB accepts: a decimal mantissa with
+// an optional exponent. big.Rat alone would also accept fractions and hex.
+var numberPattern = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+
+func parseNumber(s string) (*big.Rat, bool) {
+	s = strings.TrimSpace(s)
+	if !numberPattern.MatchString(s) {
+		return nil, false
+	}
+
+	return new(big.Rat).SetString(s)
+}
+
+// formatNumber writes r the way DynamoDB returns numbers: without an exponent
+// or trailing zeros.
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	s := strings.TrimRight(r.FloatString(decimalPlaces(r.Denom())), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// decimalPlaces returns how many places write 1/denom exactly. Numbers parsed
+// from decimals, and their sums and differences, have denominators made of
+// factors 2 and 5 only; any other denominator falls back to DynamoDB's 38
+// digits of precision.
+func decimalPlaces(denom *big.Int) int {
+	d := new(big.Int).Set(denom)
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+
+	five, fives := big.NewInt(5), 0
+	for q, m := new(big.Int), new(big.Int); ; fives++ {
+		q.QuoRem(d, five, m)
+		if m.Sign() != 0 {
+			break
+		}
+		d.Set(q)
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 38
+	}
+
+	return max(twos, fives)
+}
+
+// normalizeNumbers rewrites the numbers in av, which must not be shared with
+// the caller, the way DynamoDB stores them, so "1.50" is read back as "1.5".
+func normalizeNumbers(av *dynamodb.AttributeValue) {
+	normalize := func(n *string) {
+		if r, ok := parseNumber(*n); ok {
+			*n = formatNumber(r)
+		}
+	}
+
+	if av.N != nil {
+		normalize(av.N)
+	}
+	for _, n := range av.NS {
+		normalize(n)
+	}
+	for _, v := range av.L {
+		normalizeNumbers(v)
+	}
+	for _, v := range av.M {
+		normalizeNumbers(v)
+	}
+}
+
+// setMembers returns the members of a set as single values.
+func setMembers(av *dynamodb.AttributeValue) []*dynamodb.AttributeValue {
+	var members []*dynamodb.AttributeValue
+	for _, s := range av.SS {
+		members = append(members, &dynamodb.AttributeValue{S: s})
+	}
+	for _, n := range av.NS {
+		members = append(members, &dynamodb.AttributeValue{N: n})
+	}
+	for _, b := range av.BS {
+		members = append(members, &dynamodb.AttributeValue{B: b})
+	}
+
+	return members
+}
+
+// newSet builds a set of typ from single values.
+func newSet(typ string, members []*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	av := &dynamodb.AttributeValue{}
+	for _, m := range members {
+		switch typ {
+		case typeSS:
+			av.SS = append(av.SS, m.S)
+		case typeNS:
+			av.NS = append(av.NS, m.N)
+		case typeBS:
+			av.BS = append(av.BS, m.B)
+		}
+	}
+
+	return av
+}
+
+// encode returns a string that is equal for equal values, so values can be used
+// as map keys. Numbers are normalised and sets and maps are sorted.
+func encode(av *dynamodb.AttributeValue) string {
+	switch typeOf(av) {
+	case typeS:
+		return "S:" + *av.S
+	case typeN:
+		if r, ok := parseNumber(*av.N); ok {
+			return "N:" + r.RatString()
+		}
+		return "N:" + *av.N
+	case typeB:
+		return "B:" + base64.StdEncoding.EncodeToString(av.B)
+	case typeBOOL:
+		if *av.BOOL {
+			return "BOOL:true"
+		}
+		return "BOOL:false"
+	case typeNULL:
+		return "NULL"
+	case typeSS, typeNS, typeBS:
+		members := setMembers(av)
+		encoded := make([]string, len(members))
+		for i, m := range members {
+			encoded[i] = encode(m)
+		}
+		sort.Strings(encoded)
+		return typeOf(av) + ":{" + strings.Join(encoded, ",") + "}"
+	case typeL:
+		encoded := make([]string, len(av.L))
+		for i, v := range av.L {
+			encoded[i] = encode(v)
+		}
+		return "L:[" + strings.Join(encoded, ",") + "]"
+	case typeM:
+		names := make([]string, 0, len(av.M))
+		for name := range av.M {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		encoded := make([]string, len(names))
+		for i, name := range names {
+			encoded[i] = name + "=" + encode(av.M[name])
+		}
+		return "M:{" + strings.Join(encoded, ",") + "}"
+	}
+
+	return ""
+}
+
+func equal(a, b *dynamodb.AttributeValue) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	return encode(a) == encode(b)
+}
+
+// compare orders two strings, numbers or binaries of the same type. ok is false
+// for values that cannot be ordered against each other.
+func compare(a, b *dynamodb.AttributeValue) (result int, ok bool) {
+	ta, tb := typeOf(a), typeOf(b)
+	if ta != tb {
+		return 0, false
+	}
+
+	switch ta {
+	case typeS:
+		return strings.Compare(*a.S, *b.S), true
+	case typeN:
+		ra, okA := parseNumber(*a.N)
+		rb, okB := parseNumber(*b.N)
+		if !okA || !okB {
+			return 0, false
+		}
+		return ra.Cmp(rb), true
+	case typeB:
+		return bytes.Compare(a.B, b.B), true
+	}
+
+	return 0, false
+}
+
+// clone returns a deep copy of av, so stored items never share memory with the
+// caller.
+func clone(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av == nil {
+		return nil
+	}
+
+	c := &dynamodb.AttributeValue{}
+	if av.S != nil {
+		c.S = copyString(av.S)
+	}
+	if av.N != nil {
+		c.N = copyString(av.N)
+	}
+	if av.B != nil {
+		c.B = append([]byte{}, av.B...)
+	}
+	if av.BOOL != nil {
+		v := *av.BOOL
+		c.BOOL = &v
+	}
+	if av.NULL != nil {
+		v := *av.NULL
+		c.NULL = &v
+	}
+	if av.SS != nil {
+		c.SS = make([]*string, len(av.SS))
+		for i, s := range av.SS {
+			c.SS[i] = copyString(s)
+		}
+	}
+	if av.NS != nil {
+		c.NS = make([]*string, len(av.NS))
+		for i, n := range av.NS {
+			c.NS[i] = copyString(n)
+		}
+	}
+	if av.BS != nil {
+		c.BS = make([][]byte, len(av.BS))
+		for i, b := range av.BS {
+			c.BS[i] = append([]byte{}, b...)
+		}
+	}
+	if av.L != nil {
+		c.L = make([]*dynamodb.AttributeValue, len(av.L))
+		for i, v := range av.L {
+			c.L[i] = clone(v)
+		}
+	}
+	if av.M != nil {
+		c.M = cloneItem(av.M)
+	}
+
+	return c
+}
+
+func cloneItem(item Item) Item {
+	if item == nil {
+		return nil
+	}
+
+	c := make(Item, len(item))
+	for name, av := range item {
+		c[name] = clone(av)
+	}
+
+	return c
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+
+	v := *s
+	return &v
+}
@@ -0,0 +1,423 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrDecryptionFailed  = errors.New("attribute decryption failed")
+	ErrSignatureMismatch = errors.New("item signature does not match its attributes")
+	ErrUnsignedItem      = errors.New("item has no encryption materials")
+)
+
+var reservedAttributes = []string{DataKeyAttribute, KeyIDAttribute, EncryptedAttributesAttribute, SignatureAttribute}
+
+// WithEncryption makes PutItem encrypt attributes before they are written and
+// GetItem decrypt them after they are read. Every item gets its own data key
+// from provider, which is stored wrapped next to the item together with the
+// provider's key ID. Each attribute named in attributes is sealed with AES-GCM
+// and bound to its name, and every attribute, encrypted or not, is covered by
+// an HMAC signature so GetItem rejects items changed outside this client. Key
+// attributes cannot be encrypted, since DynamoDB must read them. It returns the
+// client to allow chaining.
+func (d *DynamoDBClient) WithEncryption(provider KeyProvider, attributes ...string) *DynamoDBClient {
+	d.keyProvider = provider
+	d.encryptedAttributes = make(map[string]bool, len(attributes))
+	for _, name := range attributes {
+		d.encryptedAttributes[name] = true
+	}
+
+	return d
+}
+
+// EncryptItem returns a copy of item with the encrypted attributes sealed and the
+// encryption materials and signature added. Items are returned unchanged when
+// encryption is not enabled.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the key provider.
+//	item (map[string]*dynamodb.AttributeValue): The plaintext item.
+//
+// Returns:
+//
+//	(map[string]*dynamodb.AttributeValue, error): The item to write, or an error if it could not be encrypted.
+func (d *DynamoDBClient) EncryptItem(ctx context.Context, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	if d.keyProvider == nil {
+		return item, nil
+	}
+
+	for _, name := range reservedAttributes {
+		if _, ok := item[name]; ok {
+			return nil, fmt.Errorf("attribute %s is reserved for encryption materials", name)
+		}
+	}
+
+	for name := range d.encryptedAttributes {
+		if d.isKeyAttribute(name) {
+			return nil, fmt.Errorf("key attribute %s cannot be encrypted", name)
+		}
+	}
+
+	encryptionContext, err := d.encryptionContext(item)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := d.keyProvider.GenerateDataKey(ctx, encryptionContext)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(deriveKey(dataKey.Plaintext, "attribute-encryption"))
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make(map[string]*dynamodb.AttributeValue, len(item)+len(reservedAttributes))
+	var encrypted []string
+
+	for name, av := range item {
+		if !d.encryptedAttributes[name] {
+			sealed[name] = av
+			continue
+		}
+
+		plaintext, err := json.Marshal(attributeValueToDynamoDBJSON(av))
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext, err := seal(aead, plaintext, d.attributeAAD(name))
+		if err != nil {
+			return nil, err
+		}
+
+		sealed[name] = &dynamodb.AttributeValue{B: ciphertext}
+		encrypted = append(encrypted, name)
+	}
+
+	sealed[DataKeyAttribute] = &dynamodb.AttributeValue{B: dataKey.Wrapped}
+	sealed[KeyIDAttribute] = &dynamodb.AttributeValue{S: aws.String(dataKey.KeyID)}
+
+	if len(encrypted) > 0 {
+		sort.Strings(encrypted)
+		sealed[EncryptedAttributesAttribute] = &dynamodb.AttributeValue{SS: aws.StringSlice(encrypted)}
+	}
+
+	signature, err := d.sign(deriveKey(dataKey.Plaintext, "item-signature"), sealed)
+	if err != nil {
+		return nil, err
+	}
+	sealed[SignatureAttribute] = &dynamodb.AttributeValue{B: signature}
+
+	return sealed, nil
+}
+
+// DecryptItem verifies the signature of an item written by EncryptItem and
+// returns a copy with the encrypted attributes opened and the encryption
+// materials removed. Items are returned unchanged when encryption is not enabled.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the key provider.
+//	item (map[string]*dynamodb.AttributeValue): The item as stored.
+//
+// Returns:
+//
+//	(map[string]*dynamodb.AttributeValue, error): The plaintext item, or ErrUnsignedItem, ErrSignatureMismatch or ErrDecryptionFailed.
+func (d *DynamoDBClient) DecryptItem(ctx context.Context, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	if d.keyProvider == nil {
+		return item, nil
+	}
+
+	wrapped, keyID, signature := item[DataKeyAttribute], item[KeyIDAttribute], item[SignatureAttribute]
+	if wrapped == nil || wrapped.B == nil || keyID == nil || keyID.S == nil || signature == nil || signature.B == nil {
+		return nil, ErrUnsignedItem
+	}
+
+	encryptionContext, err := d.encryptionContext(item)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintextKey, err := d.keyProvider.DecryptDataKey(ctx, wrapped.B, aws.StringValue(keyID.S), encryptionContext)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, av := range item {
+		if name != SignatureAttribute {
+			signed[name] = av
+		}
+	}
+
+	expected, err := d.sign(deriveKey(plaintextKey, "item-signature"), signed)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(expected, signature.B) {
+		return nil, ErrSignatureMismatch
+	}
+
+	aead, err := newAEAD(deriveKey(plaintextKey, "attribute-encryption"))
+	if err != nil {
+		return nil, err
+	}
+
+	opened := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, av := range item {
+		opened[name] = av
+	}
+
+	for _, name := range reservedAttributes {
+		delete(opened, name)
+	}
+
+	if encrypted := item[EncryptedAttributesAttribute]; encrypted != nil {
+		for _, name := range aws.StringValueSlice(encrypted.SS) {
+			av, err := d.openAttribute(aead, name, item[name])
+			if err != nil {
+				return nil, err
+			}
+			opened[name] = av
+		}
+	}
+
+	return opened, nil
+}
+
+func (d *DynamoDBClient) openAttribute(aead cipher.AEAD, name string, av *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	if av == nil || av.B == nil {
+		return nil, fmt.Errorf("attribute %s: %w", name, ErrDecryptionFailed)
+	}
+
+	plaintext, err := open(aead, av.B, d.attributeAAD(name))
+	if err != nil {
+		return nil, fmt.Errorf("attribute %s: %w", name, err)
+	}
+
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(plaintext, &typed); err != nil {
+		return nil, fmt.Errorf("attribute %s: %w", name, ErrDecryptionFailed)
+	}
+
+	return attributeValueFromDynamoDBJSON(typed)
+}
+
+func (d *DynamoDBClient) isKeyAttribute(name string) bool {
+	if name == d.keySchema.HashKey || name == d.keySchema.RangeKey {
+		return true
+	}
+
+	for _, gsi := range d.gsiKeySchema {
+		if name == gsi.HashKey || name == gsi.RangeKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encryptionContext binds a data key to the table and the primary key of the
+// item it protects, so that sealed attributes cannot be moved to another item.
+func (d *DynamoDBClient) encryptionContext(item map[string]*dynamodb.AttributeValue) (map[string]string, error) {
+	encryptionContext := map[string]string{"table": d.tableName}
+
+	for _, name := range []string{d.keySchema.HashKey, d.keySchema.RangeKey} {
+		if name == "" {
+			continue
+		}
+
+		av := item[name]
+		switch {
+		case av == nil:
+			return nil, fmt.Errorf("item is missing key attribute %s", name)
+		case av.S != nil:
+			encryptionContext["key:"+name] = *av.S
+		case av.N != nil:
+			n, err := canonicalNumber(*av.N)
+			if err != nil {
+				return nil, fmt.Errorf("key attribute %s: %w", name, err)
+			}
+			encryptionContext["key:"+name] = n
+		case av.B != nil:
+			encryptionContext["key:"+name] = base64.StdEncoding.EncodeToString(av.B)
+		default:
+			return nil, fmt.Errorf("key attribute %s must be a string, number or binary", name)
+		}
+	}
+
+	return encryptionContext, nil
+}
+
+func (d *DynamoDBClient) attributeAAD(name string) []byte {
+	return []byte(d.tableName + "\x00" + name)
+}
+
+// sign computes an HMAC-SHA256 over the table name and every attribute, in name
+// order, with sets sorted since DynamoDB does not preserve their order and
+// numbers in the form DynamoDB returns them.
+func (d *DynamoDBClient) sign(key []byte, item map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mac := hmac.New(sha256.New, key)
+	writeField := func(data []byte) {
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+		mac.Write(data)
+	}
+
+	writeField([]byte(d.tableName))
+	for _, name := range names {
+		canonical, err := canonicalAttribute(attributeValueToDynamoDBJSON(item[name]))
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+
+		value, err := json.Marshal(canonical)
+		if err != nil {
+			return nil, err
+		}
+
+		writeField([]byte(name))
+		writeField(value)
+	}
+
+	return mac.Sum(nil), nil
+}
+
+func canonicalAttribute(typed map[string]interface{}) (map[string]interface{}, error) {
+	for typ, value := range typed {
+		switch typ {
+		case "N":
+			n, err := canonicalNumber(value.(string))
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{typ: n}, nil
+		case "NS":
+			set := make([]string, len(value.([]string)))
+			for i, v := range value.([]string) {
+				n, err := canonicalNumber(v)
+				if err != nil {
+					return nil, err
+				}
+				set[i] = n
+			}
+			sort.Strings(set)
+			return map[string]interface{}{typ: set}, nil
+		case "SS":
+			set := append([]string{}, value.([]string)...)
+			sort.Strings(set)
+			return map[string]interface{}{typ: set}, nil
+		case "BS":
+			set := append([][]byte{}, value.([][]byte)...)
+			sort.Slice(set, func(i, j int) bool { return bytes.Compare(set[i], set[j]) < 0 })
+			return map[string]interface{}{typ: set}, nil
+		case "L":
+			list := value.([]interface{})
+			canonical := make([]interface{}, len(list))
+			for i, v := range list {
+				c, err := canonicalAttribute(v.(map[string]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				canonical[i] = c
+			}
+			return map[string]interface{}{typ: canonical}, nil
+		case "M":
+			m := value.(map[string]interface{})
+			canonical := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				c, err := canonicalAttribute(v.(map[string]interface{}))
+				if err != nil {
+					return nil, err
+				}
+				canonical[k] = c
+			}
+			return map[string]interface{}{typ: canonical}, nil
+		}
+	}
+
+	return typed, nil
+}
+
+var numberPattern = regexp.MustCompile(`^([+-]?)([0-9]*)(?:\.([0-9]*))?(?:[eE]([+-]?[0-9]+))?$`)
+
+// canonicalNumber rewrites n the way DynamoDB stores it: without a plus sign,
+// an exponent, or leading and trailing zeros, so that "1.50" becomes "1.5" and
+// "1e2" becomes "100". Numbers outside the range DynamoDB accepts are rejected.
+func canonicalNumber(n string) (string, error) {
+	m := numberPattern.FindStringSubmatch(strings.TrimSpace(n))
+	if m == nil || m[2]+m[3] == "" {
+		return "", fmt.Errorf("invalid number %q", n)
+	}
+
+	digits := m[2] + m[3]
+	exponent := -len(m[3])
+	if m[4] != "" {
+		e, err := strconv.Atoi(m[4])
+		if err != nil {
+			return "", fmt.Errorf("number %q is out of range", n)
+		}
+		exponent += e
+	}
+
+	// Drop leading zeros and fold trailing zeros into the exponent.
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0", nil
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exponent += len(digits) - len(trimmed)
+	digits = trimmed
+
+	if magnitude := len(digits) + exponent - 1; magnitude < -130 || magnitude > 125 {
+		return "", fmt.Errorf("number %q is out of range", n)
+	}
+
+	var canonical string
+	switch {
+	case exponent >= 0:
+		canonical = digits + strings.Repeat("0", exponent)
+	case -exponent < len(digits):
+		canonical = digits[:len(digits)+exponent] + "." + digits[len(digits)+exponent:]
+	default:
+		canonical = "0." + strings.Repeat("0", -exponent-len(digits)) + digits
+	}
+
+	if m[1] == "-" {
+		canonical = "-" + canonical
+	}
+
+	return canonical, nil
+}
+
+// deriveKey derives an independent key for purpose from a data key.
+func deriveKey(dataKey []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,332 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newEncryptedClient(t *testing.T, attributes ...string) (*DynamoDBClient, *mockDynamoDBClient) {
+	t.Helper()
+
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, mockClient, err := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+	require.NoError(t, err)
+
+	provider, err := NewStaticKeyProvider("local", bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	return dynamoClient.WithEncryption(provider, attributes...), mockClient
+}
+
+// putAndCapture writes item through the client and returns the item sent to DynamoDB.
+func putAndCapture(t *testing.T, dynamoClient *DynamoDBClient, mockClient *mockDynamoDBClient, item map[string]interface{}) map[string]*dynamodb.AttributeValue {
+	t.Helper()
+
+	var stored map[string]*dynamodb.AttributeValue
+	mockClient.On("PutItem", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*dynamodb.PutItemInput).Item
+	}).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	_, err := dynamoClient.PutItem(item)
+	require.NoError(t, err)
+
+	return stored
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	dynamoClient, mockClient := newEncryptedClient(t, "ssn", "tags")
+
+	stored := putAndCapture(t, dynamoClient, mockClient, map[string]interface{}{
+		"id":   "123",
+		"name": "test",
+		"ssn":  "123-45-6789",
+		"tags": []string{"a", "b"},
+	})
+
+	assert.Equal(t, "test", aws.StringValue(stored["name"].S))
+	assert.NotNil(t, stored["ssn"].B)
+	assert.NotContains(t, string(stored["ssn"].B), "123-45-6789")
+	assert.NotNil(t, stored["tags"].B)
+	assert.Equal(t, "local", aws.StringValue(stored[KeyIDAttribute].S))
+	assert.Equal(t, []string{"ssn", "tags"}, aws.StringValueSlice(stored[EncryptedAttributesAttribute].SS))
+	assert.NotEmpty(t, stored[DataKeyAttribute].B)
+	assert.NotEmpty(t, stored[SignatureAttribute].B)
+
+	mockClient.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{Item: stored}, nil)
+
+	output, err := dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*dynamodb.AttributeValue{
+		"id":   {S: aws.String("123")},
+		"name": {S: aws.String("test")},
+		"ssn":  {S: aws.String("123-45-6789")},
+		"tags": {L: []*dynamodb.AttributeValue{{S: aws.String("a")}, {S: aws.String("b")}}},
+	}, output.Item)
+}
+
+func TestEncryptionDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(item map[string]*dynamodb.AttributeValue)
+		wantErr error
+	}{
+		{
+			name: "plaintext attribute changed",
+			tamper: func(item map[string]*dynamodb.AttributeValue) {
+				item["name"] = &dynamodb.AttributeValue{S: aws.String("other")}
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name: "attribute added",
+			tamper: func(item map[string]*dynamodb.AttributeValue) {
+				item["admin"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+			},
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name:    "ciphertext changed",
+			tamper:  func(item map[string]*dynamodb.AttributeValue) { item["ssn"].B[len(item["ssn"].B)-1] ^= 1 },
+			wantErr: ErrSignatureMismatch,
+		},
+		{
+			name:    "signature removed",
+			tamper:  func(item map[string]*dynamodb.AttributeValue) { delete(item, SignatureAttribute) },
+			wantErr: ErrUnsignedItem,
+		},
+		{
+			name: "item moved to another key",
+			tamper: func(item map[string]*dynamodb.AttributeValue) {
+				item["id"] = &dynamodb.AttributeValue{S: aws.String("456")}
+			},
+			wantErr: ErrDecryptionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoClient, mockClient := newEncryptedClient(t, "ssn")
+
+			stored := putAndCapture(t, dynamoClient, mockClient, map[string]interface{}{
+				"id":   "123",
+				"name": "test",
+				"ssn":  "123-45-6789",
+			})
+			tt.tamper(stored)
+
+			_, err := dynamoClient.DecryptItem(context.Background(), stored)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestEncryptionSealsAttributesToTheirNames(t *testing.T) {
+	dynamoClient, mockClient := newEncryptedClient(t, "ssn", "pin")
+
+	stored := putAndCapture(t, dynamoClient, mockClient, map[string]interface{}{
+		"id":  "123",
+		"ssn": "123-45-6789",
+		"pin": "0000",
+	})
+
+	// Swapping ciphertexts would also break the signature, so open them directly.
+	aead, err := newAEAD(deriveKey(dataKeyFor(t, dynamoClient, stored), "attribute-encryption"))
+	require.NoError(t, err)
+
+	opened, err := dynamoClient.openAttribute(aead, "ssn", stored["ssn"])
+	require.NoError(t, err)
+	assert.Equal(t, "123-45-6789", aws.StringValue(opened.S))
+
+	_, err = dynamoClient.openAttribute(aead, "pin", stored["ssn"])
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestEncryptionRejectsUnknownKeyID(t *testing.T) {
+	dynamoClient, mockClient := newEncryptedClient(t, "ssn")
+
+	stored := putAndCapture(t, dynamoClient, mockClient, map[string]interface{}{"id": "123", "ssn": "123-45-6789"})
+
+	provider, err := NewStaticKeyProvider("rotated", bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+	dynamoClient.WithEncryption(provider, "ssn")
+
+	_, err = dynamoClient.DecryptItem(context.Background(), stored)
+
+	assert.EqualError(t, err, `unknown key ID "local"`)
+}
+
+func TestEncryptionRejectsKeyAttributes(t *testing.T) {
+	dynamoClient, mockClient := newEncryptedClient(t, "id")
+
+	_, err := dynamoClient.PutItem(map[string]interface{}{"id": "123"})
+
+	assert.EqualError(t, err, "key attribute id cannot be encrypted")
+	mockClient.AssertNotCalled(t, "PutItem", mock.Anything)
+}
+
+func TestEncryptionRejectsReservedAttributes(t *testing.T) {
+	dynamoClient, _ := newEncryptedClient(t, "ssn")
+
+	_, err := dynamoClient.PutItem(map[string]interface{}{"id": "123", SignatureAttribute: "forged"})
+
+	assert.EqualError(t, err, "attribute _encSignature is reserved for encryption materials")
+}
+
+func TestEncryptionSignatureIgnoresSetOrder(t *testing.T) {
+	dynamoClient, mockClient := newEncryptedClient(t)
+
+	stored := putAndCapture(t, dynamoClient, mockClient, map[string]interface{}{"id": "123"})
+	stored["roles"] = &dynamodb.AttributeValue{SS: aws.StringSlice([]string{"admin", "user"})}
+
+	key := dataKeyFor(t, dynamoClient, stored)
+	signature, err := dynamoClient.sign(deriveKey(key, "item-signature"), withoutSignature(stored))
+	require.NoError(t, err)
+	stored[SignatureAttribute] = &dynamodb.AttributeValue{B: signature}
+	stored["roles"].SS = aws.StringSlice([]string{"user", "admin"})
+
+	_, err = dynamoClient.DecryptItem(context.Background(), stored)
+
+	assert.NoError(t, err)
+}
+
+func TestEncryptionSignatureCanonicalizesNumbers(t *testing.T) {
+	dynamoClient, mockClient := newEncryptedClient(t)
+
+	stored := putAndCapture(t, dynamoClient, mockClient, map[string]interface{}{"id": "123"})
+	stored["price"] = &dynamodb.AttributeValue{N: aws.String("1.50")}
+	stored["limits"] = &dynamodb.AttributeValue{NS: aws.StringSlice([]string{"1e2", "0.5"})}
+
+	key := dataKeyFor(t, dynamoClient, stored)
+	signature, err := dynamoClient.sign(deriveKey(key, "item-signature"), withoutSignature(stored))
+	require.NoError(t, err)
+	stored[SignatureAttribute] = &dynamodb.AttributeValue{B: signature}
+
+	// DynamoDB returns the numbers in their canonical form.
+	stored["price"].N = aws.String("1.5")
+	stored["limits"].NS = aws.StringSlice([]string{"100", ".5"})
+
+	_, err = dynamoClient.DecryptItem(context.Background(), stored)
+
+	assert.NoError(t, err)
+}
+
+func TestEncryptionContextCanonicalizesNumbers(t *testing.T) {
+	dynamoClient, _ := newEncryptedClient(t)
+
+	written, err := dynamoClient.encryptionContext(map[string]*dynamodb.AttributeValue{"id": {N: aws.String("1e2")}})
+	require.NoError(t, err)
+	read, err := dynamoClient.encryptionContext(map[string]*dynamodb.AttributeValue{"id": {N: aws.String("100")}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "100", written["key:id"])
+	assert.Equal(t, written, read)
+}
+
+func TestCanonicalNumber(t *testing.T) {
+	tests := []struct {
+		n, want string
+	}{
+		{"1.50", "1.5"},
+		{"1e2", "100"},
+		{"+007", "7"},
+		{"-0.0", "0"},
+		{"-12.340E-3", "-0.01234"},
+		{"15e-1", "1.5"},
+		{".5", "0.5"},
+		{"100.", "100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			got, err := canonicalNumber(tt.n)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, n := range []string{"", ".", "1/2", "0x10", "1e", "1e200", "1e-200", "NaN"} {
+		_, err := canonicalNumber(n)
+		assert.Error(t, err, n)
+	}
+}
+
+func TestEncryptionDisabledPassesItemsThrough(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, _, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+	item := map[string]*dynamodb.AttributeValue{"id": {S: aws.String("123")}}
+
+	encrypted, err := dynamoClient.EncryptItem(context.Background(), item)
+	require.NoError(t, err)
+	decrypted, err := dynamoClient.DecryptItem(context.Background(), item)
+	require.NoError(t, err)
+
+	assert.Equal(t, item, encrypted)
+	assert.Equal(t, item, decrypted)
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	mockKMS := new(mockKMSClient)
+	provider := &KMSKeyProvider{keyID: "alias/items", client: mockKMS}
+	encryptionContext := map[string]string{"table": "test-table", "key:id": "123"}
+
+	mockKMS.On("GenerateDataKeyWithContext", mock.Anything, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String("alias/items"),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: aws.StringMap(encryptionContext),
+	}).Return(&kms.GenerateDataKeyOutput{
+		Plaintext:      []byte("plaintext"),
+		CiphertextBlob: []byte("wrapped"),
+		KeyId:          aws.String("arn:aws:kms:us-east-1:123456789012:key/1"),
+	}, nil)
+	mockKMS.On("DecryptWithContext", mock.Anything, &kms.DecryptInput{
+		CiphertextBlob:    []byte("wrapped"),
+		KeyId:             aws.String("arn:aws:kms:us-east-1:123456789012:key/1"),
+		EncryptionContext: aws.StringMap(encryptionContext),
+	}).Return(&kms.DecryptOutput{Plaintext: []byte("plaintext")}, nil)
+
+	dataKey, err := provider.GenerateDataKey(context.Background(), encryptionContext)
+	require.NoError(t, err)
+	assert.Equal(t, DataKey{
+		Plaintext: []byte("plaintext"),
+		Wrapped:   []byte("wrapped"),
+		KeyID:     "arn:aws:kms:us-east-1:123456789012:key/1",
+	}, dataKey)
+
+	plaintext, err := provider.DecryptDataKey(context.Background(), dataKey.Wrapped, dataKey.KeyID, encryptionContext)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("plaintext"), plaintext)
+	mockKMS.AssertExpectations(t)
+}
+
+func dataKeyFor(t *testing.T, dynamoClient *DynamoDBClient, item map[string]*dynamodb.AttributeValue) []byte {
+	t.Helper()
+
+	encryptionContext, err := dynamoClient.encryptionContext(item)
+	require.NoError(t, err)
+
+	key, err := dynamoClient.keyProvider.DecryptDataKey(context.Background(), item[DataKeyAttribute].B, aws.StringValue(item[KeyIDAttribute].S), encryptionContext)
+	require.NoError(t, err)
+
+	return key
+}
+
+func withoutSignature(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	signed := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, av := range item {
+		if name != SignatureAttribute {
+			signed[name] = av
+		}
+	}
+	return signed
+}
@@ -0,0 +1,187 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ExportToS3 starts a point-in-time export of the table into an S3 bucket.
+// The table must have point-in-time recovery enabled. The export runs in the
+// background; use WaitForExport to block until it finishes.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the request.
+//	input (ExportInput): The destination bucket, prefix, format and export time.
+//
+// Returns:
+//
+//	(*dynamodb.ExportDescription, error): The description of the started export, or an error if the operation failed.
+func (d *DynamoDBClient) ExportToS3(ctx context.Context, input ExportInput) (*dynamodb.ExportDescription, error) {
+	if err := validateExportInput(input); err != nil {
+		return nil, err
+	}
+
+	table, err := d.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(d.tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	format := input.Format
+	if format == "" {
+		format = ExportFormatDynamoDBJSON
+	}
+
+	exportInput := &dynamodb.ExportTableToPointInTimeInput{
+		TableArn:     table.Table.TableArn,
+		S3Bucket:     aws.String(input.Bucket),
+		ExportFormat: aws.String(format),
+	}
+
+	if input.Prefix != "" {
+		exportInput.S3Prefix = aws.String(input.Prefix)
+	}
+
+	if !input.ExportTime.IsZero() {
+		exportInput.ExportTime = aws.Time(input.ExportTime)
+	}
+
+	output, err := d.client.ExportTableToPointInTimeWithContext(ctx, exportInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.ExportDescription, nil
+}
+
+// WaitForExport polls an export started by ExportToS3 until it completes.
+// An export that ends in the FAILED state is reported as an error.
+func (d *DynamoDBClient) WaitForExport(ctx context.Context, exportArn string, opts WaitOptions) (*dynamodb.ExportDescription, error) {
+	var description *dynamodb.ExportDescription
+
+	err := pollUntil(ctx, opts, func(ctx context.Context) (bool, error) {
+		output, err := d.client.DescribeExportWithContext(ctx, &dynamodb.DescribeExportInput{
+			ExportArn: aws.String(exportArn),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		description = output.ExportDescription
+
+		switch aws.StringValue(description.ExportStatus) {
+		case dynamodb.ExportStatusCompleted:
+			return true, nil
+		case dynamodb.ExportStatusFailed:
+			return false, fmt.Errorf("export %s failed: %s", exportArn, aws.StringValue(description.FailureMessage))
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return description, nil
+}
+
+// ImportFromS3 creates the table from data stored in S3, using the client's
+// key schema and GSI definitions. The table must not exist yet. The import runs
+// in the background; use WaitForImport to block until it finishes.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the request.
+//	input (ImportInput): The source bucket, key prefix, format and compression.
+//
+// Returns:
+//
+//	(*dynamodb.ImportTableDescription, error): The description of the started import, or an error if the operation failed.
+func (d *DynamoDBClient) ImportFromS3(ctx context.Context, input ImportInput) (*dynamodb.ImportTableDescription, error) {
+	if err := validateImportInput(input); err != nil {
+		return nil, err
+	}
+
+	attributeDefinitions, keySchema, globalSecondaryIndexes := buildTableDefinition(d.keySchema, d.gsiKeySchema)
+
+	tableParameters := &dynamodb.TableCreationParameters{
+		TableName:            aws.String(d.tableName),
+		AttributeDefinitions: attributeDefinitions,
+		KeySchema:            keySchema,
+		BillingMode:          aws.String(dynamodb.BillingModeProvisioned),
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(d.keySchema.ReadCapacityUnits),
+			WriteCapacityUnits: aws.Int64(d.keySchema.WriteCapacityUnits),
+		},
+	}
+
+	if len(globalSecondaryIndexes) > 0 {
+		tableParameters.GlobalSecondaryIndexes = globalSecondaryIndexes
+	}
+
+	format := input.Format
+	if format == "" {
+		format = ExportFormatDynamoDBJSON
+	}
+
+	compressionType := input.CompressionType
+	if compressionType == "" {
+		compressionType = CompressionTypeNone
+	}
+
+	source := &dynamodb.S3BucketSource{
+		S3Bucket: aws.String(input.Bucket),
+	}
+
+	if input.KeyPrefix != "" {
+		source.S3KeyPrefix = aws.String(input.KeyPrefix)
+	}
+
+	output, err := d.client.ImportTableWithContext(ctx, &dynamodb.ImportTableInput{
+		InputFormat:             aws.String(format),
+		InputCompressionType:    aws.String(compressionType),
+		S3BucketSource:          source,
+		TableCreationParameters: tableParameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.ImportTableDescription, nil
+}
+
+// WaitForImport polls an import started by ImportFromS3 until it completes.
+// An import that ends FAILED or CANCELLED is reported as an error.
+func (d *DynamoDBClient) WaitForImport(ctx context.Context, importArn string, opts WaitOptions) (*dynamodb.ImportTableDescription, error) {
+	var description *dynamodb.ImportTableDescription
+
+	err := pollUntil(ctx, opts, func(ctx context.Context) (bool, error) {
+		output, err := d.client.DescribeImportWithContext(ctx, &dynamodb.DescribeImportInput{
+			ImportArn: aws.String(importArn),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		description = output.ImportTableDescription
+
+		switch aws.StringValue(description.ImportStatus) {
+		case dynamodb.ImportStatusCompleted:
+			return true, nil
+		case dynamodb.ImportStatusFailed, dynamodb.ImportStatusCancelled:
+			return false, fmt.Errorf("import %s ended with status %s: %s", importArn, aws.StringValue(description.ImportStatus), aws.StringValue(description.FailureMessage))
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return description, nil
+}
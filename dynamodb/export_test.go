@@ -0,0 +1,196 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExportToS3(t *testing.T) {
+	tableName := "test-table"
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+
+	t.Run("Valid export", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient(tableName, keySchemaInput, nil)
+		exportTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		mockClient.On("DescribeTableWithContext", mock.Anything, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		}).Return(&dynamodb.DescribeTableOutput{
+			Table: &dynamodb.TableDescription{TableArn: aws.String("arn:aws:dynamodb:us-east-1:123:table/test-table")},
+		}, nil)
+		mockClient.On("ExportTableToPointInTimeWithContext", mock.Anything, &dynamodb.ExportTableToPointInTimeInput{
+			TableArn:     aws.String("arn:aws:dynamodb:us-east-1:123:table/test-table"),
+			S3Bucket:     aws.String("brunojet-storage"),
+			S3Prefix:     aws.String("exports/"),
+			ExportFormat: aws.String(ExportFormatIon),
+			ExportTime:   aws.Time(exportTime),
+		}).Return(&dynamodb.ExportTableToPointInTimeOutput{
+			ExportDescription: &dynamodb.ExportDescription{ExportArn: aws.String("export-arn")},
+		}, nil)
+
+		output, err := dynamoClient.ExportToS3(context.Background(), ExportInput{
+			Bucket:     "brunojet-storage",
+			Prefix:     "exports/",
+			Format:     ExportFormatIon,
+			ExportTime: exportTime,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "export-arn", *output.ExportArn)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Missing bucket", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient(tableName, keySchemaInput, nil)
+
+		output, err := dynamoClient.ExportToS3(context.Background(), ExportInput{})
+
+		assert.Nil(t, output)
+		assert.EqualError(t, err, "export bucket cannot be empty")
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Describe table error", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient(tableName, keySchemaInput, nil)
+
+		mockClient.On("DescribeTableWithContext", mock.Anything, mock.Anything).Return((*dynamodb.DescribeTableOutput)(nil), errors.New("not found"))
+
+		output, err := dynamoClient.ExportToS3(context.Background(), ExportInput{Bucket: "brunojet-storage"})
+
+		assert.Nil(t, output)
+		assert.EqualError(t, err, "not found")
+		mockClient.AssertExpectations(t)
+	})
+}
+
+func TestWaitForExport(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	opts := WaitOptions{PollInterval: time.Millisecond}
+
+	t.Run("Completes after polling", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		mockClient.On("DescribeExportWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeExportOutput{
+			ExportDescription: &dynamodb.ExportDescription{ExportStatus: aws.String(dynamodb.ExportStatusInProgress)},
+		}, nil).Once()
+		mockClient.On("DescribeExportWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeExportOutput{
+			ExportDescription: &dynamodb.ExportDescription{ExportStatus: aws.String(dynamodb.ExportStatusCompleted)},
+		}, nil).Once()
+
+		output, err := dynamoClient.WaitForExport(context.Background(), "export-arn", opts)
+
+		assert.NoError(t, err)
+		assert.Equal(t, dynamodb.ExportStatusCompleted, *output.ExportStatus)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Failed export", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		mockClient.On("DescribeExportWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeExportOutput{
+			ExportDescription: &dynamodb.ExportDescription{
+				ExportStatus:   aws.String(dynamodb.ExportStatusFailed),
+				FailureMessage: aws.String("access denied"),
+			},
+		}, nil)
+
+		output, err := dynamoClient.WaitForExport(context.Background(), "export-arn", opts)
+
+		assert.Nil(t, output)
+		assert.EqualError(t, err, "export export-arn failed: access denied")
+	})
+}
+
+func TestImportFromS3(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", RangeKey: "range", RangeType: AttrValInteger, ReadCapacityUnits: 1, WriteCapacityUnits: 2}
+	gsiKeySchemaInput := []*GsiKeySchemaInput{
+		{
+			KeySchemaInput: KeySchemaInput{HashKey: "field1", ReadCapacityUnits: 1, WriteCapacityUnits: 1},
+			IndexName:      "GSI1",
+			ProjectionType: "ALL",
+		},
+	}
+
+	t.Run("Valid import", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, gsiKeySchemaInput)
+
+		var captured *dynamodb.ImportTableInput
+		mockClient.On("ImportTableWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.ImportTableInput")).Run(func(args mock.Arguments) {
+			captured = args.Get(1).(*dynamodb.ImportTableInput)
+		}).Return(&dynamodb.ImportTableOutput{
+			ImportTableDescription: &dynamodb.ImportTableDescription{ImportArn: aws.String("import-arn")},
+		}, nil)
+
+		output, err := dynamoClient.ImportFromS3(context.Background(), ImportInput{
+			Bucket:          "brunojet-storage",
+			KeyPrefix:       "exports/data/",
+			CompressionType: CompressionTypeGzip,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "import-arn", *output.ImportArn)
+		assert.Equal(t, ExportFormatDynamoDBJSON, *captured.InputFormat)
+		assert.Equal(t, CompressionTypeGzip, *captured.InputCompressionType)
+		assert.Equal(t, "brunojet-storage", *captured.S3BucketSource.S3Bucket)
+		assert.Equal(t, "exports/data/", *captured.S3BucketSource.S3KeyPrefix)
+
+		params := captured.TableCreationParameters
+		assert.Equal(t, "test-table", *params.TableName)
+		assert.Equal(t, 2, len(params.KeySchema))
+		assert.Equal(t, 3, len(params.AttributeDefinitions))
+		assert.Equal(t, AttrValInteger, *params.AttributeDefinitions[1].AttributeType)
+		assert.Equal(t, int64(2), *params.ProvisionedThroughput.WriteCapacityUnits)
+		assert.Equal(t, 1, len(params.GlobalSecondaryIndexes))
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		dynamoClient, _, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		output, err := dynamoClient.ImportFromS3(context.Background(), ImportInput{Bucket: "brunojet-storage", Format: "XML"})
+
+		assert.Nil(t, output)
+		assert.EqualError(t, err, "import format must be one of DYNAMODB_JSON, ION or CSV")
+	})
+}
+
+func TestWaitForImport(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	opts := WaitOptions{PollInterval: time.Millisecond}
+
+	t.Run("Completes", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		mockClient.On("DescribeImportWithContext", mock.Anything, &dynamodb.DescribeImportInput{
+			ImportArn: aws.String("import-arn"),
+		}).Return(&dynamodb.DescribeImportOutput{
+			ImportTableDescription: &dynamodb.ImportTableDescription{ImportStatus: aws.String(dynamodb.ImportStatusCompleted)},
+		}, nil)
+
+		output, err := dynamoClient.WaitForImport(context.Background(), "import-arn", opts)
+
+		assert.NoError(t, err)
+		assert.Equal(t, dynamodb.ImportStatusCompleted, *output.ImportStatus)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Cancelled import", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		mockClient.On("DescribeImportWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeImportOutput{
+			ImportTableDescription: &dynamodb.ImportTableDescription{ImportStatus: aws.String(dynamodb.ImportStatusCancelled)},
+		}, nil)
+
+		output, err := dynamoClient.WaitForImport(context.Background(), "import-arn", opts)
+
+		assert.Nil(t, output)
+		assert.EqualError(t, err, "import import-arn ended with status CANCELLED: ")
+	})
+}
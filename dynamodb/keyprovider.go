@@ -0,0 +1,156 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+const dataKeySize = 32
+
+var (
+	_ KeyProvider = (*KMSKeyProvider)(nil)
+	_ KeyProvider = (*StaticKeyProvider)(nil)
+)
+
+// KMSKeyProvider generates data keys with a KMS key. The encryption context is
+// passed to KMS, so it is recorded in CloudTrail and can be used in key policies.
+type KMSKeyProvider struct {
+	keyID  string
+	client kmsiface.KMSAPI
+}
+
+// NewKMSKeyProvider returns a provider that wraps data keys with the KMS key
+// keyID, which may be a key ID, key ARN, alias name or alias ARN.
+func NewKMSKeyProvider(keyID string) (*KMSKeyProvider, error) {
+	if keyID == "" {
+		return nil, errors.New("KMS key ID cannot be empty")
+	}
+
+	return &KMSKeyProvider{keyID: keyID, client: kms.New(getAwsSession())}, nil
+}
+
+func (p *KMSKeyProvider) GenerateDataKey(ctx context.Context, encryptionContext map[string]string) (DataKey, error) {
+	output, err := p.client.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(p.keyID),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: aws.StringMap(encryptionContext),
+	})
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	return DataKey{
+		Plaintext: output.Plaintext,
+		Wrapped:   output.CiphertextBlob,
+		KeyID:     aws.StringValue(output.KeyId),
+	}, nil
+}
+
+func (p *KMSKeyProvider) DecryptDataKey(ctx context.Context, wrapped []byte, keyID string, encryptionContext map[string]string) ([]byte, error) {
+	output, err := p.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		CiphertextBlob:    wrapped,
+		KeyId:             aws.String(keyID),
+		EncryptionContext: aws.StringMap(encryptionContext),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Plaintext, nil
+}
+
+// StaticKeyProvider wraps data keys locally with AES-GCM under a fixed key. It is
+// meant for tests and local development, where no KMS key is available.
+type StaticKeyProvider struct {
+	keyID string
+	aead  cipher.AEAD
+}
+
+// NewStaticKeyProvider returns a provider that wraps data keys with key, a 16, 24
+// or 32 byte AES key identified by keyID.
+func NewStaticKeyProvider(keyID string, key []byte) (*StaticKeyProvider, error) {
+	if keyID == "" {
+		return nil, errors.New("key ID cannot be empty")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticKeyProvider{keyID: keyID, aead: aead}, nil
+}
+
+func (p *StaticKeyProvider) GenerateDataKey(ctx context.Context, encryptionContext map[string]string) (DataKey, error) {
+	plaintext := make([]byte, dataKeySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return DataKey{}, err
+	}
+
+	aad, err := json.Marshal(encryptionContext)
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	wrapped, err := seal(p.aead, plaintext, aad)
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	return DataKey{Plaintext: plaintext, Wrapped: wrapped, KeyID: p.keyID}, nil
+}
+
+func (p *StaticKeyProvider) DecryptDataKey(ctx context.Context, wrapped []byte, keyID string, encryptionContext map[string]string) ([]byte, error) {
+	if keyID != p.keyID {
+		return nil, fmt.Errorf("unknown key ID %q", keyID)
+	}
+
+	aad, err := json.Marshal(encryptionContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(p.aead, wrapped, aad)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which it prepends to the result.
+func seal(aead cipher.AEAD, plaintext []byte, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open reverses seal.
+func open(aead cipher.AEAD, sealed []byte, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	return plaintext, nil
+}
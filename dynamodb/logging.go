@@ -0,0 +1,27 @@
+package dynamodb
+
+import (
+	"log/slog"
+
+	"go_aws_services/session"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// WithLogger logs every call of this client with session.LogHandler, replacing
+// the logger of the session. A nil logger turns logging off for this client.
+// It is not safe to call while the client is in use, and returns the client to
+// allow chaining.
+func (d *DynamoDBClient) WithLogger(logger *slog.Logger, opts session.LogOptions) *DynamoDBClient {
+	setLogger(&d.ownHandle("a logger").Handlers, logger, opts)
+
+	return d
+}
+
+func setLogger(handlers *request.Handlers, logger *slog.Logger, opts session.LogOptions) {
+	handlers.Complete.RemoveByName(session.LogHandlerName)
+
+	if logger != nil {
+		handlers.Complete.PushBackNamed(session.LogHandler(logger, opts))
+	}
+}
@@ -0,0 +1,43 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go_aws_services/internal/awstest"
+	"go_aws_services/session"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	dynamoClient, _ := newServerClient(t, awstest.Throttled, awstest.OK)
+
+	var buf bytes.Buffer
+	dynamoClient.
+		WithRetryPolicy(fastRetries).
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil)), session.LogOptions{})
+
+	_, err := dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+	require.NoError(t, err)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "GetItem", record["operation"])
+	assert.Equal(t, "test-table", record["table"])
+	assert.Equal(t, session.Redacted, record["key"])
+	assert.Equal(t, float64(1), record["retries"])
+
+	t.Run("Nil logger turns logging off", func(t *testing.T) {
+		buf.Reset()
+		dynamoClient.WithLogger(nil, session.LogOptions{})
+
+		_, err := dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+
+		require.NoError(t, err)
+		assert.Zero(t, buf.Len())
+	})
+}
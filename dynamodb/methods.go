@@ -1,25 +1,52 @@
 package dynamodb
 
 import (
+	"context"
 	"errors"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
 var _ DynamoDBService = (*DynamoDBClient)(nil)
 
 func NewDynamoDBClient(tableName string, keySchemaInput KeySchemaInput, gsiKeySchemaInput []*GsiKeySchemaInput) (*DynamoDBClient, error) {
-	if tableName == "" {
-		return nil, errors.New("table name cannot be empty")
+	if err := validateClientInput(tableName, keySchemaInput, gsiKeySchemaInput); err != nil {
+		return nil, err
 	}
 
-	if err := validateSchemaIntegrity(keySchemaInput); err != nil {
-		return nil, err
+	return &DynamoDBClient{
+		tableName:    tableName,
+		keySchema:    keySchemaInput,
+		gsiKeySchema: gsiKeySchemaInput,
+		client:       initAwsDynamoDb(),
+	}, nil
+}
+
+// NewDynamoDBClientWithAPI creates a client that sends its requests to api
+// instead of the shared AWS handle, e.g. to the in-memory fake of the
+// dynamodbtest package. Settings applied through SDK handlers, such as the
+// retry policy, rate limiter, logging, instrumentation, metrics and capacity
+// collection, need an AWS handle, so setting them on the client panics.
+//
+// Parameters:
+//
+//	api (dynamodbiface.DynamoDBAPI): The DynamoDB API the client calls.
+//	tableName (string): The name of the table.
+//	keySchemaInput (KeySchemaInput): The key schema of the table.
+//	gsiKeySchemaInput ([]*GsiKeySchemaInput): The global secondary indexes of the table.
+//
+// Returns:
+//
+//	(*DynamoDBClient, error): The client, or an error if the table definition is invalid.
+func NewDynamoDBClientWithAPI(api dynamodbiface.DynamoDBAPI, tableName string, keySchemaInput KeySchemaInput, gsiKeySchemaInput []*GsiKeySchemaInput) (*DynamoDBClient, error) {
+	if api == nil {
+		return nil, errors.New("api cannot be nil")
 	}
 
-	if err := validateGsiSchemaIntegrity(gsiKeySchemaInput); err != nil {
+	if err := validateClientInput(tableName, keySchemaInput, gsiKeySchemaInput); err != nil {
 		return nil, err
 	}
 
@@ -27,17 +54,29 @@ func NewDynamoDBClient(tableName string, keySchemaInput KeySchemaInput, gsiKeySc
 		tableName:    tableName,
 		keySchema:    keySchemaInput,
 		gsiKeySchema: gsiKeySchemaInput,
-		client:       initAwsDynamoDb(),
+		client:       api,
+		customAPI:    true,
 	}, nil
 }
 
-func (d *DynamoDBClient) CreateTableAsync() (*dynamodb.CreateTableOutput, error) {
-	attributeDefinitions := []*dynamodb.AttributeDefinition{}
-	attributeMap := make(map[string]bool)
+func validateClientInput(tableName string, keySchemaInput KeySchemaInput, gsiKeySchemaInput []*GsiKeySchemaInput) error {
+	if tableName == "" {
+		return errors.New("table name cannot be empty")
+	}
 
-	keySchema := convertKeySchema(d.keySchema, &attributeDefinitions, attributeMap)
+	if err := validateSchemaIntegrity(keySchemaInput); err != nil {
+		return err
+	}
+
+	return validateGsiSchemaIntegrity(gsiKeySchemaInput)
+}
 
-	globalSecondaryIndexes := convertGSI(d.gsiKeySchema, &attributeDefinitions, attributeMap)
+func (d *DynamoDBClient) CreateTableAsync() (*dynamodb.CreateTableOutput, error) {
+	return d.client.CreateTable(d.createTableInput())
+}
+
+func (d *DynamoDBClient) createTableInput() *dynamodb.CreateTableInput {
+	attributeDefinitions, keySchema, globalSecondaryIndexes := buildTableDefinition(d.keySchema, d.gsiKeySchema)
 
 	input := &dynamodb.CreateTableInput{
 		TableName:            aws.String(d.tableName),
@@ -53,20 +92,35 @@ func (d *DynamoDBClient) CreateTableAsync() (*dynamodb.CreateTableOutput, error)
 		input.GlobalSecondaryIndexes = globalSecondaryIndexes
 	}
 
-	return d.client.CreateTable(input)
+	if d.deletionProtection {
+		input.DeletionProtectionEnabled = aws.Bool(true)
+	}
+
+	return input
 }
 
 func (d *DynamoDBClient) CreateTable() (*dynamodb.CreateTableOutput, error) {
-	output, err := d.CreateTableAsync()
+	return d.CreateTableWithContext(context.Background(), WaitOptions{MaxWait: DefaultMaxWait})
+}
+
+// CreateTableWithContext creates the table and waits until the table and all of
+// its global secondary indexes are ACTIVE.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the request and the wait.
+//	opts (WaitOptions): The polling interval and the maximum time to wait.
+//
+// Returns:
+//
+//	(*dynamodb.CreateTableOutput, error): The output from the CreateTable operation, or an error if the operation or the wait failed.
+func (d *DynamoDBClient) CreateTableWithContext(ctx context.Context, opts WaitOptions) (*dynamodb.CreateTableOutput, error) {
+	output, err := d.client.CreateTableWithContext(ctx, d.createTableInput())
 	if err != nil {
 		return nil, err
 	}
 
-	err = dynamoClient.WaitUntilTableExists(&dynamodb.DescribeTableInput{
-		TableName: aws.String(d.tableName),
-	})
-
-	if err != nil {
+	if err := d.WaitUntilTableActive(ctx, opts); err != nil {
 		return nil, err
 	}
 
@@ -74,24 +128,36 @@ func (d *DynamoDBClient) CreateTable() (*dynamodb.CreateTableOutput, error) {
 }
 
 func (d *DynamoDBClient) DeleteTableAsync() (*dynamodb.DeleteTableOutput, error) {
-	input := &dynamodb.DeleteTableInput{
+	return d.client.DeleteTable(d.deleteTableInput())
+}
+
+func (d *DynamoDBClient) deleteTableInput() *dynamodb.DeleteTableInput {
+	return &dynamodb.DeleteTableInput{
 		TableName: aws.String(d.tableName),
 	}
-
-	return d.client.DeleteTable(input)
 }
 
 func (d *DynamoDBClient) DeleteTable() (*dynamodb.DeleteTableOutput, error) {
-	output, err := d.DeleteTableAsync()
+	return d.DeleteTableWithContext(context.Background(), WaitOptions{MaxWait: DefaultMaxWait})
+}
+
+// DeleteTableWithContext deletes the table and waits until it no longer exists.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the request and the wait.
+//	opts (WaitOptions): The polling interval and the maximum time to wait.
+//
+// Returns:
+//
+//	(*dynamodb.DeleteTableOutput, error): The output from the DeleteTable operation, or an error if the operation or the wait failed.
+func (d *DynamoDBClient) DeleteTableWithContext(ctx context.Context, opts WaitOptions) (*dynamodb.DeleteTableOutput, error) {
+	output, err := d.client.DeleteTableWithContext(ctx, d.deleteTableInput())
 	if err != nil {
 		return nil, err
 	}
 
-	err = dynamoClient.WaitUntilTableNotExists(&dynamodb.DescribeTableInput{
-		TableName: aws.String(d.tableName),
-	})
-
-	if err != nil {
+	if err := d.WaitUntilTableDeleted(ctx, opts); err != nil {
 		return nil, err
 	}
 
@@ -100,7 +166,8 @@ func (d *DynamoDBClient) DeleteTable() (*dynamodb.DeleteTableOutput, error) {
 
 // PutItem inserts an item into the DynamoDB table.
 // It takes an item as input, marshals it into a DynamoDB attribute value map,
-// and then calls the PutItem method of the DynamoDB client.
+// encrypts and signs it when encryption is enabled, and then calls the PutItem
+// method of the DynamoDB client.
 //
 // Parameters:
 //
@@ -110,15 +177,59 @@ func (d *DynamoDBClient) DeleteTable() (*dynamodb.DeleteTableOutput, error) {
 //
 //	(*dynamodb.PutItemOutput, error): The output from the PutItem operation, or an error if the operation failed.
 func (d *DynamoDBClient) PutItem(item map[string]interface{}) (*dynamodb.PutItemOutput, error) {
+	return d.putItem(item, nil)
+}
+
+// PutItemWithCondition inserts an item like PutItem, but only if condition holds
+// for the item it replaces. When it does not, DynamoDB fails the call with a
+// ConditionalCheckFailedException and the table is left unchanged.
+//
+// Parameters:
+//
+//	item (map[string]interface{}): The item to insert.
+//	condition (Condition): The condition the replaced item must satisfy.
+//
+// Returns:
+//
+//	(*dynamodb.PutItemOutput, error): The output from the PutItem operation, or an error if the operation failed.
+func (d *DynamoDBClient) PutItemWithCondition(item map[string]interface{}, condition Condition) (*dynamodb.PutItemOutput, error) {
+	return d.putItem(item, &condition)
+}
+
+func (d *DynamoDBClient) putItem(item map[string]interface{}, condition *Condition) (*dynamodb.PutItemOutput, error) {
 	av, err := dynamodbattribute.MarshalMap(item)
 	if err != nil {
 		return nil, err
 	}
 
+	av, err = d.EncryptItem(context.Background(), av)
+	if err != nil {
+		return nil, err
+	}
+
 	input := &dynamodb.PutItemInput{
 		TableName: aws.String(d.tableName),
 		Item:      av,
 	}
+
+	if condition != nil {
+		if condition.Expression == "" {
+			return nil, errors.New("condition expression cannot be empty")
+		}
+
+		input.ConditionExpression = aws.String(condition.Expression)
+
+		if len(condition.Names) > 0 {
+			input.ExpressionAttributeNames = aws.StringMap(condition.Names)
+		}
+
+		if len(condition.Values) > 0 {
+			if input.ExpressionAttributeValues, err = dynamodbattribute.MarshalMap(condition.Values); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return d.client.PutItem(input)
 }
 
@@ -152,7 +263,8 @@ func (d *DynamoDBClient) QueryItem(key map[string]interface{}, indexName string)
 
 // GetItem retrieves an item from the DynamoDB table.
 // It takes a key as input, marshals it into a DynamoDB attribute value map,
-// and then calls the GetItem method of the DynamoDB client.
+// calls the GetItem method of the DynamoDB client, and then verifies and
+// decrypts the item when encryption is enabled.
 //
 // Parameters:
 //
@@ -171,7 +283,17 @@ func (d *DynamoDBClient) GetItem(key map[string]interface{}) (*dynamodb.GetItemO
 		TableName: aws.String(d.tableName),
 		Key:       av,
 	}
-	return d.client.GetItem(input)
+
+	output, err := d.client.GetItem(input)
+	if err != nil || output.Item == nil {
+		return output, err
+	}
+
+	output.Item, err = d.DecryptItem(context.Background(), output.Item)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
 }
 
 // DeleteItem deletes an item from the DynamoDB table.
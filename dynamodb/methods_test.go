@@ -8,6 +8,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	"go_aws_services/session"
 )
 
 func TestNewDynamoDBClient(t *testing.T) {
@@ -32,6 +34,33 @@ func TestNewDynamoDBClient(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestNewDynamoDBClientWithAPI(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	mockClient := new(mockDynamoDBClient)
+
+	dynamoClient, err := NewDynamoDBClientWithAPI(mockClient, "test-table", keySchemaInput, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, mockClient, dynamoClient.client)
+
+	// Settings that need SDK handlers cannot apply to the API, so they panic.
+	assert.PanicsWithValue(t, "dynamodb: a retry policy cannot be set on a client created with NewDynamoDBClientWithAPI", func() {
+		dynamoClient.WithRetryPolicy(session.RetryPolicy{MaxAttempts: 1})
+	})
+	assert.Panics(t, func() { dynamoClient.WithRateLimiter(nil) })
+	assert.Panics(t, func() { dynamoClient.WithLogger(nil, session.LogOptions{}) })
+	assert.Panics(t, func() { dynamoClient.WithInstrumentation(nil) })
+	assert.Panics(t, func() { dynamoClient.WithMetrics(nil) })
+	assert.Panics(t, func() { dynamoClient.WithCapacityCollector(NewCapacityCollector()) })
+	assert.Equal(t, mockClient, dynamoClient.client)
+	assert.Nil(t, dynamoClient.CapacityCollector())
+
+	_, err = NewDynamoDBClientWithAPI(nil, "test-table", keySchemaInput, nil)
+	assert.NotNil(t, err)
+
+	_, err = NewDynamoDBClientWithAPI(mockClient, "", keySchemaInput, nil)
+	assert.NotNil(t, err)
+}
+
 func TestTable(t *testing.T) {
 	tableName := "test-table"
 	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
@@ -117,6 +146,34 @@ func TestPutItem(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestPutItemWithCondition(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+	item := map[string]interface{}{"id": "123", "status": "DONE"}
+	av, _ := dynamodbattribute.MarshalMap(item)
+
+	mockClient.On("PutItem", &dynamodb.PutItemInput{
+		TableName:                 aws.String("test-table"),
+		Item:                      av,
+		ConditionExpression:       aws.String("#status = :pending"),
+		ExpressionAttributeNames:  map[string]*string{"#status": aws.String("status")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":pending": {S: aws.String("PENDING")}},
+	}).Return(&dynamodb.PutItemOutput{}, nil)
+
+	_, err := dynamoClient.PutItemWithCondition(item, Condition{
+		Expression: "#status = :pending",
+		Names:      map[string]string{"#status": "status"},
+		Values:     map[string]interface{}{":pending": "PENDING"},
+	})
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+
+	_, err = dynamoClient.PutItemWithCondition(item, Condition{})
+	assert.EqualError(t, err, "condition expression cannot be empty")
+}
+
 func TestQueryItem(t *testing.T) {
 	tableName := "test-table"
 	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
@@ -0,0 +1,22 @@
+package dynamodb
+
+import (
+	"go_aws_services/metrics"
+)
+
+// WithMetrics records Prometheus metrics for this client's calls in m,
+// replacing any inherited from the session. A nil m removes them. It is not
+// safe to call while the client is in use, and returns the client to allow
+// chaining.
+func (d *DynamoDBClient) WithMetrics(m *metrics.Metrics) *DynamoDBClient {
+	handlers := &d.ownHandle("metrics").Handlers
+
+	if m == nil {
+		metrics.Uninstall(handlers)
+		return d
+	}
+
+	m.Install(handlers)
+
+	return d
+}
@@ -0,0 +1,37 @@
+package dynamodb
+
+import (
+	"strings"
+	"testing"
+
+	"go_aws_services/internal/awstest"
+	"go_aws_services/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMetrics(t *testing.T) {
+	dynamoClient, _ := newServerClient(t, awstest.Throttled, awstest.OK)
+	m, err := metrics.New()
+	require.NoError(t, err)
+
+	dynamoClient.WithRetryPolicy(fastRetries).WithMetrics(m)
+
+	_, err = dynamoClient.PutItem(map[string]interface{}{"id": "123"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.Registry(), "aws_client_throttles_total"))
+
+	dynamoClient.WithMetrics(nil)
+	_, err = dynamoClient.PutItem(map[string]interface{}{"id": "123"})
+	require.NoError(t, err)
+
+	expected := `
+# HELP aws_client_requests_total Number of AWS calls by outcome and error code.
+# TYPE aws_client_requests_total counter
+aws_client_requests_total{bucket="",error_code="",operation="PutItem",outcome="success",service="DynamoDB",table="test-table"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "aws_client_requests_total"))
+}
@@ -4,10 +4,14 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -39,9 +43,9 @@ func (m *mockDynamoDBClient) CreateTable(input *dynamodb.CreateTableInput) (*dyn
 	return args.Get(0).(*dynamodb.CreateTableOutput), args.Error(1)
 }
 
-func (m *mockDynamoDBClient) WaitUntilTableExists(input *dynamodb.DescribeTableInput) error {
-	args := m.Called(input)
-	return args.Error(0)
+func (m *mockDynamoDBClient) CreateTableWithContext(ctx aws.Context, input *dynamodb.CreateTableInput, opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.CreateTableOutput), args.Error(1)
 }
 
 func (m *mockDynamoDBClient) DeleteTable(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
@@ -49,9 +53,9 @@ func (m *mockDynamoDBClient) DeleteTable(input *dynamodb.DeleteTableInput) (*dyn
 	return args.Get(0).(*dynamodb.DeleteTableOutput), args.Error(1)
 }
 
-func (m *mockDynamoDBClient) WaitUntilTableNotExists(input *dynamodb.DescribeTableInput) error {
-	args := m.Called(input)
-	return args.Error(0)
+func (m *mockDynamoDBClient) DeleteTableWithContext(ctx aws.Context, input *dynamodb.DeleteTableInput, opts ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.DeleteTableOutput), args.Error(1)
 }
 
 func (m *mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
@@ -74,6 +78,61 @@ func (m *mockDynamoDBClient) DeleteItem(input *dynamodb.DeleteItemInput) (*dynam
 	return args.Get(0).(*dynamodb.DeleteItemOutput), args.Error(1)
 }
 
+func (m *mockDynamoDBClient) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.DescribeTableOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) ExportTableToPointInTimeWithContext(ctx aws.Context, input *dynamodb.ExportTableToPointInTimeInput, opts ...request.Option) (*dynamodb.ExportTableToPointInTimeOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.ExportTableToPointInTimeOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) DescribeExportWithContext(ctx aws.Context, input *dynamodb.DescribeExportInput, opts ...request.Option) (*dynamodb.DescribeExportOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.DescribeExportOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) ImportTableWithContext(ctx aws.Context, input *dynamodb.ImportTableInput, opts ...request.Option) (*dynamodb.ImportTableOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.ImportTableOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) DescribeImportWithContext(ctx aws.Context, input *dynamodb.DescribeImportInput, opts ...request.Option) (*dynamodb.DescribeImportOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.DescribeImportOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	args := m.Called(ctx, input, fn)
+	return args.Error(0)
+}
+
+func (m *mockDynamoDBClient) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.BatchWriteItemOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) UpdateTableWithContext(ctx aws.Context, input *dynamodb.UpdateTableInput, opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.UpdateTableOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) UpdateTimeToLiveWithContext(ctx aws.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.UpdateTimeToLiveOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) CreateBackupWithContext(ctx aws.Context, input *dynamodb.CreateBackupInput, opts ...request.Option) (*dynamodb.CreateBackupOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.CreateBackupOutput), args.Error(1)
+}
+
+func (m *mockDynamoDBClient) DescribeBackupWithContext(ctx aws.Context, input *dynamodb.DescribeBackupInput, opts ...request.Option) (*dynamodb.DescribeBackupOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*dynamodb.DescribeBackupOutput), args.Error(1)
+}
+
 func mockNewDynamoDBClient(tableName string, keySchemaInput KeySchemaInput, gsiKeySchemaInput []*GsiKeySchemaInput) (*DynamoDBClient, *mockDynamoDBClient, error) {
 	mockClient := new(mockDynamoDBClient)
 
@@ -85,13 +144,15 @@ func mockNewDynamoDBClient(tableName string, keySchemaInput KeySchemaInput, gsiK
 }
 
 func mockCreateTable(mockClient *mockDynamoDBClient) {
-	mockClient.On("CreateTable", mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(&dynamodb.CreateTableOutput{}, nil)
-	mockClient.On("WaitUntilTableExists", mock.AnythingOfType("*dynamodb.DescribeTableInput")).Return(nil)
+	mockClient.On("CreateTableWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.CreateTableInput")).Return(&dynamodb.CreateTableOutput{}, nil)
+	mockClient.On("DescribeTableWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.DescribeTableInput")).Return(&dynamodb.DescribeTableOutput{
+		Table: &dynamodb.TableDescription{TableStatus: aws.String(dynamodb.TableStatusActive)},
+	}, nil).Once()
 }
 
 func mockDeleteTable(mockClient *mockDynamoDBClient) {
-	mockClient.On("DeleteTable", mock.AnythingOfType("*dynamodb.DeleteTableInput")).Return(&dynamodb.DeleteTableOutput{}, nil)
-	mockClient.On("WaitUntilTableNotExists", mock.AnythingOfType("*dynamodb.DescribeTableInput")).Return(nil)
+	mockClient.On("DeleteTableWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.DeleteTableInput")).Return(&dynamodb.DeleteTableOutput{}, nil)
+	mockClient.On("DescribeTableWithContext", mock.Anything, mock.AnythingOfType("*dynamodb.DescribeTableInput")).Return((*dynamodb.DescribeTableOutput)(nil), awserr.New(dynamodb.ErrCodeResourceNotFoundException, "table not found", nil)).Once()
 }
 
 func mockTable(t *testing.T, tableName string, keySchemaInput KeySchemaInput, gsiKeySchemaInput []*GsiKeySchemaInput) {
@@ -119,3 +180,18 @@ func mockTable(t *testing.T, tableName string, keySchemaInput KeySchemaInput, gs
 		mockClient.AssertExpectations(t)
 	})
 }
+
+type mockKMSClient struct {
+	kmsiface.KMSAPI
+	mock.Mock
+}
+
+func (m *mockKMSClient) GenerateDataKeyWithContext(ctx aws.Context, input *kms.GenerateDataKeyInput, opts ...request.Option) (*kms.GenerateDataKeyOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*kms.GenerateDataKeyOutput), args.Error(1)
+}
+
+func (m *mockKMSClient) DecryptWithContext(ctx aws.Context, input *kms.DecryptInput, opts ...request.Option) (*kms.DecryptOutput, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*kms.DecryptOutput), args.Error(1)
+}
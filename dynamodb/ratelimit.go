@@ -0,0 +1,165 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces out operations so that no more than perSecond of them
+// start in any one second. A nil *rateLimiter never blocks.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until n more operations are allowed or the context is done.
+func (l *rateLimiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(n) * l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+const (
+	throttleBackoffFactor = 0.5
+	minRateFraction       = 0.05
+	recoveryFraction      = 0.05
+)
+
+// AdaptiveRateLimiter is a token bucket for the requests of one table. It halves
+// its rate every time DynamoDB throttles a request and climbs back towards the
+// configured rate by a twentieth of it for every request that succeeds, so a
+// client backs off on its own instead of retrying into the throttling.
+type AdaptiveRateLimiter struct {
+	mu      sync.Mutex
+	maxRate float64
+	minRate float64
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+// NewAdaptiveRateLimiter returns a limiter that allows perSecond requests on
+// average and up to burst at once.
+//
+// Parameters:
+//
+//	perSecond (float64): The rate to allow while DynamoDB does not throttle.
+//	burst (int): The number of requests that can start at once.
+//
+// Returns:
+//
+//	(*AdaptiveRateLimiter, error): The limiter, or an error if perSecond or burst is not positive.
+func NewAdaptiveRateLimiter(perSecond float64, burst int) (*AdaptiveRateLimiter, error) {
+	if perSecond <= 0 {
+		return nil, errors.New("rate limit must be positive")
+	}
+
+	if burst <= 0 {
+		return nil, errors.New("rate limit burst must be positive")
+	}
+
+	return &AdaptiveRateLimiter{
+		maxRate: perSecond,
+		minRate: perSecond * minRateFraction,
+		rate:    perSecond,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}, nil
+}
+
+// Rate returns the number of requests per second currently allowed.
+func (l *AdaptiveRateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
+
+// Wait blocks until a request may start or the context is done.
+func (l *AdaptiveRateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	l.refill()
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// OnThrottle lowers the rate after a throttled request and drops any saved-up
+// burst.
+func (l *AdaptiveRateLimiter) OnThrottle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.rate = max(l.rate*throttleBackoffFactor, l.minRate)
+	l.tokens = min(l.tokens, 0)
+}
+
+// OnSuccess raises the rate after a request that was not throttled.
+func (l *AdaptiveRateLimiter) OnSuccess() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.rate = min(l.rate+l.maxRate*recoveryFraction, l.maxRate)
+}
+
+// refill adds the tokens earned since the last call at the current rate.
+func (l *AdaptiveRateLimiter) refill() {
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+	l.last = now
+}
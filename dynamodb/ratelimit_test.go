@@ -0,0 +1,94 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("Nil limiter never blocks", func(t *testing.T) {
+		var limiter *rateLimiter
+
+		assert.Nil(t, newRateLimiter(0))
+		assert.NoError(t, limiter.Wait(context.Background(), 100))
+	})
+
+	t.Run("Spaces out operations", func(t *testing.T) {
+		limiter := newRateLimiter(1000)
+
+		start := time.Now()
+		assert.NoError(t, limiter.Wait(context.Background(), 10))
+		assert.NoError(t, limiter.Wait(context.Background(), 10))
+
+		assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	})
+
+	t.Run("Context cancelled while waiting", func(t *testing.T) {
+		limiter := newRateLimiter(1)
+		assert.NoError(t, limiter.Wait(context.Background(), 1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.Equal(t, context.Canceled, limiter.Wait(ctx, 1))
+	})
+}
+
+func TestAdaptiveRateLimiter(t *testing.T) {
+	t.Run("Rejects invalid settings", func(t *testing.T) {
+		_, err := NewAdaptiveRateLimiter(0, 1)
+		assert.EqualError(t, err, "rate limit must be positive")
+
+		_, err = NewAdaptiveRateLimiter(1, 0)
+		assert.EqualError(t, err, "rate limit burst must be positive")
+	})
+
+	t.Run("Allows a burst, then spaces out requests", func(t *testing.T) {
+		limiter, _ := NewAdaptiveRateLimiter(200, 5)
+
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, limiter.Wait(context.Background()))
+		}
+		assert.Less(t, limiter.tokens, 1.0, "the burst is used up")
+
+		assert.NoError(t, limiter.Wait(context.Background()))
+		assert.NoError(t, limiter.Wait(context.Background()))
+		assert.GreaterOrEqual(t, time.Since(start), 5*time.Millisecond)
+	})
+
+	t.Run("Backs off on throttling and recovers on success", func(t *testing.T) {
+		limiter, _ := NewAdaptiveRateLimiter(100, 1)
+
+		limiter.OnThrottle()
+		assert.InDelta(t, 50, limiter.Rate(), 0.001)
+
+		for i := 0; i < 10; i++ {
+			limiter.OnThrottle()
+		}
+		assert.InDelta(t, 5, limiter.Rate(), 0.001)
+
+		limiter.OnSuccess()
+		assert.InDelta(t, 10, limiter.Rate(), 0.001)
+
+		for i := 0; i < 100; i++ {
+			limiter.OnSuccess()
+		}
+		assert.InDelta(t, 100, limiter.Rate(), 0.001)
+	})
+
+	t.Run("Returns the token when the context is done", func(t *testing.T) {
+		limiter, _ := NewAdaptiveRateLimiter(1, 1)
+		assert.NoError(t, limiter.Wait(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.Equal(t, context.Canceled, limiter.Wait(ctx))
+		assert.Less(t, limiter.tokens, 0.01)
+		assert.Greater(t, limiter.tokens, -0.01)
+	})
+}
@@ -0,0 +1,90 @@
+package dynamodb
+
+import (
+	"go_aws_services/session"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	rateLimitWaitHandler     = "go_aws_services.RateLimitWait"
+	rateLimitThrottleHandler = "go_aws_services.RateLimitThrottle"
+	rateLimitSuccessHandler  = "go_aws_services.RateLimitSuccess"
+)
+
+// ownHandle gives the client a DynamoDB handle of its own the first time it is
+// configured, so that retry and rate limit settings of one table do not leak to
+// the other tables sharing the package-wide handle. A client created with
+// NewDynamoDBClientWithAPI never sends requests through SDK handlers, so
+// configuring setting on it panics rather than silently doing nothing.
+func (d *DynamoDBClient) ownHandle(setting string) *dynamodb.DynamoDB {
+	if d.customAPI {
+		panic("dynamodb: " + setting + " cannot be set on a client created with NewDynamoDBClientWithAPI")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.handle == nil {
+		d.handle = newDynamodb(getAwsSession())
+		d.client = d.handle
+	}
+
+	return d.handle
+}
+
+// WithRetryPolicy sets how the requests of this client are retried, overriding
+// the policy of the session. It is not safe to call while the client is in use,
+// and returns the client to allow chaining.
+func (d *DynamoDBClient) WithRetryPolicy(policy session.RetryPolicy) *DynamoDBClient {
+	d.ownHandle("a retry policy").Retryer = policy.Retryer()
+
+	return d
+}
+
+// WithRateLimiter makes every attempt of every request of this client wait for
+// limiter, and reports throttled and successful requests back to it so its rate
+// follows the capacity of the table. Passing nil removes the limiter. It is not
+// safe to call while the client is in use, and returns the client to allow
+// chaining.
+func (d *DynamoDBClient) WithRateLimiter(limiter *AdaptiveRateLimiter) *DynamoDBClient {
+	handlers := &d.ownHandle("a rate limiter").Handlers
+
+	handlers.Sign.RemoveByName(rateLimitWaitHandler)
+	handlers.Retry.RemoveByName(rateLimitThrottleHandler)
+	handlers.Complete.RemoveByName(rateLimitSuccessHandler)
+
+	if limiter == nil {
+		return d
+	}
+
+	// Sign runs again before every retry, so each attempt takes a token.
+	handlers.Sign.PushFrontNamed(request.NamedHandler{
+		Name: rateLimitWaitHandler,
+		Fn: func(r *request.Request) {
+			if err := limiter.Wait(r.Context()); err != nil {
+				r.Error = awserr.New(request.CanceledErrorCode, "request context canceled while rate limited", err)
+			}
+		},
+	})
+	handlers.Retry.PushBackNamed(request.NamedHandler{
+		Name: rateLimitThrottleHandler,
+		Fn: func(r *request.Request) {
+			if r.IsErrorThrottle() {
+				limiter.OnThrottle()
+			}
+		},
+	})
+	handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: rateLimitSuccessHandler,
+		Fn: func(r *request.Request) {
+			if r.Error == nil {
+				limiter.OnSuccess()
+			}
+		},
+	})
+
+	return d
+}
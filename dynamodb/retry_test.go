@@ -0,0 +1,145 @@
+package dynamodb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go_aws_services/internal/awstest"
+	"go_aws_services/session"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServerClient returns a client whose own handle talks to an awstest.Server
+// answering with responses, and the server.
+func newServerClient(t *testing.T, responses ...http.HandlerFunc) (*DynamoDBClient, *awstest.Server) {
+	t.Helper()
+
+	server := awstest.NewServer(t, responses...)
+	sess := server.Session(t)
+
+	oldAwsSession, oldNewDynamodb := getAwsSession, newDynamodb
+	getAwsSession = func() *awssession.Session { return sess }
+	newDynamodb = dynamodb.New
+	t.Cleanup(func() {
+		getAwsSession, newDynamodb = oldAwsSession, oldNewDynamodb
+	})
+
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, _, err := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+	require.NoError(t, err)
+
+	return dynamoClient, server
+}
+
+var fastRetries = session.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+func TestWithRetryPolicy(t *testing.T) {
+	t.Run("Retries throttling until success", func(t *testing.T) {
+		dynamoClient, server := newServerClient(t, awstest.Throttled, awstest.Throttled, awstest.OK)
+		dynamoClient.WithRetryPolicy(fastRetries)
+
+		_, err := dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), server.Calls())
+	})
+
+	t.Run("Gives up after MaxAttempts", func(t *testing.T) {
+		dynamoClient, server := newServerClient(t, awstest.Unavailable)
+		dynamoClient.WithRetryPolicy(fastRetries)
+
+		_, err := dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+
+		var aerr awserr.Error
+		require.ErrorAs(t, err, &aerr)
+		assert.Equal(t, "InternalServerError", aerr.Code())
+		assert.Equal(t, int32(3), server.Calls())
+	})
+
+	t.Run("Does not retry errors outside its classes", func(t *testing.T) {
+		dynamoClient, server := newServerClient(t, awstest.Throttled, awstest.OK)
+		policy := fastRetries
+		policy.Retryable = session.RetryTransient
+		dynamoClient.WithRetryPolicy(policy)
+
+		_, err := dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), server.Calls())
+	})
+
+	t.Run("Does not retry client errors", func(t *testing.T) {
+		dynamoClient, server := newServerClient(t, awstest.Invalid, awstest.OK)
+		dynamoClient.WithRetryPolicy(fastRetries)
+
+		_, err := dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), server.Calls())
+	})
+
+	t.Run("Does not change the shared handle", func(t *testing.T) {
+		tableClient, _ := newServerClient(t, awstest.OK)
+		shared := tableClient.client
+
+		tableClient.WithRetryPolicy(fastRetries)
+
+		assert.NotSame(t, shared, tableClient.client)
+		assert.Same(t, shared, dynamoClient)
+	})
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	t.Run("Adapts to throttling", func(t *testing.T) {
+		dynamoClient, server := newServerClient(t, awstest.Throttled, awstest.Throttled, awstest.OK)
+		limiter, err := NewAdaptiveRateLimiter(1000, 10)
+		require.NoError(t, err)
+		dynamoClient.WithRetryPolicy(fastRetries).WithRateLimiter(limiter)
+
+		_, err = dynamoClient.GetItem(map[string]interface{}{"id": "123"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), server.Calls())
+		// Halved twice, then raised by a twentieth of the configured rate.
+		assert.InDelta(t, 300, limiter.Rate(), 0.001)
+	})
+
+	t.Run("Gives up when the context is done", func(t *testing.T) {
+		dynamoClient, server := newServerClient(t, awstest.OK)
+		limiter, err := NewAdaptiveRateLimiter(0.001, 1)
+		require.NoError(t, err)
+		dynamoClient.WithRateLimiter(limiter)
+		require.NoError(t, limiter.Wait(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = dynamoClient.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String("test-table"),
+			Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String("123")}},
+		})
+
+		var aerr awserr.Error
+		require.ErrorAs(t, err, &aerr)
+		assert.Equal(t, request.CanceledErrorCode, aerr.Code())
+		assert.Equal(t, int32(0), server.Calls())
+	})
+
+	t.Run("Replaces the previous limiter", func(t *testing.T) {
+		dynamoClient, _ := newServerClient(t, awstest.OK)
+		first, _ := NewAdaptiveRateLimiter(10, 1)
+		second, _ := NewAdaptiveRateLimiter(10, 1)
+
+		dynamoClient.WithRateLimiter(first).WithRateLimiter(second).WithRateLimiter(nil)
+
+		assert.Equal(t, 0, dynamoClient.handle.Handlers.Sign.Len()-dynamodb.New(getAwsSession()).Handlers.Sign.Len())
+	})
+}
@@ -0,0 +1,22 @@
+package dynamodb
+
+import (
+	"go_aws_services/telemetry"
+)
+
+// WithInstrumentation traces this client's calls and records their metrics with
+// instrumentation, replacing any inherited from the session. A nil
+// instrumentation removes it. It is not safe to call while the client is in
+// use, and returns the client to allow chaining.
+func (d *DynamoDBClient) WithInstrumentation(instrumentation *telemetry.Instrumentation) *DynamoDBClient {
+	handlers := &d.ownHandle("instrumentation").Handlers
+
+	if instrumentation == nil {
+		telemetry.Uninstall(handlers)
+		return d
+	}
+
+	instrumentation.Install(handlers)
+
+	return d
+}
@@ -0,0 +1,35 @@
+package dynamodb
+
+import (
+	"testing"
+
+	"go_aws_services/internal/awstest"
+	"go_aws_services/telemetry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithInstrumentation(t *testing.T) {
+	dynamoClient, _ := newServerClient(t, awstest.Throttled, awstest.OK)
+	spans := tracetest.NewInMemoryExporter()
+	instrumentation, err := telemetry.New(telemetry.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))))
+	require.NoError(t, err)
+
+	dynamoClient.WithRetryPolicy(fastRetries).WithInstrumentation(instrumentation)
+
+	_, err = dynamoClient.PutItem(map[string]interface{}{"id": "123"})
+	require.NoError(t, err)
+
+	require.Len(t, spans.GetSpans(), 1)
+	assert.Equal(t, "DynamoDB.PutItem", spans.GetSpans()[0].Name)
+	assert.Len(t, spans.GetSpans()[0].Events, 1)
+
+	dynamoClient.WithInstrumentation(nil)
+	_, err = dynamoClient.PutItem(map[string]interface{}{"id": "123"})
+	require.NoError(t, err)
+
+	assert.Len(t, spans.GetSpans(), 1)
+}
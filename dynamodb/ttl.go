@@ -0,0 +1,21 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// EnableTimeToLive makes DynamoDB delete items once the epoch-seconds timestamp
+// stored in attributeName has passed. Deletion usually happens within a few days
+// of expiry, so readers should still check the timestamp themselves.
+func (d *DynamoDBClient) EnableTimeToLive(ctx context.Context, attributeName string) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return d.client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(d.tableName),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(attributeName),
+			Enabled:       aws.Bool(true),
+		},
+	})
+}
@@ -0,0 +1,29 @@
+package dynamodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableTimeToLive(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+	mockClient.On("UpdateTimeToLiveWithContext", context.Background(), &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String("test-table"),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String("expiresAt"),
+			Enabled:       aws.Bool(true),
+		},
+	}).Return(&dynamodb.UpdateTimeToLiveOutput{}, nil)
+
+	output, err := dynamoClient.EnableTimeToLive(context.Background(), "expiresAt")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+	mockClient.AssertExpectations(t)
+}
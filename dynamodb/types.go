@@ -1,15 +1,27 @@
 package dynamodb
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
 type DynamoDBClient struct {
-	tableName    string
-	keySchema    KeySchemaInput
-	gsiKeySchema []*GsiKeySchemaInput
-	client       dynamodbiface.DynamoDBAPI
+	tableName           string
+	keySchema           KeySchemaInput
+	gsiKeySchema        []*GsiKeySchemaInput
+	deletionProtection  bool
+	keyProvider         KeyProvider
+	encryptedAttributes map[string]bool
+	capacity            *CapacityCollector
+	// mu guards the creation of handle and client by ownHandle.
+	mu        sync.Mutex
+	handle    *dynamodb.DynamoDB
+	client    dynamodbiface.DynamoDBAPI
+	customAPI bool
 }
 
 type KeySchemaInput struct {
@@ -37,12 +49,162 @@ const (
 	ProjectionTypeInclude  = "INCLUDE"
 )
 
+const (
+	ExportFormatDynamoDBJSON = "DYNAMODB_JSON"
+	ExportFormatIon          = "ION"
+	ImportFormatCSV          = "CSV"
+	CompressionTypeNone      = "NONE"
+	CompressionTypeGzip      = "GZIP"
+	CompressionTypeZstd      = "ZSTD"
+	DefaultPollInterval      = 10 * time.Second
+	DefaultMaxWait           = 10 * time.Minute
+)
+
+const (
+	DumpFormatDynamoDBJSON = "DYNAMODB_JSON"
+	DumpFormatJSON         = "JSON"
+	DefaultDumpSegments    = 4
+	MaxBatchWriteItems     = 25
+)
+
+// ExportInput describes where a point-in-time export of the table is written.
+// A zero ExportTime exports the current state of the table.
+type ExportInput struct {
+	Bucket     string    `json:"bucket"`
+	Prefix     string    `json:"prefix,omitempty"`
+	Format     string    `json:"format,omitempty"`
+	ExportTime time.Time `json:"exportTime,omitempty"`
+}
+
+// ImportInput describes the S3 data a new table is created from.
+type ImportInput struct {
+	Bucket          string `json:"bucket"`
+	KeyPrefix       string `json:"keyPrefix,omitempty"`
+	Format          string `json:"format,omitempty"`
+	CompressionType string `json:"compressionType,omitempty"`
+}
+
+// DumpOptions controls how Dump scans the table and encodes each item.
+// Progress, when set, is called with the running number of items written. The
+// calls never overlap, even though the segments are scanned concurrently.
+type DumpOptions struct {
+	Segments int
+	Format   string
+	Progress func(items int64)
+}
+
+// LoadOptions controls how Load reads an NDJSON stream into the table.
+// Offset skips that many lines so an interrupted load can be resumed with the
+// last value reported to Progress. ItemsPerSecond of zero disables rate limiting.
+type LoadOptions struct {
+	Format         string
+	Offset         int64
+	ItemsPerSecond float64
+	Progress       func(lines int64)
+}
+
+// DeleteGuard lists the checks SafeDeleteTable runs before dropping a table.
+// ProductionPattern is a regular expression matched against the table name and
+// MaxItemCount of zero disables the item count check. When BackupBeforeDelete is
+// set, an on-demand backup named BackupName (or a generated name) is taken
+// before the table is deleted. Wait applies to both the backup and the delete.
+type DeleteGuard struct {
+	ProductionPattern  string
+	MaxItemCount       int64
+	BackupBeforeDelete bool
+	BackupName         string
+	Wait               WaitOptions
+}
+
+// WaitOptions controls how long and how often long-running operations are polled.
+// Zero values fall back to DefaultPollInterval and no deadline besides the context.
+type WaitOptions struct {
+	PollInterval time.Duration
+	MaxWait      time.Duration
+}
+
+// Reserved attributes that hold the encryption materials of an item written by a
+// client with encryption enabled.
+const (
+	DataKeyAttribute             = "_encDataKey"
+	KeyIDAttribute               = "_encKeyId"
+	EncryptedAttributesAttribute = "_encAttributes"
+	SignatureAttribute           = "_encSignature"
+)
+
+// KeyProvider wraps and unwraps the per-item data keys used for attribute
+// encryption. encryptionContext names the table and the item's key, and must be
+// passed unchanged to DecryptDataKey.
+type KeyProvider interface {
+	GenerateDataKey(ctx context.Context, encryptionContext map[string]string) (DataKey, error)
+	DecryptDataKey(ctx context.Context, wrapped []byte, keyID string, encryptionContext map[string]string) ([]byte, error)
+}
+
+// DataKey is a 256-bit key in plaintext and wrapped by the KeyProvider key KeyID.
+// Only Wrapped and KeyID are stored.
+type DataKey struct {
+	Plaintext []byte
+	Wrapped   []byte
+	KeyID     string
+}
+
+// Billing modes a Pricing can describe.
+const (
+	BillingModeOnDemand    = dynamodb.BillingModePayPerRequest
+	BillingModeProvisioned = dynamodb.BillingModeProvisioned
+)
+
+// CapacityUsage is the capacity one request consumed on one table or index.
+// Index is empty for the table itself.
+type CapacityUsage struct {
+	Operation  string  `json:"operation"`
+	Table      string  `json:"table"`
+	Index      string  `json:"index,omitempty"`
+	ReadUnits  float64 `json:"readUnits"`
+	WriteUnits float64 `json:"writeUnits"`
+}
+
+// CapacityKey groups the totals of a CapacityCollector.
+type CapacityKey struct {
+	Table     string `json:"table"`
+	Index     string `json:"index,omitempty"`
+	Operation string `json:"operation"`
+}
+
+// CapacityTotals is the capacity consumed by the requests of one CapacityKey.
+type CapacityTotals struct {
+	Requests   int64   `json:"requests"`
+	ReadUnits  float64 `json:"readUnits"`
+	WriteUnits float64 `json:"writeUnits"`
+}
+
+// Pricing holds the prices used to estimate what consumed capacity costs, in
+// dollars. With on-demand billing they are per read and write request unit; with
+// provisioned billing they are per RCU and WCU hour.
+type Pricing struct {
+	Mode       string  `json:"mode"`
+	ReadPrice  float64 `json:"readPrice"`
+	WritePrice float64 `json:"writePrice"`
+}
+
+// Condition is a ConditionExpression with the placeholders it uses. Names maps
+// #placeholders to attribute names and Values maps :placeholders to values,
+// which are marshalled like the attributes of an item.
+type Condition struct {
+	Expression string
+	Names      map[string]string
+	Values     map[string]interface{}
+}
+
 type DynamoDBService interface {
 	PutItem(item map[string]interface{}) (*dynamodb.PutItemOutput, error)
+	PutItemWithCondition(item map[string]interface{}, condition Condition) (*dynamodb.PutItemOutput, error)
 	QueryItem(key map[string]interface{}, indexName string) (*dynamodb.QueryOutput, error)
 	GetItem(key map[string]interface{}) (*dynamodb.GetItemOutput, error)
 	CreateTableAsync() (*dynamodb.CreateTableOutput, error)
 	CreateTable() (*dynamodb.CreateTableOutput, error)
+	CreateTableWithContext(ctx context.Context, opts WaitOptions) (*dynamodb.CreateTableOutput, error)
 	DeleteTableAsync() (*dynamodb.DeleteTableOutput, error)
 	DeleteTable() (*dynamodb.DeleteTableOutput, error)
+	DeleteTableWithContext(ctx context.Context, opts WaitOptions) (*dynamodb.DeleteTableOutput, error)
 }
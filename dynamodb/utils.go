@@ -80,6 +80,17 @@ func convertGSI(inputs []*GsiKeySchemaInput, attributeDefinitions *[]*dynamodb.A
 	return gsis
 }
 
+func buildTableDefinition(keySchemaInput KeySchemaInput, gsiKeySchemaInput []*GsiKeySchemaInput) ([]*dynamodb.AttributeDefinition, []*dynamodb.KeySchemaElement, []*dynamodb.GlobalSecondaryIndex) {
+	attributeDefinitions := []*dynamodb.AttributeDefinition{}
+	attributeMap := make(map[string]bool)
+
+	keySchema := convertKeySchema(keySchemaInput, &attributeDefinitions, attributeMap)
+
+	globalSecondaryIndexes := convertGSI(gsiKeySchemaInput, &attributeDefinitions, attributeMap)
+
+	return attributeDefinitions, keySchema, globalSecondaryIndexes
+}
+
 func addAttributeDefinition(attributeDefinitions *[]*dynamodb.AttributeDefinition, attributeMap map[string]bool, attributeName string, attributeType string) {
 	if attributeMap[attributeName] {
 		return
@@ -43,3 +43,35 @@ func validateGsiSchemaIntegrity(gsiKeySchemaInput []*GsiKeySchemaInput) error {
 
 	return nil
 }
+
+func validateExportInput(input ExportInput) error {
+	if input.Bucket == "" {
+		return errors.New("export bucket cannot be empty")
+	}
+
+	switch input.Format {
+	case "", ExportFormatDynamoDBJSON, ExportFormatIon:
+		return nil
+	default:
+		return errors.New("export format must be one of DYNAMODB_JSON or ION")
+	}
+}
+
+func validateImportInput(input ImportInput) error {
+	if input.Bucket == "" {
+		return errors.New("import bucket cannot be empty")
+	}
+
+	switch input.Format {
+	case "", ExportFormatDynamoDBJSON, ExportFormatIon, ImportFormatCSV:
+	default:
+		return errors.New("import format must be one of DYNAMODB_JSON, ION or CSV")
+	}
+
+	switch input.CompressionType {
+	case "", CompressionTypeNone, CompressionTypeGzip, CompressionTypeZstd:
+		return nil
+	default:
+		return errors.New("import compression type must be one of NONE, GZIP or ZSTD")
+	}
+}
@@ -122,3 +122,41 @@ func TestValidateGsiSchemaIntegrity(t *testing.T) {
 		assert.Equal(t, "GSI projection type must be one of ALL, INCLUDE, or KEYS_ONLY", err.Error())
 	})
 }
+
+func TestValidateExportInput(t *testing.T) {
+	t.Run("Valid Export Input", func(t *testing.T) {
+		err := validateExportInput(ExportInput{Bucket: "bucket", Format: ExportFormatIon})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Empty Bucket", func(t *testing.T) {
+		err := validateExportInput(ExportInput{})
+		assert.Error(t, err)
+		assert.Equal(t, "export bucket cannot be empty", err.Error())
+	})
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		err := validateExportInput(ExportInput{Bucket: "bucket", Format: ImportFormatCSV})
+		assert.Error(t, err)
+		assert.Equal(t, "export format must be one of DYNAMODB_JSON or ION", err.Error())
+	})
+}
+
+func TestValidateImportInput(t *testing.T) {
+	t.Run("Valid Import Input", func(t *testing.T) {
+		err := validateImportInput(ImportInput{Bucket: "bucket", Format: ImportFormatCSV, CompressionType: CompressionTypeZstd})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Empty Bucket", func(t *testing.T) {
+		err := validateImportInput(ImportInput{})
+		assert.Error(t, err)
+		assert.Equal(t, "import bucket cannot be empty", err.Error())
+	})
+
+	t.Run("Invalid Compression Type", func(t *testing.T) {
+		err := validateImportInput(ImportInput{Bucket: "bucket", CompressionType: "BZIP2"})
+		assert.Error(t, err)
+		assert.Equal(t, "import compression type must be one of NONE, GZIP or ZSTD", err.Error())
+	})
+}
@@ -0,0 +1,103 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var errWaitTimeout = errors.New("timed out waiting for operation to complete")
+
+// pollUntil calls check every opts.PollInterval until it reports done, returns an
+// error, the context is cancelled or opts.MaxWait elapses.
+func pollUntil(ctx context.Context, opts WaitOptions, check func(ctx context.Context) (bool, error)) error {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	if opts.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxWait)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := check(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errWaitTimeout
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitUntilTableActive polls the table until it and all of its global secondary
+// indexes are ACTIVE. A table that is not visible yet is polled again, since
+// DescribeTable is eventually consistent right after CreateTable.
+func (d *DynamoDBClient) WaitUntilTableActive(ctx context.Context, opts WaitOptions) error {
+	return pollUntil(ctx, opts, func(ctx context.Context) (bool, error) {
+		output, err := d.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(d.tableName),
+		})
+		if isResourceNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		return isTableActive(output.Table), nil
+	})
+}
+
+// WaitUntilTableDeleted polls the table until DescribeTable reports that it no
+// longer exists.
+func (d *DynamoDBClient) WaitUntilTableDeleted(ctx context.Context, opts WaitOptions) error {
+	return pollUntil(ctx, opts, func(ctx context.Context) (bool, error) {
+		_, err := d.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(d.tableName),
+		})
+		if isResourceNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	})
+}
+
+func isTableActive(table *dynamodb.TableDescription) bool {
+	if aws.StringValue(table.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isResourceNotFound(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException
+}
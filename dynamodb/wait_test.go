@@ -0,0 +1,142 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPollUntil(t *testing.T) {
+	t.Run("Done after a few polls", func(t *testing.T) {
+		calls := 0
+
+		err := pollUntil(context.Background(), WaitOptions{PollInterval: time.Millisecond}, func(ctx context.Context) (bool, error) {
+			calls++
+			return calls == 3, nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Check error", func(t *testing.T) {
+		err := pollUntil(context.Background(), WaitOptions{PollInterval: time.Millisecond}, func(ctx context.Context) (bool, error) {
+			return false, errors.New("boom")
+		})
+
+		assert.EqualError(t, err, "boom")
+	})
+
+	t.Run("Max wait exceeded", func(t *testing.T) {
+		err := pollUntil(context.Background(), WaitOptions{PollInterval: time.Millisecond, MaxWait: 5 * time.Millisecond}, func(ctx context.Context) (bool, error) {
+			return false, nil
+		})
+
+		assert.Equal(t, errWaitTimeout, err)
+	})
+
+	t.Run("Context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := pollUntil(ctx, WaitOptions{PollInterval: time.Millisecond}, func(ctx context.Context) (bool, error) {
+			return false, nil
+		})
+
+		assert.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestWaitUntilTableActive(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	opts := WaitOptions{PollInterval: time.Millisecond}
+
+	t.Run("Waits for table and GSIs", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		mockClient.On("DescribeTableWithContext", mock.Anything, &dynamodb.DescribeTableInput{
+			TableName: aws.String("test-table"),
+		}).Return((*dynamodb.DescribeTableOutput)(nil), awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not yet", nil)).Once()
+		mockClient.On("DescribeTableWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeTableOutput{
+			Table: &dynamodb.TableDescription{
+				TableStatus: aws.String(dynamodb.TableStatusActive),
+				GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+					{IndexStatus: aws.String(dynamodb.IndexStatusCreating)},
+				},
+			},
+		}, nil).Once()
+		mockClient.On("DescribeTableWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeTableOutput{
+			Table: &dynamodb.TableDescription{
+				TableStatus: aws.String(dynamodb.TableStatusActive),
+				GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+					{IndexStatus: aws.String(dynamodb.IndexStatusActive)},
+				},
+			},
+		}, nil).Once()
+
+		err := dynamoClient.WaitUntilTableActive(context.Background(), opts)
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Times out", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		mockClient.On("CreateTableWithContext", mock.Anything, mock.Anything).Return(&dynamodb.CreateTableOutput{}, nil)
+		mockClient.On("DescribeTableWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeTableOutput{
+			Table: &dynamodb.TableDescription{TableStatus: aws.String(dynamodb.TableStatusCreating)},
+		}, nil)
+
+		output, err := dynamoClient.CreateTableWithContext(context.Background(), WaitOptions{PollInterval: time.Millisecond, MaxWait: 5 * time.Millisecond})
+
+		assert.Nil(t, output)
+		assert.Equal(t, errWaitTimeout, err)
+	})
+
+	t.Run("Create uses the caller's context", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mockClient.On("CreateTableWithContext", ctx, mock.Anything).Return((*dynamodb.CreateTableOutput)(nil), context.Canceled)
+
+		output, err := dynamoClient.CreateTableWithContext(ctx, opts)
+
+		assert.Nil(t, output)
+		assert.Equal(t, context.Canceled, err)
+		mockClient.AssertNotCalled(t, "DescribeTableWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Describe error", func(t *testing.T) {
+		dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+		mockClient.On("DescribeTableWithContext", mock.Anything, mock.Anything).Return((*dynamodb.DescribeTableOutput)(nil), errors.New("access denied"))
+
+		err := dynamoClient.WaitUntilTableActive(context.Background(), opts)
+
+		assert.EqualError(t, err, "access denied")
+	})
+}
+
+func TestWaitUntilTableDeleted(t *testing.T) {
+	keySchemaInput := KeySchemaInput{HashKey: "id", ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	dynamoClient, mockClient, _ := mockNewDynamoDBClient("test-table", keySchemaInput, nil)
+
+	mockClient.On("DescribeTableWithContext", mock.Anything, mock.Anything).Return(&dynamodb.DescribeTableOutput{
+		Table: &dynamodb.TableDescription{TableStatus: aws.String(dynamodb.TableStatusDeleting)},
+	}, nil).Once()
+	mockClient.On("DescribeTableWithContext", mock.Anything, mock.Anything).Return((*dynamodb.DescribeTableOutput)(nil), awserr.New(dynamodb.ErrCodeResourceNotFoundException, "gone", nil)).Once()
+
+	err := dynamoClient.WaitUntilTableDeleted(context.Background(), WaitOptions{PollInterval: time.Millisecond})
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	storage "go_aws_services/s3"
+)
+
+// NewDispatcher creates a Dispatcher that recovers upload Metadata from object
+// keys with decodeKey. A nil decodeKey uses DecodeKey.
+func NewDispatcher(decodeKey KeyDecoder) *Dispatcher {
+	if decodeKey == nil {
+		decodeKey = DecodeKey
+	}
+
+	return &Dispatcher{decodeKey: decodeKey}
+}
+
+// OnObjectCreated registers handler for uploads that landed in S3.
+func (d *Dispatcher) OnObjectCreated(handler UploadHandler) *Dispatcher {
+	d.created = append(d.created, handler)
+	return d
+}
+
+// OnObjectRemoved registers handler for uploads that were deleted.
+func (d *Dispatcher) OnObjectRemoved(handler UploadHandler) *Dispatcher {
+	d.removed = append(d.removed, handler)
+	return d
+}
+
+// Handle parses body and passes every created or removed upload to the handlers
+// registered for its type. Events on keys that are not upload keys, and event
+// types without handlers, are skipped. Every event is handled even when an
+// earlier handler fails; the returned error joins all handler errors, so that a
+// queue consumer can leave the message to be redelivered.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context passed to the handlers.
+//	body (json []byte): The message body as delivered by S3, SNS, SQS or EventBridge.
+//
+// Returns:
+//
+//	(error): An error if the payload could not be parsed or any handler failed.
+func (d *Dispatcher) Handle(ctx context.Context, body []byte) error {
+	events, err := Parse(body)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, event := range events {
+		var handlers []UploadHandler
+		switch event.Type {
+		case TypeObjectCreated:
+			handlers = d.created
+		case TypeObjectRemoved:
+			handlers = d.removed
+		}
+
+		if len(handlers) == 0 {
+			continue
+		}
+
+		metadata, err := d.decodeKey(event.Key)
+		if errors.Is(err, ErrUnrecognizedKey) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		upload := UploadEvent{Event: event, Metadata: metadata}
+		for _, handler := range handlers {
+			if err := handler(ctx, upload); err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", event.Type, event.Key, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DecodeKey decodes keys rendered with DefaultKeyPrefix and DefaultKeyTemplate,
+// "uploads/{partner}-{app}-{model}.apk".
+func DecodeKey(key string) (storage.Metadata, error) {
+	return PrefixKeyDecoder(storage.DefaultKeyPrefix)(key)
+}
+
+// PrefixKeyDecoder returns a KeyDecoder for keys rendered with
+// DefaultKeyTemplate under prefix.
+func PrefixKeyDecoder(prefix string) KeyDecoder {
+	return func(key string) (storage.Metadata, error) {
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			return storage.Metadata{}, ErrUnrecognizedKey
+		}
+
+		name, ok = strings.CutSuffix(name, ".apk")
+		if !ok {
+			return storage.Metadata{}, ErrUnrecognizedKey
+		}
+
+		parts := strings.Split(name, "-")
+		if len(parts) != 3 {
+			return storage.Metadata{}, ErrUnrecognizedKey
+		}
+
+		ids := make([]int, len(parts))
+		for i, part := range parts {
+			id, err := strconv.Atoi(part)
+			if err != nil || id < 0 {
+				return storage.Metadata{}, ErrUnrecognizedKey
+			}
+			ids[i] = id
+		}
+
+		return storage.Metadata{PartnerID: ids[0], AppID: ids[1], DeviceModelID: ids[2]}, nil
+	}
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	storage "go_aws_services/s3"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeKey(t *testing.T) {
+	metadata, err := DecodeKey("uploads/12-34-56.apk")
+
+	assert.NoError(t, err)
+	assert.Equal(t, storage.Metadata{PartnerID: 12, AppID: 34, DeviceModelID: 56}, metadata)
+
+	for _, key := range []string{
+		"reports/12-34-56.apk",
+		"uploads/12-34-56.zip",
+		"uploads/12-34.apk",
+		"uploads/12-34-56-78.apk",
+		"uploads/12-x-56.apk",
+		"uploads/12--56.apk",
+		"uploads/nested/12-34-56.apk",
+	} {
+		_, err := DecodeKey(key)
+
+		assert.Equal(t, ErrUnrecognizedKey, err, key)
+	}
+}
+
+func TestPrefixKeyDecoder(t *testing.T) {
+	decode := PrefixKeyDecoder("partners/apks/")
+
+	metadata, err := decode("partners/apks/1-2-3.apk")
+
+	assert.NoError(t, err)
+	assert.Equal(t, storage.Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3}, metadata)
+
+	_, err = decode("uploads/1-2-3.apk")
+
+	assert.Equal(t, ErrUnrecognizedKey, err)
+}
+
+func TestDispatcherHandle(t *testing.T) {
+	t.Run("Created uploads", func(t *testing.T) {
+		var created []UploadEvent
+		dispatcher := NewDispatcher(nil).
+			OnObjectCreated(func(ctx context.Context, event UploadEvent) error {
+				created = append(created, event)
+				return nil
+			}).
+			OnObjectRemoved(func(ctx context.Context, event UploadEvent) error {
+				t.Fatal("unexpected removed event")
+				return nil
+			})
+
+		err := dispatcher.Handle(context.Background(), readPayload(t, "sqs_sns_s3.json"))
+
+		// The report object in the same notification is not an upload.
+		assert.NoError(t, err)
+		assert.Equal(t, []UploadEvent{{
+			Event:    putEvent,
+			Metadata: storage.Metadata{PartnerID: 12, AppID: 34, DeviceModelID: 56},
+		}}, created)
+	})
+
+	t.Run("Removed uploads", func(t *testing.T) {
+		var removed []storage.Metadata
+		dispatcher := NewDispatcher(nil).OnObjectRemoved(func(ctx context.Context, event UploadEvent) error {
+			removed = append(removed, event.Metadata)
+			return nil
+		})
+
+		err := dispatcher.Handle(context.Background(), readPayload(t, "eventbridge_deleted.json"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []storage.Metadata{{PartnerID: 7, AppID: 8, DeviceModelID: 9}}, removed)
+	})
+
+	t.Run("No handlers", func(t *testing.T) {
+		err := NewDispatcher(nil).Handle(context.Background(), readPayload(t, "s3_put.json"))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Handler errors", func(t *testing.T) {
+		calls := 0
+		failure := errors.New("database unavailable")
+		dispatcher := NewDispatcher(nil).
+			OnObjectCreated(func(ctx context.Context, event UploadEvent) error {
+				calls++
+				return failure
+			}).
+			OnObjectCreated(func(ctx context.Context, event UploadEvent) error {
+				calls++
+				return nil
+			})
+
+		err := dispatcher.Handle(context.Background(), readPayload(t, "s3_put.json"))
+
+		assert.True(t, errors.Is(err, failure))
+		assert.Contains(t, err.Error(), "ObjectCreated uploads/12-34-56.apk")
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Decoder errors", func(t *testing.T) {
+		failure := errors.New("lookup failed")
+		dispatcher := NewDispatcher(func(key string) (storage.Metadata, error) {
+			return storage.Metadata{}, failure
+		}).OnObjectCreated(func(ctx context.Context, event UploadEvent) error {
+			t.Fatal("handler called after decoder failure")
+			return nil
+		})
+
+		err := dispatcher.Handle(context.Background(), readPayload(t, "s3_put.json"))
+
+		assert.True(t, errors.Is(err, failure))
+	})
+
+	t.Run("Unsupported payload", func(t *testing.T) {
+		err := NewDispatcher(nil).Handle(context.Background(), []byte(`{"hello":"world"}`))
+
+		assert.True(t, errors.Is(err, ErrUnsupportedPayload))
+	})
+}
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxEnvelopeDepth bounds how many envelopes Parse unwraps, e.g. an S3
+// notification published to SNS and delivered to SQS is three levels deep.
+const maxEnvelopeDepth = 4
+
+// envelope holds the fields that tell the supported payloads apart.
+type envelope struct {
+	Records    []json.RawMessage `json:"Records"`
+	Type       string            `json:"Type"`
+	Message    string            `json:"Message"`
+	Source     string            `json:"source"`
+	DetailType string            `json:"detail-type"`
+	Time       time.Time         `json:"time"`
+	Detail     json.RawMessage   `json:"detail"`
+	Event      string            `json:"Event"`
+}
+
+// record is a Records entry. encoding/json matches keys case-insensitively, so
+// EventSource also reads the "EventSource" key SNS uses.
+type record struct {
+	EventSource string `json:"eventSource"`
+	Body        string `json:"body"`
+	Sns         struct {
+		Message string `json:"Message"`
+	} `json:"Sns"`
+	EventName string    `json:"eventName"`
+	EventTime time.Time `json:"eventTime"`
+	S3        struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object notificationObject `json:"object"`
+	} `json:"s3"`
+}
+
+type notificationObject struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	ETag      string `json:"eTag"`
+	Sequencer string `json:"sequencer"`
+}
+
+type eventBridgeDetail struct {
+	Bucket struct {
+		Name string `json:"name"`
+	} `json:"bucket"`
+	Object struct {
+		Key       string `json:"key"`
+		Size      int64  `json:"size"`
+		ETag      string `json:"etag"`
+		Sequencer string `json:"sequencer"`
+	} `json:"object"`
+}
+
+// Parse extracts the S3 object events from body. It accepts S3 event
+// notifications as S3 sends them, wrapped in SNS notifications, in SQS messages
+// (including Lambda SQS and SNS batches) and S3 events from EventBridge. The
+// s3:TestEvent S3 sends when notifications are configured yields no events.
+func Parse(body []byte) ([]Event, error) {
+	return parse(body, 0)
+}
+
+func parse(body []byte, depth int) ([]Event, error) {
+	if depth >= maxEnvelopeDepth {
+		return nil, fmt.Errorf("%w: too many nested envelopes", ErrUnsupportedPayload)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedPayload, err)
+	}
+
+	switch {
+	case env.Records != nil:
+		return parseRecords(env.Records, depth)
+
+	case env.Type == "Notification":
+		return parse([]byte(env.Message), depth+1)
+
+	case env.Source == "aws.s3" && env.Detail != nil:
+		event, err := parseEventBridge(env)
+		if err != nil {
+			return nil, err
+		}
+		return []Event{event}, nil
+
+	case env.Event == "s3:TestEvent":
+		return nil, nil
+
+	default:
+		return nil, ErrUnsupportedPayload
+	}
+}
+
+func parseRecords(records []json.RawMessage, depth int) ([]Event, error) {
+	var events []Event
+
+	for _, raw := range records {
+		var r record
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedPayload, err)
+		}
+
+		switch r.EventSource {
+		case "aws:s3":
+			event, err := notificationEvent(r)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+
+		case "aws:sqs", "aws:sns":
+			message := r.Body
+			if r.EventSource == "aws:sns" {
+				message = r.Sns.Message
+			}
+
+			nested, err := parse([]byte(message), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, nested...)
+
+		default:
+			return nil, fmt.Errorf("%w: event source %q", ErrUnsupportedPayload, r.EventSource)
+		}
+	}
+
+	return events, nil
+}
+
+func notificationEvent(r record) (Event, error) {
+	// Notification keys are URL-encoded with spaces as '+'.
+	key, err := url.QueryUnescape(r.S3.Object.Key)
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid object key %q: %v", r.S3.Object.Key, err)
+	}
+
+	event := Event{
+		Name:      r.EventName,
+		Source:    SourceNotification,
+		Bucket:    r.S3.Bucket.Name,
+		Key:       key,
+		Size:      r.S3.Object.Size,
+		ETag:      r.S3.Object.ETag,
+		Sequencer: r.S3.Object.Sequencer,
+		Time:      r.EventTime,
+	}
+
+	switch {
+	case strings.HasPrefix(r.EventName, TypeObjectCreated+":"):
+		event.Type = TypeObjectCreated
+	case strings.HasPrefix(r.EventName, TypeObjectRemoved+":"):
+		event.Type = TypeObjectRemoved
+	}
+
+	return event, nil
+}
+
+func parseEventBridge(env envelope) (Event, error) {
+	var detail eventBridgeDetail
+	if err := json.Unmarshal(env.Detail, &detail); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrUnsupportedPayload, err)
+	}
+
+	event := Event{
+		Name:      env.DetailType,
+		Source:    SourceEventBridge,
+		Bucket:    detail.Bucket.Name,
+		Key:       detail.Object.Key,
+		Size:      detail.Object.Size,
+		ETag:      detail.Object.ETag,
+		Sequencer: detail.Object.Sequencer,
+		Time:      env.Time,
+	}
+
+	switch env.DetailType {
+	case "Object Created":
+		event.Type = TypeObjectCreated
+	case "Object Deleted":
+		event.Type = TypeObjectRemoved
+	}
+
+	return event, nil
+}
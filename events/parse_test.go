@@ -0,0 +1,137 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readPayload(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+var (
+	putEvent = Event{
+		Type:      TypeObjectCreated,
+		Name:      "ObjectCreated:Put",
+		Source:    SourceNotification,
+		Bucket:    "apk-uploads",
+		Key:       "uploads/12-34-56.apk",
+		Size:      1048576,
+		ETag:      "0123456789abcdef0123456789abcdef",
+		Sequencer: "0055AED6DCD90281E5",
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	reportEvent = Event{
+		Type:      TypeObjectCreated,
+		Name:      "ObjectCreated:Put",
+		Source:    SourceNotification,
+		Bucket:    "apk-uploads",
+		Key:       "reports/daily summary(2024).csv",
+		Size:      1048576,
+		ETag:      "0123456789abcdef0123456789abcdef",
+		Sequencer: "0055AED6DCD90281E5",
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	removedEvent = Event{
+		Type:      TypeObjectRemoved,
+		Name:      "ObjectRemoved:Delete",
+		Source:    SourceNotification,
+		Bucket:    "apk-uploads",
+		Key:       "uploads/12-34-56.apk",
+		Sequencer: "0055AED6DCD90281F0",
+		Time:      time.Date(2024, 5, 2, 8, 30, 0, 0, time.UTC),
+	}
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected []Event
+	}{
+		{"S3 notification", "s3_put.json", []Event{putEvent}},
+		{"S3 removed notification", "s3_removed.json", []Event{removedEvent}},
+		{"SNS notification", "sns_s3.json", []Event{putEvent, reportEvent}},
+		{"SQS message", "sqs_s3.json", []Event{removedEvent}},
+		{"SNS to SQS", "sqs_sns_s3.json", []Event{putEvent, reportEvent}},
+		{"Lambda SNS event", "lambda_sns_s3.json", []Event{putEvent, reportEvent}},
+		{"EventBridge created", "eventbridge_created.json", []Event{{
+			Type:      TypeObjectCreated,
+			Name:      "Object Created",
+			Source:    SourceEventBridge,
+			Bucket:    "apk-uploads",
+			Key:       "uploads/7-8-9.apk",
+			Size:      2048,
+			ETag:      "b1946ac92492d2347c6235b4d2611184",
+			Sequencer: "00617F08299329D189",
+			Time:      time.Date(2024, 5, 3, 10, 15, 0, 0, time.UTC),
+		}}},
+		{"EventBridge deleted", "eventbridge_deleted.json", []Event{{
+			Type:      TypeObjectRemoved,
+			Name:      "Object Deleted",
+			Source:    SourceEventBridge,
+			Bucket:    "apk-uploads",
+			Key:       "uploads/7-8-9.apk",
+			Sequencer: "00617F0829932A1B2C",
+			Time:      time.Date(2024, 5, 4, 10, 15, 0, 0, time.UTC),
+		}}},
+		{"S3 test event", "s3_test_event.json", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := Parse(readPayload(t, tt.payload))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, events)
+		})
+	}
+
+	t.Run("Other S3 event", func(t *testing.T) {
+		events, err := Parse([]byte(`{"Records":[{"eventSource":"aws:s3","eventName":"ObjectRestore:Completed","s3":{"object":{"key":"uploads/1-2-3.apk"}}}]}`))
+
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Empty(t, events[0].Type)
+		assert.Equal(t, "ObjectRestore:Completed", events[0].Name)
+	})
+
+	t.Run("Unsupported payloads", func(t *testing.T) {
+		for _, body := range []string{
+			`not json`,
+			`{"hello":"world"}`,
+			`{"Records":[{"eventSource":"aws:dynamodb"}]}`,
+			`{"source":"aws.ec2","detail-type":"EC2 Instance State-change Notification","detail":{}}`,
+		} {
+			_, err := Parse([]byte(body))
+
+			assert.True(t, errors.Is(err, ErrUnsupportedPayload), body)
+		}
+	})
+
+	t.Run("Too many envelopes", func(t *testing.T) {
+		body := readPayload(t, "s3_put.json")
+		for i := 0; i < maxEnvelopeDepth; i++ {
+			body = []byte(`{"Type":"Notification","Message":` + quote(body) + `}`)
+		}
+
+		_, err := Parse(body)
+
+		assert.True(t, errors.Is(err, ErrUnsupportedPayload))
+	})
+}
+
+func quote(data []byte) string {
+	quoted, _ := json.Marshal(string(data))
+	return string(quoted)
+}
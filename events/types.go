@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	storage "go_aws_services/s3"
+)
+
+const (
+	TypeObjectCreated = "ObjectCreated"
+	TypeObjectRemoved = "ObjectRemoved"
+)
+
+const (
+	SourceNotification = "notification"
+	SourceEventBridge  = "eventbridge"
+)
+
+var (
+	ErrUnsupportedPayload = errors.New("unsupported event payload")
+	ErrUnrecognizedKey    = errors.New("object key does not match the upload key layout")
+)
+
+// Event is one S3 object event, whichever way it was delivered. Key is already
+// URL-decoded. Type is TypeObjectCreated or TypeObjectRemoved, or empty for
+// other S3 events such as restores and replication, while Name keeps the exact
+// event name, e.g. "ObjectCreated:Put" or "Object Created".
+type Event struct {
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	Size      int64     `json:"size,omitempty"`
+	ETag      string    `json:"etag,omitempty"`
+	Sequencer string    `json:"sequencer,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// UploadEvent is an Event on an upload object together with the Metadata its
+// key was rendered from.
+type UploadEvent struct {
+	Event
+	Metadata storage.Metadata `json:"metadata"`
+}
+
+// KeyDecoder recovers the Metadata an object key was rendered from. It returns
+// ErrUnrecognizedKey for keys that are not upload keys.
+type KeyDecoder func(key string) (storage.Metadata, error)
+
+// UploadHandler handles one UploadEvent.
+type UploadHandler func(ctx context.Context, event UploadEvent) error
+
+// Dispatcher parses event payloads and calls the handlers registered for each
+// event type.
+type Dispatcher struct {
+	decodeKey KeyDecoder
+	created   []UploadHandler
+	removed   []UploadHandler
+}
@@ -0,0 +1,104 @@
+// Package awstest provides a scripted AWS endpoint for tests that need the
+// real SDK handlers, such as retries, logging and instrumentation, to run.
+package awstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/require"
+)
+
+// RequestID is the request ID RespondJSON answers with.
+const RequestID = "REQ123"
+
+var (
+	// OK answers a JSON protocol call with an empty result.
+	OK = RespondJSON(http.StatusOK, `{}`)
+	// Throttled answers a DynamoDB call with ProvisionedThroughputExceededException.
+	Throttled = RespondDynamoDBError(http.StatusBadRequest, "ProvisionedThroughputExceededException")
+	// Unavailable answers a DynamoDB call with InternalServerError.
+	Unavailable = RespondDynamoDBError(http.StatusInternalServerError, "InternalServerError")
+	// Invalid answers a DynamoDB call with ValidationException.
+	Invalid = RespondDynamoDBError(http.StatusBadRequest, "ValidationException")
+)
+
+// Server is a test endpoint that answers each request with the next of its
+// responses, repeating the last one.
+type Server struct {
+	URL   string
+	calls atomic.Int32
+}
+
+// NewServer starts a Server that is closed when the test ends.
+//
+// Parameters:
+//
+//	t (testing.TB): The test.
+//	responses (...http.HandlerFunc): The responses, in the order requests receive them.
+//
+// Returns:
+//
+//	*Server: The running server.
+func NewServer(t testing.TB, responses ...http.HandlerFunc) *Server {
+	t.Helper()
+
+	s := &Server{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(s.calls.Add(1)) - 1
+		responses[min(call, len(responses)-1)](w, r)
+	}))
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+
+	return s
+}
+
+// Calls returns the number of requests the server received.
+func (s *Server) Calls() int32 {
+	return s.calls.Load()
+}
+
+// Session returns a session with static credentials that sends every call to
+// the server, addressing S3 buckets by path.
+func (s *Server) Session(t testing.TB) *session.Session {
+	t.Helper()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(s.URL),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	require.NoError(t, err)
+
+	return sess
+}
+
+// NewSession starts a Server with responses and returns a session talking to it.
+func NewSession(t testing.TB, responses ...http.HandlerFunc) *session.Session {
+	t.Helper()
+
+	return NewServer(t, responses...).Session(t)
+}
+
+// RespondJSON answers with status and body the way JSON protocol services such
+// as DynamoDB do, with RequestID as the request ID.
+func RespondJSON(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Header().Set("X-Amzn-Requestid", RequestID)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+// RespondDynamoDBError answers with status and the DynamoDB error code.
+func RespondDynamoDBError(status int, code string) http.HandlerFunc {
+	return RespondJSON(status, `{"__type":"com.amazonaws.dynamodb.v20120810#`+code+`","message":"test"}`)
+}
@@ -0,0 +1,74 @@
+// Package observe tells observers such as tracing and metrics when the AWS
+// calls made through a session or client start, are retried and end, and
+// extracts what those calls are made against.
+package observe
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Observer follows AWS calls. Start is called once before a call is validated
+// and may replace the request's context, e.g. with one holding a span. What it
+// returns is handed to Retry before each retry and to End when the call
+// completes.
+type Observer interface {
+	Start(r *request.Request) interface{}
+	Retry(r *request.Request, state interface{})
+	End(r *request.Request, state interface{})
+}
+
+type stateKey string
+
+// started wraps the state Start returned, so that a nil state still marks the
+// call as started.
+type started struct {
+	state interface{}
+}
+
+// Install adds observer to handlers under name, replacing whatever was
+// installed under name before, so it can be called on a client that inherited
+// handlers from an observed session.
+//
+// Parameters:
+//
+//	handlers (*request.Handlers): The handlers of a session or client.
+//	name (string): The prefix of the handler names, unique to each kind of observer.
+//	observer (Observer): The observer to call.
+func Install(handlers *request.Handlers, name string, observer Observer) {
+	Uninstall(handlers, name)
+
+	key := stateKey(name)
+	stateOf := func(r *request.Request) (interface{}, bool) {
+		s, ok := r.Context().Value(key).(started)
+		return s.state, ok
+	}
+
+	handlers.Validate.PushFrontNamed(request.NamedHandler{Name: name + "Start", Fn: func(r *request.Request) {
+		// Presigning builds a request that is never sent, so it is not observed.
+		if r.ExpireTime > 0 {
+			return
+		}
+
+		state := observer.Start(r)
+		r.SetContext(context.WithValue(r.Context(), key, started{state: state}))
+	}})
+	handlers.Retry.PushBackNamed(request.NamedHandler{Name: name + "Retry", Fn: func(r *request.Request) {
+		if state, ok := stateOf(r); ok {
+			observer.Retry(r, state)
+		}
+	}})
+	handlers.Complete.PushBackNamed(request.NamedHandler{Name: name + "End", Fn: func(r *request.Request) {
+		if state, ok := stateOf(r); ok {
+			observer.End(r, state)
+		}
+	}})
+}
+
+// Uninstall removes the observer installed under name from handlers.
+func Uninstall(handlers *request.Handlers, name string) {
+	handlers.Validate.RemoveByName(name + "Start")
+	handlers.Retry.RemoveByName(name + "Retry")
+	handlers.Complete.RemoveByName(name + "End")
+}
@@ -0,0 +1,99 @@
+package observe
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go_aws_services/internal/awstest"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the events it observes, numbering the calls it starts.
+type recorder struct {
+	t      *testing.T
+	events []string
+	calls  int
+}
+
+func (r *recorder) Start(req *request.Request) interface{} {
+	r.calls++
+	r.events = append(r.events, "start "+req.Operation.Name)
+	return r.calls
+}
+
+func (r *recorder) Retry(req *request.Request, state interface{}) {
+	r.events = append(r.events, "retry "+ErrorType(req.Error))
+	assert.Equal(r.t, r.calls, state)
+}
+
+func (r *recorder) End(req *request.Request, state interface{}) {
+	r.events = append(r.events, "end")
+	assert.Equal(r.t, r.calls, state)
+}
+
+func TestInstall(t *testing.T) {
+	client := dynamodb.New(awstest.NewSession(t, awstest.Throttled, awstest.OK))
+	observer := &recorder{t: t}
+	Install(&client.Handlers, "test.Observer", observer)
+	// Installing again replaces the observer rather than adding another.
+	Install(&client.Handlers, "test.Observer", observer)
+
+	_, err := client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("users"),
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String("1")}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"start GetItem", "retry ProvisionedThroughputExceededException", "end"}, observer.events)
+
+	Uninstall(&client.Handlers, "test.Observer")
+	_, err = client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("users"),
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String("1")}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, observer.events, 3)
+}
+
+func TestInstallSkipsPresigning(t *testing.T) {
+	client := s3.New(awstest.NewSession(t, awstest.OK))
+	observer := &recorder{t: t}
+	Install(&client.Handlers, "test.Observer", observer)
+
+	req, _ := client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String("uploads"), Key: aws.String("a")})
+	_, err := req.Presign(time.Minute)
+	require.NoError(t, err)
+
+	assert.Empty(t, observer.events)
+}
+
+func TestParams(t *testing.T) {
+	batch := &dynamodb.BatchGetItemInput{RequestItems: map[string]*dynamodb.KeysAndAttributes{"users": {}, "apps": {}}}
+
+	assert.Equal(t, []string{"users"}, TableNames(&dynamodb.QueryInput{TableName: aws.String("users")}))
+	assert.Equal(t, []string{"apps", "users"}, TableNames(batch))
+	assert.Empty(t, TableNames(&s3.HeadObjectInput{Bucket: aws.String("uploads")}))
+	assert.Equal(t, "uploads", BucketName(&s3.HeadObjectInput{Bucket: aws.String("uploads")}))
+	assert.Empty(t, BucketName(&dynamodb.QueryInput{}))
+	assert.Empty(t, BucketName(nil))
+
+	single := &dynamodb.ConsumedCapacity{TableName: aws.String("users"), CapacityUnits: aws.Float64(0.5)}
+	perTable := []*dynamodb.ConsumedCapacity{single, {TableName: aws.String("apps"), CapacityUnits: aws.Float64(1)}}
+	assert.Equal(t, []*dynamodb.ConsumedCapacity{single}, ConsumedCapacity(&dynamodb.GetItemOutput{ConsumedCapacity: single}))
+	assert.Equal(t, perTable, ConsumedCapacity(&dynamodb.BatchGetItemOutput{ConsumedCapacity: perTable}))
+	assert.Empty(t, ConsumedCapacity(&dynamodb.GetItemOutput{}))
+	assert.Empty(t, ConsumedCapacity(&s3.HeadObjectOutput{}))
+}
+
+func TestErrorType(t *testing.T) {
+	assert.Equal(t, "NoSuchKey", ErrorType(awserr.New("NoSuchKey", "missing", nil)))
+	assert.Equal(t, OtherError, ErrorType(errors.New("boom")))
+}
@@ -0,0 +1,103 @@
+package observe
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// OtherError is the error type of errors that carry no AWS error code, the
+// same fallback OpenTelemetry uses for error.type.
+const OtherError = "_OTHER"
+
+// ErrorType returns the AWS error code of err, or OtherError for errors that
+// carry none.
+func ErrorType(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+
+	return OtherError
+}
+
+// TableNames returns the tables a DynamoDB call reads or writes: its TableName,
+// or the tables of a batch call, in name order.
+func TableNames(params interface{}) []string {
+	if name, ok := Field[*string](params, "TableName"); ok {
+		return []string{aws.StringValue(name)}
+	}
+
+	f, ok := fieldValue(params, "RequestItems")
+	if !ok || f.Kind() != reflect.Map {
+		return nil
+	}
+
+	names := make([]string, 0, f.Len())
+	for _, key := range f.MapKeys() {
+		if key.Kind() == reflect.String {
+			names = append(names, key.String())
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// BucketName returns the S3 bucket a call is made against, or "" for calls
+// that are not made against one.
+func BucketName(params interface{}) string {
+	if bucket, ok := Field[*string](params, "Bucket"); ok {
+		return *bucket
+	}
+
+	return ""
+}
+
+// ConsumedCapacity returns the capacity a DynamoDB output reports, which is a
+// single value or, for batch and transaction calls, one per table.
+func ConsumedCapacity(data interface{}) []*dynamodb.ConsumedCapacity {
+	if consumed, ok := Field[*dynamodb.ConsumedCapacity](data, "ConsumedCapacity"); ok {
+		return []*dynamodb.ConsumedCapacity{consumed}
+	}
+
+	consumed, _ := Field[[]*dynamodb.ConsumedCapacity](data, "ConsumedCapacity")
+	return consumed
+}
+
+// Field returns the named field of the struct v points to, such as the input
+// or output of a call, when it is set and of type T.
+func Field[T any](v interface{}, name string) (T, bool) {
+	var zero T
+
+	f, ok := fieldValue(v, name)
+	if !ok {
+		return zero, false
+	}
+
+	t, ok := f.Interface().(T)
+	return t, ok
+}
+
+func fieldValue(v interface{}, name string) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	f := rv.Elem().FieldByName(name)
+	if !f.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	switch f.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if f.IsNil() {
+			return reflect.Value{}, false
+		}
+	}
+
+	return f, true
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_aws_services/internal/observe"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	callLabels     = []string{"service", "operation", "table", "bucket", "outcome", "error_code"}
+	throttleLabels = []string{"service", "operation", "table", "bucket", "error_code"}
+)
+
+// WithRegistry registers the metrics with registry, so they are served together
+// with the metrics already in it. It defaults to a new, empty registry.
+func WithRegistry(registry *prometheus.Registry) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithNamespace sets the prefix of the metric names. It defaults to
+// DefaultNamespace.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithBuckets sets the upper bounds of the duration histogram buckets, in
+// seconds. They default to prometheus.DefBuckets.
+func WithBuckets(buckets ...float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// New creates the metrics and registers them.
+//
+// Parameters:
+//
+//	opts (...Option): The registry, namespace and histogram buckets.
+//
+// Returns:
+//
+//	(*Metrics, error): The metrics, or an error if they could not be registered.
+func New(opts ...Option) (*Metrics, error) {
+	o := &options{namespace: DefaultNamespace, buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.registry == nil {
+		o.registry = prometheus.NewRegistry()
+	}
+
+	if len(o.buckets) == 0 {
+		return nil, errors.New("at least one histogram bucket is required")
+	}
+
+	m := &Metrics{
+		registry: o.registry,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      RequestsMetric,
+			Help:      "Number of AWS calls by outcome and error code.",
+		}, callLabels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      DurationMetric,
+			Help:      "Duration of AWS calls, including retries.",
+			Buckets:   o.buckets,
+		}, callLabels),
+		throttles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      ThrottlesMetric,
+			Help:      "Number of AWS call attempts that were throttled.",
+		}, throttleLabels),
+	}
+
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.throttles} {
+		if err := m.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Handler returns the handler that serves the registry in the Prometheus
+// exposition format, to be mounted on e.g. /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry returns the registry the metrics are registered with.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Install adds the metrics to handlers, replacing any metrics installed before,
+// so it can be called on a client that inherited handlers from a session.
+func (m *Metrics) Install(handlers *request.Handlers) {
+	observe.Install(handlers, handlerName, observer{m})
+}
+
+// Uninstall removes the metrics from handlers.
+func Uninstall(handlers *request.Handlers) {
+	observe.Uninstall(handlers, handlerName)
+}
+
+// observer counts calls for Metrics without adding the observe methods to its
+// API.
+type observer struct {
+	*Metrics
+}
+
+func (o observer) Start(r *request.Request) interface{} {
+	return time.Now()
+}
+
+func (o observer) Retry(r *request.Request, _ interface{}) {
+	if !r.IsErrorThrottle() {
+		return
+	}
+
+	service, operation, table, bucket := resource(r)
+	o.throttles.WithLabelValues(service, operation, table, bucket, observe.ErrorType(r.Error)).Inc()
+}
+
+func (o observer) End(r *request.Request, state interface{}) {
+	outcome, code := OutcomeSuccess, ""
+	if r.Error != nil {
+		outcome, code = OutcomeError, observe.ErrorType(r.Error)
+	}
+
+	service, operation, table, bucket := resource(r)
+	o.requests.WithLabelValues(service, operation, table, bucket, outcome, code).Inc()
+	o.duration.WithLabelValues(service, operation, table, bucket, outcome, code).Observe(time.Since(state.(time.Time)).Seconds())
+}
+
+// resource returns the labels naming what a call was made against. The tables
+// of a batch call are joined with commas; labels that do not apply are empty.
+func resource(r *request.Request) (service, operation, table, bucket string) {
+	table = strings.Join(observe.TableNames(r.Params), ",")
+	bucket = observe.BucketName(r.Params)
+
+	return r.ClientInfo.ServiceID, r.Operation.Name, table, bucket
+}
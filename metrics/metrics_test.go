@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_aws_services/internal/awstest"
+	"go_aws_services/session"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetrics(t *testing.T, opts ...Option) *Metrics {
+	t.Helper()
+
+	m, err := New(opts...)
+	require.NoError(t, err)
+
+	return m
+}
+
+func getItem(table string) *dynamodb.GetItemInput {
+	return &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String("1")}},
+	}
+}
+
+func TestDynamoDBCalls(t *testing.T) {
+	m := newMetrics(t)
+	client := dynamodb.New(awstest.NewSession(t, awstest.OK, awstest.Invalid))
+	client.Retryer = session.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}.Retryer()
+	m.Install(&client.Handlers)
+
+	_, err := client.GetItem(getItem("users"))
+	require.NoError(t, err)
+	_, err = client.GetItem(getItem("users"))
+	require.Error(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("DynamoDB", "GetItem", "users", "", OutcomeSuccess, "")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("DynamoDB", "GetItem", "users", "", OutcomeError, "ValidationException")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.duration))
+	assert.Equal(t, 0, testutil.CollectAndCount(m.throttles))
+}
+
+func TestThrottles(t *testing.T) {
+	m := newMetrics(t)
+	client := dynamodb.New(awstest.NewSession(t, awstest.Throttled, awstest.Throttled, awstest.OK))
+	client.Retryer = session.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}.Retryer()
+	m.Install(&client.Handlers)
+
+	_, err := client.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: map[string]*dynamodb.KeysAndAttributes{
+		"users":  {Keys: []map[string]*dynamodb.AttributeValue{{"id": {S: aws.String("1")}}}},
+		"orders": {Keys: []map[string]*dynamodb.AttributeValue{{"id": {S: aws.String("1")}}}},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.throttles.WithLabelValues("DynamoDB", "BatchGetItem", "orders,users", "", "ProvisionedThroughputExceededException")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("DynamoDB", "BatchGetItem", "orders,users", "", OutcomeSuccess, "")))
+}
+
+func TestS3Calls(t *testing.T) {
+	m := newMetrics(t)
+	client := s3.New(awstest.NewSession(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	m.Install(&client.Handlers)
+
+	_, err := client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String("assets"), Key: aws.String("missing.txt")})
+	require.Error(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("S3", "HeadObject", "", "assets", OutcomeError, "NotFound")))
+}
+
+func TestPresignIsNotCounted(t *testing.T) {
+	m := newMetrics(t)
+	client := s3.New(awstest.NewSession(t, awstest.OK))
+	m.Install(&client.Handlers)
+
+	req, _ := client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String("assets"), Key: aws.String("a.txt")})
+	_, err := req.Presign(time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, testutil.CollectAndCount(m.requests))
+}
+
+func TestUninstall(t *testing.T) {
+	m := newMetrics(t)
+	client := dynamodb.New(awstest.NewSession(t, awstest.OK))
+	m.Install(&client.Handlers)
+	m.Install(&client.Handlers)
+
+	_, err := client.GetItem(getItem("users"))
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("DynamoDB", "GetItem", "users", "", OutcomeSuccess, "")))
+
+	Uninstall(&client.Handlers)
+	_, err = client.GetItem(getItem("users"))
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("DynamoDB", "GetItem", "users", "", OutcomeSuccess, "")))
+}
+
+func TestHandler(t *testing.T) {
+	m := newMetrics(t, WithNamespace("app"), WithBuckets(0.1, 1))
+	client := dynamodb.New(awstest.NewSession(t, awstest.OK))
+	m.Install(&client.Handlers)
+
+	_, err := client.GetItem(getItem("users"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `app_requests_total{bucket="",error_code="",operation="GetItem",outcome="success",service="DynamoDB",table="users"} 1`)
+	assert.Contains(t, string(body), `app_request_duration_seconds_bucket{bucket="",error_code="",operation="GetItem",outcome="success",service="DynamoDB",table="users",le="1"} 1`)
+	assert.False(t, strings.Contains(string(body), "app_throttles_total{"))
+}
+
+func TestNew(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := newMetrics(t, WithRegistry(registry))
+	assert.Same(t, registry, m.Registry())
+
+	_, err := New(WithRegistry(registry))
+	assert.Error(t, err, "registering the same metrics twice")
+
+	_, err = New(WithBuckets())
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNamespace prefixes the metric names unless WithNamespace sets another.
+const DefaultNamespace = "aws_client"
+
+const (
+	// RequestsMetric counts calls by outcome and error code.
+	RequestsMetric = "requests_total"
+	// DurationMetric records how long each call took, including retries.
+	DurationMetric = "request_duration_seconds"
+	// ThrottlesMetric counts attempts that AWS throttled.
+	ThrottlesMetric = "throttles_total"
+)
+
+const (
+	// OutcomeSuccess labels calls that succeeded, possibly after retries.
+	OutcomeSuccess = "success"
+	// OutcomeError labels calls that failed after any retries.
+	OutcomeError = "error"
+)
+
+// handlerName prefixes the names of the handlers the metrics install.
+const handlerName = "go_aws_services.Metrics"
+
+// Metrics records Prometheus metrics for AWS calls and serves them for
+// scraping. Like telemetry.Instrumentation it is installed into the handlers of
+// a session or client, so every call made through them is counted, including
+// calls made on the raw SDK client.
+type Metrics struct {
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	throttles *prometheus.CounterVec
+}
+
+// Option customises Metrics.
+type Option func(*options)
+
+type options struct {
+	registry  *prometheus.Registry
+	namespace string
+	buckets   []float64
+}
@@ -0,0 +1,623 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Error codes S3 returns when a setting has never been configured.
+const (
+	errCodeNoSuchCORS              = "NoSuchCORSConfiguration"
+	errCodeNoSuchLifecycle         = "NoSuchLifecycleConfiguration"
+	errCodeNoSuchPublicAccessBlock = "NoSuchPublicAccessBlockConfiguration"
+)
+
+// ErrUnsupportedLifecycle is returned when the bucket has lifecycle rules that
+// LifecycleRule cannot represent, such as transitions or tag filters. S3 only
+// replaces the lifecycle configuration as a whole, so managing it would drop
+// them.
+var ErrUnsupportedLifecycle = errors.New("bucket has lifecycle rules LifecycleRule cannot represent")
+
+// DefaultBucketConfig returns the configuration the upload flow relies on:
+// browsers on allowedOrigins may PUT, POST and GET objects and read their ETag,
+// incomplete multipart uploads under the key prefix are aborted after a day,
+// overwritten versions are expired after 30 days, versioning is enabled and all
+// public access is blocked.
+func (s *S3Service) DefaultBucketConfig(allowedOrigins ...string) BucketConfig {
+	prefix := s.effectivePrefix()
+
+	return BucketConfig{
+		CORS: []CORSRule{{
+			ID:             "browser-uploads",
+			AllowedOrigins: allowedOrigins,
+			AllowedMethods: []string{"GET", "HEAD", "POST", "PUT"},
+			AllowedHeaders: []string{"*"},
+			ExposeHeaders:  []string{"ETag", "x-amz-checksum-sha256"},
+			MaxAgeSeconds:  3000,
+		}},
+		Lifecycle: []LifecycleRule{{
+			ID:                                 "abandoned-uploads",
+			Prefix:                             prefix,
+			NoncurrentVersionExpirationDays:    30,
+			AbortIncompleteMultipartUploadDays: 1,
+		}},
+		Versioning: VersioningEnabled,
+		PublicAccessBlock: &PublicAccessBlock{
+			BlockPublicAcls:       true,
+			IgnorePublicAcls:      true,
+			BlockPublicPolicy:     true,
+			RestrictPublicBuckets: true,
+		},
+	}
+}
+
+// Validate reports the first problem S3 would reject the configuration for.
+func (c BucketConfig) Validate() error {
+	for i, rule := range c.CORS {
+		if len(rule.AllowedOrigins) == 0 || len(rule.AllowedMethods) == 0 {
+			return fmt.Errorf("CORS rule %d needs allowed origins and methods", i)
+		}
+
+		for _, method := range rule.AllowedMethods {
+			switch strings.ToUpper(method) {
+			case "GET", "PUT", "POST", "DELETE", "HEAD":
+			default:
+				return fmt.Errorf("CORS rule %d: unsupported method %q", i, method)
+			}
+		}
+	}
+
+	ids := make(map[string]bool, len(c.Lifecycle))
+	for i, rule := range c.Lifecycle {
+		if rule.ID == "" {
+			return fmt.Errorf("lifecycle rule %d needs an ID", i)
+		}
+
+		if ids[rule.ID] {
+			return fmt.Errorf("duplicate lifecycle rule ID %q", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		if rule.ExpirationDays < 0 || rule.NoncurrentVersionExpirationDays < 0 || rule.AbortIncompleteMultipartUploadDays < 0 {
+			return fmt.Errorf("lifecycle rule %q: day counts cannot be negative", rule.ID)
+		}
+
+		if rule.ExpirationDays == 0 && rule.NoncurrentVersionExpirationDays == 0 && rule.AbortIncompleteMultipartUploadDays == 0 {
+			return fmt.Errorf("lifecycle rule %q has no action", rule.ID)
+		}
+	}
+
+	switch c.Versioning {
+	case "", VersioningEnabled, VersioningSuspended:
+	default:
+		return fmt.Errorf("invalid versioning status %q", c.Versioning)
+	}
+
+	return nil
+}
+
+// Empty reports whether the bucket already matches the desired configuration.
+func (p BucketPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the plan for review, one line per change, e.g.
+//
+//	bucket brunojet-storage: 2 changes
+//	  update versioning: Suspended -> Enabled
+//	  create lifecycle: 1 rule
+func (p BucketPlan) String() string {
+	var b strings.Builder
+
+	if p.Empty() {
+		fmt.Fprintf(&b, "bucket %s: up to date", p.Bucket)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "bucket %s: %d change", p.Bucket, len(p.Changes))
+	if len(p.Changes) > 1 {
+		b.WriteString("s")
+	}
+
+	for _, change := range p.Changes {
+		fmt.Fprintf(&b, "\n  %s %s", change.Action, change.Setting)
+		switch change.Action {
+		case ActionCreate:
+			fmt.Fprintf(&b, ": %s", describeSetting(change.Desired))
+		case ActionUpdate:
+			fmt.Fprintf(&b, ": %s -> %s", describeSetting(change.Current), describeSetting(change.Desired))
+		}
+	}
+
+	return b.String()
+}
+
+func describeSetting(value interface{}) string {
+	count := func(n int, noun string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", noun)
+		}
+		return fmt.Sprintf("%d %ss", n, noun)
+	}
+
+	switch v := value.(type) {
+	case []CORSRule:
+		return count(len(v), "rule")
+	case []LifecycleRule:
+		return count(len(v), "rule")
+	case *PublicAccessBlock:
+		return fmt.Sprintf("%+v", *v)
+	case string:
+		if v == "" {
+			return "Unversioned"
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// CurrentBucketConfig reads the CORS rules, lifecycle rules, versioning status
+// and public access block the bucket has now. Settings that were never
+// configured are returned empty, with Versioning "" for a bucket that has never
+// been versioned. A lifecycle rule LifecycleRule cannot represent fails the read
+// with ErrUnsupportedLifecycle and an empty BucketConfig.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the requests.
+//
+// Returns:
+//
+//	(BucketConfig, error): The current configuration, or an error if any of it could not be read.
+func (s *S3Service) CurrentBucketConfig(ctx context.Context) (BucketConfig, error) {
+	current, err := s.readBucketConfig(ctx)
+	if err != nil {
+		return BucketConfig{}, err
+	}
+
+	if current.unsupportedLifecycle != nil {
+		return BucketConfig{}, current.unsupportedLifecycle
+	}
+
+	return current.config, nil
+}
+
+// currentBucket is the configuration read from the bucket. When its lifecycle
+// rules cannot be represented, config holds no lifecycle rules and
+// unsupportedLifecycle says why; that only matters when the lifecycle
+// configuration is managed.
+type currentBucket struct {
+	config               BucketConfig
+	unsupportedLifecycle error
+}
+
+// readBucketConfig reads the current configuration.
+func (s *S3Service) readBucketConfig(ctx context.Context) (currentBucket, error) {
+	bucket := aws.String(s.bucketName())
+	current := currentBucket{config: BucketConfig{CORS: []CORSRule{}, Lifecycle: []LifecycleRule{}}}
+	config := &current.config
+
+	cors, err := s.api().GetBucketCorsWithContext(ctx, &s3.GetBucketCorsInput{Bucket: bucket})
+	if err != nil && !isErrorCode(err, errCodeNoSuchCORS) {
+		return currentBucket{}, err
+	}
+	if err == nil {
+		config.CORS = corsRulesFromS3(cors.CORSRules)
+	}
+
+	lifecycle, err := s.api().GetBucketLifecycleConfigurationWithContext(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: bucket})
+	if err != nil && !isErrorCode(err, errCodeNoSuchLifecycle) {
+		return currentBucket{}, err
+	}
+	if err == nil {
+		config.Lifecycle, current.unsupportedLifecycle = lifecycleRulesFromS3(lifecycle.Rules)
+	}
+
+	versioning, err := s.api().GetBucketVersioningWithContext(ctx, &s3.GetBucketVersioningInput{Bucket: bucket})
+	if err != nil {
+		return currentBucket{}, err
+	}
+	config.Versioning = aws.StringValue(versioning.Status)
+
+	block, err := s.api().GetPublicAccessBlockWithContext(ctx, &s3.GetPublicAccessBlockInput{Bucket: bucket})
+	if err != nil && !isErrorCode(err, errCodeNoSuchPublicAccessBlock) {
+		return currentBucket{}, err
+	}
+	if err == nil && block.PublicAccessBlockConfiguration != nil {
+		c := block.PublicAccessBlockConfiguration
+		config.PublicAccessBlock = &PublicAccessBlock{
+			BlockPublicAcls:       aws.BoolValue(c.BlockPublicAcls),
+			IgnorePublicAcls:      aws.BoolValue(c.IgnorePublicAcls),
+			BlockPublicPolicy:     aws.BoolValue(c.BlockPublicPolicy),
+			RestrictPublicBuckets: aws.BoolValue(c.RestrictPublicBuckets),
+		}
+	}
+
+	return current, nil
+}
+
+// PlanBucketConfig compares the bucket with desired and returns the changes
+// needed to reconcile them, without making any. CORS rules are compared in
+// order, since S3 applies the first match, while lifecycle rules are compared
+// by ID. Origins, methods and headers within a rule are compared as sets. When
+// desired manages the lifecycle but the bucket has rules LifecycleRule cannot
+// represent, planning fails with ErrUnsupportedLifecycle rather than planning
+// to drop them.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the requests.
+//	desired (BucketConfig): The configuration the bucket should have.
+//
+// Returns:
+//
+//	(BucketPlan, error): The changes to make, or an error if desired is invalid or the bucket could not be read.
+func (s *S3Service) PlanBucketConfig(ctx context.Context, desired BucketConfig) (BucketPlan, error) {
+	if err := desired.Validate(); err != nil {
+		return BucketPlan{}, err
+	}
+
+	current, err := s.readBucketConfig(ctx)
+	if err != nil {
+		return BucketPlan{}, err
+	}
+
+	if desired.Lifecycle != nil && current.unsupportedLifecycle != nil {
+		return BucketPlan{}, current.unsupportedLifecycle
+	}
+
+	return diffBucketConfig(s.bucketName(), current.config, desired), nil
+}
+
+// ApplyBucketConfig brings the bucket to desired and returns the plan it
+// carried out. Applying the same configuration again makes no requests beyond
+// the reads, since the plan is empty. With dryRun set only the plan is returned,
+// as PlanBucketConfig does.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the requests.
+//	desired (BucketConfig): The configuration the bucket should have.
+//	dryRun (bool): Whether to only plan the changes.
+//
+// Returns:
+//
+//	(BucketPlan, error): The planned changes, or an error naming the first setting that could not be changed.
+func (s *S3Service) ApplyBucketConfig(ctx context.Context, desired BucketConfig, dryRun bool) (BucketPlan, error) {
+	plan, err := s.PlanBucketConfig(ctx, desired)
+	if err != nil || dryRun {
+		return plan, err
+	}
+
+	for _, change := range plan.Changes {
+		if err := s.applyBucketChange(ctx, change); err != nil {
+			return plan, fmt.Errorf("%s %s: %w", change.Action, change.Setting, err)
+		}
+	}
+
+	return plan, nil
+}
+
+func (s *S3Service) applyBucketChange(ctx context.Context, change BucketChange) error {
+	bucket := aws.String(s.bucketName())
+
+	switch change.Setting {
+	case SettingCORS:
+		if change.Action == ActionDelete {
+			_, err := s.api().DeleteBucketCorsWithContext(ctx, &s3.DeleteBucketCorsInput{Bucket: bucket})
+			return err
+		}
+
+		_, err := s.api().PutBucketCorsWithContext(ctx, &s3.PutBucketCorsInput{
+			Bucket:            bucket,
+			CORSConfiguration: &s3.CORSConfiguration{CORSRules: corsRulesToS3(change.Desired.([]CORSRule))},
+		})
+		return err
+
+	case SettingLifecycle:
+		if change.Action == ActionDelete {
+			_, err := s.api().DeleteBucketLifecycleWithContext(ctx, &s3.DeleteBucketLifecycleInput{Bucket: bucket})
+			return err
+		}
+
+		_, err := s.api().PutBucketLifecycleConfigurationWithContext(ctx, &s3.PutBucketLifecycleConfigurationInput{
+			Bucket:                 bucket,
+			LifecycleConfiguration: &s3.BucketLifecycleConfiguration{Rules: lifecycleRulesToS3(change.Desired.([]LifecycleRule))},
+		})
+		return err
+
+	case SettingVersioning:
+		_, err := s.api().PutBucketVersioningWithContext(ctx, &s3.PutBucketVersioningInput{
+			Bucket:                  bucket,
+			VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String(change.Desired.(string))},
+		})
+		return err
+
+	case SettingPublicAccessBlock:
+		block := change.Desired.(*PublicAccessBlock)
+		_, err := s.api().PutPublicAccessBlockWithContext(ctx, &s3.PutPublicAccessBlockInput{
+			Bucket: bucket,
+			PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(block.BlockPublicAcls),
+				IgnorePublicAcls:      aws.Bool(block.IgnorePublicAcls),
+				BlockPublicPolicy:     aws.Bool(block.BlockPublicPolicy),
+				RestrictPublicBuckets: aws.Bool(block.RestrictPublicBuckets),
+			},
+		})
+		return err
+	}
+
+	return fmt.Errorf("unknown bucket setting %q", change.Setting)
+}
+
+// diffBucketConfig lists the changes that turn current into desired, in the
+// order CORS, lifecycle, versioning, public access block.
+func diffBucketConfig(bucket string, current BucketConfig, desired BucketConfig) BucketPlan {
+	plan := BucketPlan{Bucket: bucket, Changes: []BucketChange{}}
+
+	if desired.CORS != nil {
+		have, want := normalizeCORSRules(current.CORS), normalizeCORSRules(desired.CORS)
+		if change, ok := diffRules(SettingCORS, have, want, len(have), len(want)); ok {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	if desired.Lifecycle != nil {
+		have, want := normalizeLifecycleRules(current.Lifecycle), normalizeLifecycleRules(desired.Lifecycle)
+		if change, ok := diffRules(SettingLifecycle, have, want, len(have), len(want)); ok {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	// A bucket that was never versioned behaves as a suspended one.
+	if desired.Versioning != "" && desired.Versioning != current.Versioning &&
+		!(desired.Versioning == VersioningSuspended && current.Versioning == "") {
+		plan.Changes = append(plan.Changes, BucketChange{
+			Setting: SettingVersioning,
+			Action:  ActionUpdate,
+			Current: current.Versioning,
+			Desired: desired.Versioning,
+		})
+	}
+
+	if desired.PublicAccessBlock != nil {
+		switch {
+		case current.PublicAccessBlock == nil:
+			plan.Changes = append(plan.Changes, BucketChange{
+				Setting: SettingPublicAccessBlock,
+				Action:  ActionCreate,
+				Desired: desired.PublicAccessBlock,
+			})
+		case *current.PublicAccessBlock != *desired.PublicAccessBlock:
+			plan.Changes = append(plan.Changes, BucketChange{
+				Setting: SettingPublicAccessBlock,
+				Action:  ActionUpdate,
+				Current: current.PublicAccessBlock,
+				Desired: desired.PublicAccessBlock,
+			})
+		}
+	}
+
+	return plan
+}
+
+// diffRules compares two normalized rule lists. S3 has no empty CORS or
+// lifecycle configuration, so an empty desired list deletes the setting.
+func diffRules(setting string, current interface{}, desired interface{}, haveRules int, wantRules int) (BucketChange, bool) {
+	switch {
+	case reflect.DeepEqual(current, desired):
+		return BucketChange{}, false
+	case wantRules == 0:
+		return BucketChange{Setting: setting, Action: ActionDelete, Current: current}, true
+	case haveRules == 0:
+		return BucketChange{Setting: setting, Action: ActionCreate, Desired: desired}, true
+	default:
+		return BucketChange{Setting: setting, Action: ActionUpdate, Current: current, Desired: desired}, true
+	}
+}
+
+func normalizeCORSRules(rules []CORSRule) []CORSRule {
+	normalized := make([]CORSRule, len(rules))
+	for i, rule := range rules {
+		methods := make([]string, len(rule.AllowedMethods))
+		for j, method := range rule.AllowedMethods {
+			methods[j] = strings.ToUpper(method)
+		}
+
+		normalized[i] = CORSRule{
+			ID:             rule.ID,
+			AllowedOrigins: sortedSet(rule.AllowedOrigins),
+			AllowedMethods: sortedSet(methods),
+			AllowedHeaders: sortedSet(rule.AllowedHeaders),
+			ExposeHeaders:  sortedSet(rule.ExposeHeaders),
+			MaxAgeSeconds:  rule.MaxAgeSeconds,
+		}
+	}
+
+	return normalized
+}
+
+func normalizeLifecycleRules(rules []LifecycleRule) []LifecycleRule {
+	normalized := append([]LifecycleRule{}, rules...)
+	sort.Slice(normalized, func(i, j int) bool {
+		return normalized[i].ID < normalized[j].ID
+	})
+
+	return normalized
+}
+
+// sortedSet returns the distinct values sorted, or nil when there are none, so
+// that a missing list and an empty one compare equal.
+func sortedSet(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	set := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			set = append(set, value)
+		}
+	}
+	sort.Strings(set)
+
+	return set
+}
+
+func corsRulesFromS3(rules []*s3.CORSRule) []CORSRule {
+	converted := make([]CORSRule, 0, len(rules))
+	for _, rule := range rules {
+		converted = append(converted, CORSRule{
+			ID:             aws.StringValue(rule.ID),
+			AllowedOrigins: aws.StringValueSlice(rule.AllowedOrigins),
+			AllowedMethods: aws.StringValueSlice(rule.AllowedMethods),
+			AllowedHeaders: aws.StringValueSlice(rule.AllowedHeaders),
+			ExposeHeaders:  aws.StringValueSlice(rule.ExposeHeaders),
+			MaxAgeSeconds:  aws.Int64Value(rule.MaxAgeSeconds),
+		})
+	}
+
+	return converted
+}
+
+func corsRulesToS3(rules []CORSRule) []*s3.CORSRule {
+	converted := make([]*s3.CORSRule, 0, len(rules))
+	for _, rule := range rules {
+		r := &s3.CORSRule{
+			AllowedOrigins: aws.StringSlice(rule.AllowedOrigins),
+			AllowedMethods: aws.StringSlice(rule.AllowedMethods),
+		}
+
+		if rule.ID != "" {
+			r.ID = aws.String(rule.ID)
+		}
+
+		if len(rule.AllowedHeaders) > 0 {
+			r.AllowedHeaders = aws.StringSlice(rule.AllowedHeaders)
+		}
+
+		if len(rule.ExposeHeaders) > 0 {
+			r.ExposeHeaders = aws.StringSlice(rule.ExposeHeaders)
+		}
+
+		if rule.MaxAgeSeconds > 0 {
+			r.MaxAgeSeconds = aws.Int64(rule.MaxAgeSeconds)
+		}
+
+		converted = append(converted, r)
+	}
+
+	return converted
+}
+
+func lifecycleRulesFromS3(rules []*s3.LifecycleRule) ([]LifecycleRule, error) {
+	converted := make([]LifecycleRule, 0, len(rules))
+	for _, rule := range rules {
+		if field := unsupportedLifecycleField(rule); field != "" {
+			return nil, fmt.Errorf("%w: rule %q has %s", ErrUnsupportedLifecycle, aws.StringValue(rule.ID), field)
+		}
+
+		r := LifecycleRule{
+			ID:       aws.StringValue(rule.ID),
+			Prefix:   aws.StringValue(rule.Prefix),
+			Disabled: aws.StringValue(rule.Status) != s3.ExpirationStatusEnabled,
+		}
+
+		if rule.Filter != nil && rule.Filter.Prefix != nil {
+			r.Prefix = aws.StringValue(rule.Filter.Prefix)
+		}
+
+		if rule.Expiration != nil {
+			r.ExpirationDays = aws.Int64Value(rule.Expiration.Days)
+		}
+
+		if rule.NoncurrentVersionExpiration != nil {
+			r.NoncurrentVersionExpirationDays = aws.Int64Value(rule.NoncurrentVersionExpiration.NoncurrentDays)
+		}
+
+		if rule.AbortIncompleteMultipartUpload != nil {
+			r.AbortIncompleteMultipartUploadDays = aws.Int64Value(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+		}
+
+		converted = append(converted, r)
+	}
+
+	return converted, nil
+}
+
+// unsupportedLifecycleField names the first part of rule LifecycleRule has no
+// field for, or returns "" when the rule converts without loss.
+func unsupportedLifecycleField(rule *s3.LifecycleRule) string {
+	switch {
+	case len(rule.Transitions) > 0:
+		return "transitions"
+	case len(rule.NoncurrentVersionTransitions) > 0:
+		return "noncurrent version transitions"
+	case rule.Expiration != nil && rule.Expiration.Date != nil:
+		return "an expiration date"
+	case rule.Expiration != nil && aws.BoolValue(rule.Expiration.ExpiredObjectDeleteMarker):
+		return "expired object delete marker removal"
+	case rule.NoncurrentVersionExpiration != nil && rule.NoncurrentVersionExpiration.NewerNoncurrentVersions != nil:
+		return "a number of newer noncurrent versions to keep"
+	}
+
+	if filter := rule.Filter; filter != nil {
+		switch {
+		case filter.And != nil:
+			return "an And filter"
+		case filter.Tag != nil:
+			return "a tag filter"
+		case filter.ObjectSizeGreaterThan != nil || filter.ObjectSizeLessThan != nil:
+			return "an object size filter"
+		}
+	}
+
+	return ""
+}
+
+func lifecycleRulesToS3(rules []LifecycleRule) []*s3.LifecycleRule {
+	converted := make([]*s3.LifecycleRule, 0, len(rules))
+	for _, rule := range rules {
+		r := &s3.LifecycleRule{
+			ID:     aws.String(rule.ID),
+			Filter: &s3.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+			Status: aws.String(s3.ExpirationStatusEnabled),
+		}
+
+		if rule.Disabled {
+			r.Status = aws.String(s3.ExpirationStatusDisabled)
+		}
+
+		if rule.ExpirationDays > 0 {
+			r.Expiration = &s3.LifecycleExpiration{Days: aws.Int64(rule.ExpirationDays)}
+		}
+
+		if rule.NoncurrentVersionExpirationDays > 0 {
+			r.NoncurrentVersionExpiration = &s3.NoncurrentVersionExpiration{NoncurrentDays: aws.Int64(rule.NoncurrentVersionExpirationDays)}
+		}
+
+		if rule.AbortIncompleteMultipartUploadDays > 0 {
+			r.AbortIncompleteMultipartUpload = &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int64(rule.AbortIncompleteMultipartUploadDays)}
+		}
+
+		converted = append(converted, r)
+	}
+
+	return converted
+}
+
+func isErrorCode(err error, code string) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == code
+}
@@ -0,0 +1,244 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func notConfigured(code string) error {
+	return awserr.NewRequestFailure(awserr.New(code, "The configuration does not exist", nil), 404, "request-id")
+}
+
+// mockUnconfiguredBucket answers every read as for a new bucket.
+func mockUnconfiguredBucket(mockClient *mockS3Client) {
+	mockClient.On("GetBucketCorsWithContext", mock.Anything, mock.Anything).Return((*s3.GetBucketCorsOutput)(nil), notConfigured(errCodeNoSuchCORS))
+	mockClient.On("GetBucketLifecycleConfigurationWithContext", mock.Anything, mock.Anything).Return((*s3.GetBucketLifecycleConfigurationOutput)(nil), notConfigured(errCodeNoSuchLifecycle))
+	mockClient.On("GetBucketVersioningWithContext", mock.Anything, mock.Anything).Return(&s3.GetBucketVersioningOutput{}, nil)
+	mockClient.On("GetPublicAccessBlockWithContext", mock.Anything, mock.Anything).Return((*s3.GetPublicAccessBlockOutput)(nil), notConfigured(errCodeNoSuchPublicAccessBlock))
+}
+
+// mockConfiguredBucket answers every read with the S3 form of the default
+// configuration for origin, listing values in a different order than
+// DefaultBucketConfig does.
+func mockConfiguredBucket(mockClient *mockS3Client, origin string) {
+	mockClient.On("GetBucketCorsWithContext", mock.Anything, mock.Anything).Return(&s3.GetBucketCorsOutput{
+		CORSRules: []*s3.CORSRule{{
+			ID:             aws.String("browser-uploads"),
+			AllowedOrigins: aws.StringSlice([]string{origin}),
+			AllowedMethods: aws.StringSlice([]string{"PUT", "POST", "HEAD", "GET"}),
+			AllowedHeaders: aws.StringSlice([]string{"*"}),
+			ExposeHeaders:  aws.StringSlice([]string{"x-amz-checksum-sha256", "ETag"}),
+			MaxAgeSeconds:  aws.Int64(3000),
+		}},
+	}, nil)
+	mockClient.On("GetBucketLifecycleConfigurationWithContext", mock.Anything, mock.Anything).Return(&s3.GetBucketLifecycleConfigurationOutput{
+		Rules: []*s3.LifecycleRule{{
+			ID:                             aws.String("abandoned-uploads"),
+			Filter:                         &s3.LifecycleRuleFilter{Prefix: aws.String("uploads/")},
+			Status:                         aws.String("Enabled"),
+			NoncurrentVersionExpiration:    &s3.NoncurrentVersionExpiration{NoncurrentDays: aws.Int64(30)},
+			AbortIncompleteMultipartUpload: &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int64(1)},
+		}},
+	}, nil)
+	mockClient.On("GetBucketVersioningWithContext", mock.Anything, mock.Anything).Return(&s3.GetBucketVersioningOutput{Status: aws.String("Enabled")}, nil)
+	mockClient.On("GetPublicAccessBlockWithContext", mock.Anything, mock.Anything).Return(&s3.GetPublicAccessBlockOutput{
+		PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+	}, nil)
+}
+
+func TestApplyBucketConfig(t *testing.T) {
+	desired := (&S3Service{}).DefaultBucketConfig("https://app.example.com")
+
+	t.Run("New bucket", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockUnconfiguredBucket(mockClient)
+		mockClient.On("PutBucketCorsWithContext", mock.Anything, mock.MatchedBy(func(input *s3.PutBucketCorsInput) bool {
+			rule := input.CORSConfiguration.CORSRules[0]
+			return aws.StringValue(input.Bucket) == "test-bucket" &&
+				assert.ObjectsAreEqual([]string{"https://app.example.com"}, aws.StringValueSlice(rule.AllowedOrigins))
+		})).Return(&s3.PutBucketCorsOutput{}, nil).Once()
+		mockClient.On("PutBucketLifecycleConfigurationWithContext", mock.Anything, mock.MatchedBy(func(input *s3.PutBucketLifecycleConfigurationInput) bool {
+			rule := input.LifecycleConfiguration.Rules[0]
+			return aws.StringValue(rule.Filter.Prefix) == "uploads/" &&
+				aws.StringValue(rule.Status) == "Enabled" &&
+				rule.Expiration == nil &&
+				aws.Int64Value(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation) == 1
+		})).Return(&s3.PutBucketLifecycleConfigurationOutput{}, nil).Once()
+		mockClient.On("PutBucketVersioningWithContext", mock.Anything, &s3.PutBucketVersioningInput{
+			Bucket:                  aws.String("test-bucket"),
+			VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String("Enabled")},
+		}).Return(&s3.PutBucketVersioningOutput{}, nil).Once()
+		mockClient.On("PutPublicAccessBlockWithContext", mock.Anything, mock.Anything).Return(&s3.PutPublicAccessBlockOutput{}, nil).Once()
+
+		plan, err := service.ApplyBucketConfig(context.Background(), desired, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{SettingCORS, SettingLifecycle, SettingVersioning, SettingPublicAccessBlock}, settings(plan))
+		assert.Equal(t, ActionCreate, plan.Changes[0].Action)
+		assert.Equal(t, ActionUpdate, plan.Changes[2].Action)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockUnconfiguredBucket(mockClient)
+
+		plan, err := service.ApplyBucketConfig(context.Background(), desired, true)
+
+		assert.NoError(t, err)
+		assert.Len(t, plan.Changes, 4)
+		assert.Equal(t, "bucket test-bucket: 4 changes\n"+
+			"  create cors: 1 rule\n"+
+			"  create lifecycle: 1 rule\n"+
+			"  update versioning: Unversioned -> Enabled\n"+
+			"  create publicAccessBlock: {BlockPublicAcls:true IgnorePublicAcls:true BlockPublicPolicy:true RestrictPublicBuckets:true}",
+			plan.String())
+		mockClient.AssertNotCalled(t, "PutBucketCorsWithContext", mock.Anything, mock.Anything)
+		mockClient.AssertNotCalled(t, "PutBucketVersioningWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already configured", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockConfiguredBucket(mockClient, "https://app.example.com")
+
+		plan, err := service.ApplyBucketConfig(context.Background(), desired, false)
+
+		assert.NoError(t, err)
+		assert.True(t, plan.Empty())
+		assert.Equal(t, "bucket test-bucket: up to date", plan.String())
+		mockClient.AssertNotCalled(t, "PutBucketCorsWithContext", mock.Anything, mock.Anything)
+		mockClient.AssertNotCalled(t, "PutBucketLifecycleConfigurationWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Changed origin", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockConfiguredBucket(mockClient, "https://old.example.com")
+		mockClient.On("PutBucketCorsWithContext", mock.Anything, mock.Anything).Return(&s3.PutBucketCorsOutput{}, nil).Once()
+
+		plan, err := service.ApplyBucketConfig(context.Background(), desired, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{SettingCORS}, settings(plan))
+		assert.Equal(t, ActionUpdate, plan.Changes[0].Action)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Removes rules and leaves unmanaged settings", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockConfiguredBucket(mockClient, "https://app.example.com")
+		mockClient.On("DeleteBucketCorsWithContext", mock.Anything, &s3.DeleteBucketCorsInput{Bucket: aws.String("test-bucket")}).
+			Return(&s3.DeleteBucketCorsOutput{}, nil).Once()
+
+		plan, err := service.ApplyBucketConfig(context.Background(), BucketConfig{CORS: []CORSRule{}}, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []BucketChange{{Setting: SettingCORS, Action: ActionDelete, Current: plan.Changes[0].Current}}, plan.Changes)
+		assert.Equal(t, "bucket test-bucket: 1 change\n  delete cors", plan.String())
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Suspended matches an unversioned bucket", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockUnconfiguredBucket(mockClient)
+
+		plan, err := service.PlanBucketConfig(context.Background(), BucketConfig{Versioning: VersioningSuspended})
+
+		assert.NoError(t, err)
+		assert.True(t, plan.Empty())
+	})
+
+	t.Run("Unsupported lifecycle rule", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("GetBucketLifecycleConfigurationWithContext", mock.Anything, mock.Anything).Return(&s3.GetBucketLifecycleConfigurationOutput{
+			Rules: []*s3.LifecycleRule{{
+				ID:          aws.String("archive"),
+				Filter:      &s3.LifecycleRuleFilter{Prefix: aws.String("apks/")},
+				Status:      aws.String("Enabled"),
+				Transitions: []*s3.Transition{{Days: aws.Int64(30), StorageClass: aws.String(s3.TransitionStorageClassGlacier)}},
+			}},
+		}, nil)
+		mockConfiguredBucket(mockClient, "https://app.example.com")
+
+		_, err := service.ApplyBucketConfig(context.Background(), desired, false)
+
+		assert.ErrorIs(t, err, ErrUnsupportedLifecycle)
+		assert.EqualError(t, err, `bucket has lifecycle rules LifecycleRule cannot represent: rule "archive" has transitions`)
+		mockClient.AssertNotCalled(t, "PutBucketCorsWithContext", mock.Anything, mock.Anything)
+		mockClient.AssertNotCalled(t, "PutBucketLifecycleConfigurationWithContext", mock.Anything, mock.Anything)
+
+		config, err := service.CurrentBucketConfig(context.Background())
+
+		assert.ErrorIs(t, err, ErrUnsupportedLifecycle)
+		assert.Equal(t, BucketConfig{}, config)
+
+		// Settings other than the lifecycle can still be managed.
+		plan, err := service.PlanBucketConfig(context.Background(), BucketConfig{Versioning: VersioningEnabled})
+
+		assert.NoError(t, err)
+		assert.True(t, plan.Empty())
+	})
+
+	t.Run("Read failure", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("GetBucketCorsWithContext", mock.Anything, mock.Anything).Return((*s3.GetBucketCorsOutput)(nil), errors.New("access denied"))
+
+		_, err := service.ApplyBucketConfig(context.Background(), desired, false)
+
+		assert.EqualError(t, err, "access denied")
+	})
+
+	t.Run("Write failure", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockUnconfiguredBucket(mockClient)
+		mockClient.On("PutBucketCorsWithContext", mock.Anything, mock.Anything).Return((*s3.PutBucketCorsOutput)(nil), errors.New("access denied"))
+
+		plan, err := service.ApplyBucketConfig(context.Background(), desired, false)
+
+		assert.EqualError(t, err, "create cors: access denied")
+		assert.Len(t, plan.Changes, 4)
+		mockClient.AssertNotCalled(t, "PutBucketLifecycleConfigurationWithContext", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBucketConfigValidate(t *testing.T) {
+	assert.NoError(t, (&S3Service{}).DefaultBucketConfig("*").Validate())
+
+	tests := []struct {
+		name   string
+		config BucketConfig
+		err    string
+	}{
+		{"CORS without origins", BucketConfig{CORS: []CORSRule{{AllowedMethods: []string{"GET"}}}}, "CORS rule 0 needs allowed origins and methods"},
+		{"CORS method", BucketConfig{CORS: []CORSRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"PATCH"}}}}, `CORS rule 0: unsupported method "PATCH"`},
+		{"Lifecycle without ID", BucketConfig{Lifecycle: []LifecycleRule{{ExpirationDays: 1}}}, "lifecycle rule 0 needs an ID"},
+		{"Duplicate lifecycle ID", BucketConfig{Lifecycle: []LifecycleRule{{ID: "a", ExpirationDays: 1}, {ID: "a", ExpirationDays: 2}}}, `duplicate lifecycle rule ID "a"`},
+		{"Lifecycle without action", BucketConfig{Lifecycle: []LifecycleRule{{ID: "a"}}}, `lifecycle rule "a" has no action`},
+		{"Versioning", BucketConfig{Versioning: "On"}, `invalid versioning status "On"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.config.Validate(), tt.err)
+		})
+	}
+}
+
+func settings(plan BucketPlan) []string {
+	var names []string
+	for _, change := range plan.Changes {
+		names = append(names, change.Setting)
+	}
+	return names
+}
@@ -2,6 +2,8 @@ package s3
 
 import (
 	"errors"
+	"sync"
+
 	"go_aws_services/session"
 
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -9,12 +11,18 @@ import (
 )
 
 var (
-	s3Client s3iface.S3API = nil
+	getAwsSession               = session.GetAWSSession
+	newS3                       = s3.New
+	s3Client      s3iface.S3API = nil
+	s3ClientMu    sync.Mutex
 )
 
 func initAwsS3() s3iface.S3API {
+	s3ClientMu.Lock()
+	defer s3ClientMu.Unlock()
+
 	if s3Client == nil {
-		s3Client = s3.New(session.GetAWSSession())
+		s3Client = newS3(getAwsSession())
 		if s3Client == nil {
 			panic(errors.New("failed to create s3 client"))
 		}
@@ -0,0 +1,467 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrObjectChanged is returned when a download is resumed but the object is no
+// longer the version the bytes already written came from.
+var ErrObjectChanged = errors.New("object changed since the download started")
+
+// DownloadError is returned when a download stops part way. Offset is the length
+// of the prefix that was written completely and ETag the version it was read
+// from; passing both as DownloadOptions.Offset and DownloadOptions.ETag resumes
+// the download from there.
+type DownloadError struct {
+	Key    string
+	Offset int64
+	ETag   string
+	Err    error
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("download of %s stopped at byte %d: %v", e.Key, e.Offset, e.Err)
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+func (r byteRange) header() string {
+	return fmt.Sprintf("bytes=%d-%d", r.start, r.end)
+}
+
+func (r byteRange) length() int64 {
+	return r.end - r.start + 1
+}
+
+// Download writes the object stored under key to w, fetching opts.PartSize byte
+// ranges with opts.Concurrency parallel GETs. Every range is requested with the
+// ETag of the object seen at the start, so an object replaced mid-download fails
+// the download instead of mixing two versions. When a range keeps failing the
+// returned *DownloadError tells where to resume. A resumed download pins the
+// ETag it is given, so it fails with ErrObjectChanged rather than appending
+// another version to the bytes already written.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the download.
+//	key (string): The object key.
+//	w (io.WriterAt): The destination, e.g. an *os.File.
+//	opts (DownloadOptions): The part size, concurrency, retries, resume offset, progress callback and encryption.
+//
+// Returns:
+//
+//	(*DownloadOutput, error): The key, ETag and size of the object, or an error if the download failed.
+func (s *S3Service) Download(ctx context.Context, key string, w io.WriterAt, opts DownloadOptions) (*DownloadOutput, error) {
+	if err := applyDownloadDefaults(&opts); err != nil {
+		return nil, err
+	}
+
+	encryption, err := s.resolveEncryption(opts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	opts.Encryption = &encryption
+
+	headInput := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	}
+
+	if opts.ETag != "" {
+		headInput.IfMatch = aws.String(opts.ETag)
+	}
+
+	encryption.params().applyHead(headInput)
+
+	head, err := s.api().HeadObjectWithContext(ctx, headInput)
+	if opts.ETag != "" && (isPreconditionFailed(err) || err == nil && aws.StringValue(head.ETag) != opts.ETag) {
+		return nil, fmt.Errorf("cannot resume download of %s: %w", key, ErrObjectChanged)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	size := aws.Int64Value(head.ContentLength)
+	etag := aws.StringValue(head.ETag)
+
+	if opts.Offset > size {
+		return nil, fmt.Errorf("offset %d is beyond the object size %d", opts.Offset, size)
+	}
+
+	var ranges []byteRange
+	for start := opts.Offset; start < size; start += opts.PartSize {
+		ranges = append(ranges, byteRange{start: start, end: min(start+opts.PartSize, size) - 1})
+	}
+
+	if err := s.downloadRanges(ctx, key, etag, w, ranges, opts); err != nil {
+		return nil, err
+	}
+
+	return &DownloadOutput{
+		Key:     key,
+		ETag:    etag,
+		Size:    size,
+		Written: size - opts.Offset,
+	}, nil
+}
+
+func applyDownloadDefaults(opts *DownloadOptions) error {
+	if opts.PartSize == 0 {
+		opts.PartSize = DefaultPartSize
+	}
+
+	if opts.PartSize < 0 {
+		return errors.New("part size cannot be negative")
+	}
+
+	if opts.Offset < 0 {
+		return errors.New("offset cannot be negative")
+	}
+
+	if opts.Offset > 0 && opts.ETag == "" {
+		return errors.New("resuming a download requires the ETag of the interrupted download")
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultUploadConcurrency
+	}
+
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = DefaultPartRetries
+	}
+
+	return nil
+}
+
+// downloadRanges fetches ranges with opts.Concurrency workers. On failure it
+// reports the end of the contiguous run of finished ranges as the resume offset.
+func (s *S3Service) downloadRanges(ctx context.Context, key string, etag string, w io.WriterAt, ranges []byteRange, opts DownloadOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+		done        = make([]bool, len(ranges))
+		written     int64
+		downloadErr error
+	)
+
+	queue := make(chan int)
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range queue {
+				err := s.downloadRange(ctx, key, etag, w, ranges[index], opts.Encryption.params(), opts.MaxRetries)
+
+				mu.Lock()
+				if err != nil {
+					if downloadErr == nil {
+						downloadErr = err
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				done[index] = true
+				written += ranges[index].length()
+				if opts.Progress != nil {
+					opts.Progress(written)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for index := range ranges {
+		select {
+		case queue <- index:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if downloadErr == nil && ctx.Err() != nil {
+		downloadErr = ctx.Err()
+	}
+
+	if downloadErr == nil {
+		return nil
+	}
+
+	offset := opts.Offset
+	for index, finished := range done {
+		if !finished {
+			break
+		}
+		offset = ranges[index].end + 1
+	}
+
+	return &DownloadError{Key: key, Offset: offset, ETag: etag, Err: downloadErr}
+}
+
+func (s *S3Service) downloadRange(ctx context.Context, key string, etag string, w io.WriterAt, r byteRange, sse sseParams, maxRetries int) error {
+	backoff := partRetryBackoff
+
+	var err error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		err = s.copyRange(ctx, key, etag, io.NewOffsetWriter(w, r.start), r, sse)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil || isPreconditionFailed(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("range %s failed after %d retries: %v", r.header(), maxRetries, err)
+}
+
+func (s *S3Service) copyRange(ctx context.Context, key string, etag string, w io.Writer, r byteRange, sse sseParams) error {
+	output, err := s.getObjectRange(ctx, key, etag, r.header(), sse)
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+
+	n, err := io.Copy(w, output.Body)
+	if err != nil {
+		return err
+	}
+
+	if n != r.length() {
+		return fmt.Errorf("range %s returned %d bytes", r.header(), n)
+	}
+
+	return nil
+}
+
+func (s *S3Service) getObjectRange(ctx context.Context, key string, etag string, rangeHeader string, sse sseParams) (*s3.GetObjectOutput, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+		Range:  aws.String(rangeHeader),
+	}
+
+	if etag != "" {
+		input.IfMatch = aws.String(etag)
+	}
+
+	sse.applyGet(input)
+
+	return s.api().GetObjectWithContext(ctx, input)
+}
+
+func isPreconditionFailed(err error) bool {
+	var failure awserr.RequestFailure
+	return errors.As(err, &failure) && failure.StatusCode() == http.StatusPreconditionFailed
+}
+
+// Open returns a reader over the object stored under key that fetches data with
+// HTTP range requests as it is read. Seeking only moves the read position; the
+// next Read starts a new range request there. This makes it cheap to read a small
+// region of a large object, such as the ZIP central directory at the end of an APK.
+// The reader also implements io.ReaderAt, so it can be passed to zip.NewReader;
+// every ReadAt is a single range request of exactly the bytes asked for.
+// Objects encrypted with a customer-provided key are read with the service's.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for every request made by the reader.
+//	key (string): The object key.
+//
+// Returns:
+//
+//	(io.ReadSeekCloser, error): The reader, or an error if the object does not exist.
+func (s *S3Service) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	sse := s.encryption.params()
+
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	}
+
+	sse.applyHead(input)
+
+	head, err := s.api().HeadObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &objectReader{
+		ctx:     ctx,
+		service: s,
+		key:     key,
+		etag:    aws.StringValue(head.ETag),
+		size:    aws.Int64Value(head.ContentLength),
+		sse:     sse,
+	}, nil
+}
+
+type objectReader struct {
+	ctx     context.Context
+	service *S3Service
+	key     string
+	etag    string
+	size    int64
+	sse     sseParams
+	offset  int64
+	body    io.ReadCloser
+	closed  bool
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, errors.New("read on closed object reader")
+	}
+
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if r.body == nil {
+		output, err := r.service.getObjectRange(r.ctx, r.key, r.etag, fmt.Sprintf("bytes=%d-", r.offset), r.sse)
+		if err != nil {
+			return 0, err
+		}
+		r.body = output.Body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+
+	if errors.Is(err, io.EOF) {
+		r.body.Close()
+		r.body = nil
+
+		if n == 0 && r.offset < r.size {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		err = nil
+	}
+
+	return n, err
+}
+
+// ReadAt reads len(p) bytes starting at off with one range request. It does not
+// use or move the read position, so it is safe to call concurrently.
+func (r *objectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	end := min(off+int64(len(p)), r.size)
+
+	output, err := r.service.getObjectRange(r.ctx, r.key, r.etag, byteRange{start: off, end: end - 1}.header(), r.sse)
+	if err != nil {
+		return 0, err
+	}
+	defer output.Body.Close()
+
+	n, err := io.ReadFull(output.Body, p[:end-off])
+	if err != nil {
+		return n, err
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	if r.closed {
+		return 0, errors.New("seek on closed object reader")
+	}
+
+	var position int64
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = r.offset + offset
+	case io.SeekEnd:
+		position = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if position < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if position != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+
+	r.offset = position
+
+	return position, nil
+}
+
+func (r *objectReader) Close() error {
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+
+	if r.body != nil {
+		err := r.body.Close()
+		r.body = nil
+		return err
+	}
+
+	return nil
+}
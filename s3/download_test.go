@@ -0,0 +1,245 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// serveRanges answers GetObject range requests from data, failing the requests
+// for which fail returns true.
+func serveRanges(data []byte, fail func(start int64) bool) func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	return func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+		var start, end int64
+		rangeHeader := aws.StringValue(input.Range)
+		if strings.HasSuffix(rangeHeader, "-") {
+			fmt.Sscanf(rangeHeader, "bytes=%d-", &start)
+			end = int64(len(data)) - 1
+		} else {
+			fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end)
+		}
+
+		if fail != nil && fail(start) {
+			return nil, errors.New("connection reset")
+		}
+
+		return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data[start : end+1]))}, nil
+	}
+}
+
+func mockHeadObject(mockClient *mockS3Client, key string, size int) {
+	mockClient.On("HeadObjectWithContext", mock.Anything, &s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String(key),
+	}).Return(&s3.HeadObjectOutput{ContentLength: aws.Int64(int64(size)), ETag: aws.String("\"v1\"")}, nil)
+}
+
+func mockResumedHeadObject(mockClient *mockS3Client, key string, size int, etag string) {
+	mockClient.On("HeadObjectWithContext", mock.Anything, &s3.HeadObjectInput{
+		Bucket:  aws.String("test-bucket"),
+		Key:     aws.String(key),
+		IfMatch: aws.String(etag),
+	}).Return(&s3.HeadObjectOutput{ContentLength: aws.Int64(int64(size)), ETag: aws.String(etag)}, nil)
+}
+
+func readFile(t *testing.T, file *os.File) []byte {
+	data, err := os.ReadFile(file.Name())
+	assert.NoError(t, err)
+	return data
+}
+
+func TestDownload(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), MinPartSize/4)
+
+	t.Run("Fetches parts in parallel", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockHeadObject(mockClient, "app.apk", len(data))
+		mockClient.On("GetObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+			return aws.StringValue(input.IfMatch) == "\"v1\""
+		})).Return(serveRanges(data, nil), nil)
+
+		file, _ := os.Create(filepath.Join(t.TempDir(), "app.apk"))
+		defer file.Close()
+
+		var progress atomic.Int64
+		output, err := service.Download(context.Background(), "app.apk", file, DownloadOptions{
+			PartSize: MinPartSize,
+			Progress: func(written int64) { progress.Store(max(progress.Load(), written)) },
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(data)), output.Size)
+		assert.Equal(t, int64(len(data)), output.Written)
+		assert.Equal(t, int64(len(data)), progress.Load())
+		assert.Equal(t, data, readFile(t, file))
+		mockClient.AssertNumberOfCalls(t, "GetObjectWithContext", 3)
+	})
+
+	t.Run("Reports where to resume and resumes", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockHeadObject(mockClient, "app.apk", len(data))
+		mockClient.On("GetObjectWithContext", mock.Anything, mock.Anything).Return(serveRanges(data, func(start int64) bool {
+			return start == MinPartSize
+		}), nil)
+
+		file, _ := os.Create(filepath.Join(t.TempDir(), "app.apk"))
+		defer file.Close()
+
+		_, err := service.Download(context.Background(), "app.apk", file, DownloadOptions{
+			PartSize:    MinPartSize,
+			Concurrency: 1,
+			MaxRetries:  1,
+		})
+
+		var downloadErr *DownloadError
+		assert.True(t, errors.As(err, &downloadErr))
+		assert.Equal(t, int64(MinPartSize), downloadErr.Offset)
+		assert.Equal(t, "\"v1\"", downloadErr.ETag)
+
+		mockClient.ExpectedCalls = nil
+		mockResumedHeadObject(mockClient, "app.apk", len(data), "\"v1\"")
+		mockClient.On("GetObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+			return aws.StringValue(input.IfMatch) == "\"v1\""
+		})).Return(serveRanges(data, nil), nil)
+
+		output, err := service.Download(context.Background(), "app.apk", file, DownloadOptions{
+			PartSize: MinPartSize,
+			Offset:   downloadErr.Offset,
+			ETag:     downloadErr.ETag,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(data)-MinPartSize), output.Written)
+		assert.Equal(t, data, readFile(t, file))
+	})
+
+	t.Run("Resume requires the ETag", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.Download(context.Background(), "app.apk", nil, DownloadOptions{Offset: MinPartSize})
+
+		assert.EqualError(t, err, "resuming a download requires the ETag of the interrupted download")
+		mockClient.AssertNotCalled(t, "HeadObjectWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Object changed before resume", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("HeadObjectWithContext", mock.Anything, &s3.HeadObjectInput{
+			Bucket:  aws.String("test-bucket"),
+			Key:     aws.String("app.apk"),
+			IfMatch: aws.String("\"v1\""),
+		}).Return((*s3.HeadObjectOutput)(nil),
+			awserr.NewRequestFailure(awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil), 412, "request-id"))
+
+		_, err := service.Download(context.Background(), "app.apk", nil, DownloadOptions{Offset: MinPartSize, ETag: "\"v1\""})
+
+		assert.ErrorIs(t, err, ErrObjectChanged)
+		mockClient.AssertNotCalled(t, "GetObjectWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Object changed during download", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockHeadObject(mockClient, "app.apk", len(data))
+		mockClient.On("GetObjectWithContext", mock.Anything, mock.Anything).Return((*s3.GetObjectOutput)(nil),
+			awserr.NewRequestFailure(awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil), 412, "request-id"))
+
+		file, _ := os.Create(filepath.Join(t.TempDir(), "app.apk"))
+		defer file.Close()
+
+		_, err := service.Download(context.Background(), "app.apk", file, DownloadOptions{PartSize: MinPartSize, Concurrency: 1})
+
+		assert.Error(t, err)
+		assert.True(t, isPreconditionFailed(err))
+		mockClient.AssertNumberOfCalls(t, "GetObjectWithContext", 1)
+	})
+
+	t.Run("Offset beyond object", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockResumedHeadObject(mockClient, "app.apk", 10, "\"v1\"")
+
+		_, err := service.Download(context.Background(), "app.apk", nil, DownloadOptions{Offset: 11, ETag: "\"v1\""})
+
+		assert.EqualError(t, err, "offset 11 is beyond the object size 10")
+	})
+}
+
+func TestOpen(t *testing.T) {
+	data := []byte("PK\x03\x04 local header ... central directory PK\x05\x06 end")
+
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	mockHeadObject(mockClient, "app.apk", len(data))
+	mockClient.On("GetObjectWithContext", mock.Anything, mock.Anything).Return(serveRanges(data, nil), nil)
+
+	reader, err := service.Open(context.Background(), "app.apk")
+	assert.NoError(t, err)
+
+	position, err := reader.Seek(-8, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)-8), position)
+
+	tail, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "PK\x05\x06 end", string(tail))
+
+	_, err = reader.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+
+	head := make([]byte, 4)
+	_, err = io.ReadFull(reader, head)
+	assert.NoError(t, err)
+	assert.Equal(t, "PK\x03\x04", string(head))
+
+	mockClient.AssertCalled(t, "GetObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+		return aws.StringValue(input.Range) == fmt.Sprintf("bytes=%d-", len(data)-8)
+	}))
+
+	_, err = reader.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+
+	assert.NoError(t, reader.Close())
+	_, err = reader.Read(head)
+	assert.Error(t, err)
+}
+
+func TestOpenReadAt(t *testing.T) {
+	data := []byte("0123456789")
+
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	mockHeadObject(mockClient, "app.apk", len(data))
+	mockClient.On("GetObjectWithContext", mock.Anything, mock.Anything).Return(serveRanges(data, nil), nil)
+
+	reader, err := service.Open(context.Background(), "app.apk")
+	assert.NoError(t, err)
+
+	readerAt, ok := reader.(io.ReaderAt)
+	assert.True(t, ok)
+
+	buf := make([]byte, 4)
+	n, err := readerAt.ReadAt(buf, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "3456", string(buf[:n]))
+
+	n, err = readerAt.ReadAt(buf, 8)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "89", string(buf[:n]))
+
+	_, err = readerAt.ReadAt(buf, 10)
+	assert.Equal(t, io.EOF, err)
+
+	mockClient.AssertCalled(t, "GetObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+		return aws.StringValue(input.Range) == "bytes=3-6"
+	}))
+}
@@ -0,0 +1,229 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// SSES3 encrypts objects with keys managed by S3.
+func SSES3() Encryption {
+	return Encryption{Algorithm: s3.ServerSideEncryptionAes256}
+}
+
+// SSEKMS encrypts objects with the KMS key keyID, or the AWS managed key when it
+// is empty, binding context as the KMS encryption context.
+func SSEKMS(keyID string, context map[string]string) Encryption {
+	return Encryption{Algorithm: s3.ServerSideEncryptionAwsKms, KMSKeyID: keyID, KMSContext: context}
+}
+
+// SSEC encrypts objects with a customer-provided 256-bit key.
+func SSEC(key []byte) Encryption {
+	return Encryption{CustomerKey: key}
+}
+
+// Validate reports settings S3 would reject.
+func (e Encryption) Validate() error {
+	if len(e.CustomerKey) > 0 {
+		if e.Algorithm != "" || e.KMSKeyID != "" || len(e.KMSContext) > 0 || e.BucketKeyEnabled {
+			return errors.New("a customer-provided key cannot be combined with server-side encryption settings")
+		}
+
+		if len(e.CustomerKey) != SSECustomerKeySize {
+			return fmt.Errorf("a customer-provided key must be %d bytes", SSECustomerKeySize)
+		}
+
+		return nil
+	}
+
+	switch e.Algorithm {
+	case "", s3.ServerSideEncryptionAes256:
+		if e.KMSKeyID != "" {
+			return errors.New("a KMS key ID requires aws:kms server-side encryption")
+		}
+
+		if len(e.KMSContext) > 0 {
+			return errors.New("an encryption context requires aws:kms server-side encryption")
+		}
+
+		if e.BucketKeyEnabled {
+			return errors.New("bucket keys require aws:kms server-side encryption")
+		}
+	case s3.ServerSideEncryptionAwsKms:
+	default:
+		return errors.New("server-side encryption must be one of AES256 or aws:kms")
+	}
+
+	return nil
+}
+
+// WithEncryption sets the encryption used by every call that does not override
+// it. Objects written with a customer-provided key can only be read, copied and
+// completed by a service that has the same key. It is not safe to call while
+// the service is in use, and returns the service to allow chaining. It panics
+// if encryption is invalid, so settings read from configuration should be
+// checked with Validate first.
+func (s *S3Service) WithEncryption(encryption Encryption) *S3Service {
+	if err := encryption.Validate(); err != nil {
+		panic(fmt.Sprintf("s3: invalid encryption: %v", err))
+	}
+
+	s.encryption = encryption
+
+	return s
+}
+
+// resolveEncryption returns override, or the service's encryption when it is nil.
+func (s *S3Service) resolveEncryption(override *Encryption) (Encryption, error) {
+	if override == nil {
+		return s.encryption, nil
+	}
+
+	return *override, override.Validate()
+}
+
+// sseParams holds the request parameters an Encryption translates to, so they
+// can be copied onto the different input types.
+type sseParams struct {
+	serverSideEncryption *string
+	kmsKeyID             *string
+	kmsContext           *string
+	bucketKeyEnabled     *bool
+	customerAlgorithm    *string
+	customerKey          *string
+	customerKeyMD5       *string
+}
+
+// params translates e. The SDK base64-encodes the customer key itself.
+func (e Encryption) params() sseParams {
+	var p sseParams
+
+	if len(e.CustomerKey) > 0 {
+		digest := md5.Sum(e.CustomerKey)
+		p.customerAlgorithm = aws.String(SSECustomerAlgorithm)
+		p.customerKey = aws.String(string(e.CustomerKey))
+		p.customerKeyMD5 = aws.String(base64.StdEncoding.EncodeToString(digest[:]))
+		return p
+	}
+
+	if e.Algorithm != "" {
+		p.serverSideEncryption = aws.String(e.Algorithm)
+	}
+
+	if e.KMSKeyID != "" {
+		p.kmsKeyID = aws.String(e.KMSKeyID)
+	}
+
+	if len(e.KMSContext) > 0 {
+		// json.Marshal sorts the keys, so the same context always encodes the same way.
+		context, _ := json.Marshal(e.KMSContext)
+		p.kmsContext = aws.String(base64.StdEncoding.EncodeToString(context))
+	}
+
+	if e.BucketKeyEnabled {
+		p.bucketKeyEnabled = aws.Bool(true)
+	}
+
+	return p
+}
+
+// formFields returns the POST form fields that request e.
+func (e Encryption) formFields() map[string]string {
+	p := e.params()
+	fields := make(map[string]string)
+
+	set := func(name string, value *string) {
+		if value != nil {
+			fields[name] = *value
+		}
+	}
+
+	set("x-amz-server-side-encryption", p.serverSideEncryption)
+	set("x-amz-server-side-encryption-aws-kms-key-id", p.kmsKeyID)
+	set("x-amz-server-side-encryption-context", p.kmsContext)
+	set("x-amz-server-side-encryption-customer-algorithm", p.customerAlgorithm)
+	set("x-amz-server-side-encryption-customer-key-md5", p.customerKeyMD5)
+
+	if p.customerKey != nil {
+		fields["x-amz-server-side-encryption-customer-key"] = base64.StdEncoding.EncodeToString(e.CustomerKey)
+	}
+
+	if p.bucketKeyEnabled != nil {
+		fields["x-amz-server-side-encryption-bucket-key-enabled"] = "true"
+	}
+
+	return fields
+}
+
+func (p sseParams) applyPut(input *s3.PutObjectInput) {
+	input.ServerSideEncryption = p.serverSideEncryption
+	input.SSEKMSKeyId = p.kmsKeyID
+	input.SSEKMSEncryptionContext = p.kmsContext
+	input.BucketKeyEnabled = p.bucketKeyEnabled
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p sseParams) applyCreateMultipart(input *s3.CreateMultipartUploadInput) {
+	input.ServerSideEncryption = p.serverSideEncryption
+	input.SSEKMSKeyId = p.kmsKeyID
+	input.SSEKMSEncryptionContext = p.kmsContext
+	input.BucketKeyEnabled = p.bucketKeyEnabled
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+// applyCopy encrypts the copy with p and reads the source with the customer
+// key in p, if any.
+func (p sseParams) applyCopy(input *s3.CopyObjectInput) {
+	input.ServerSideEncryption = p.serverSideEncryption
+	input.SSEKMSKeyId = p.kmsKeyID
+	input.SSEKMSEncryptionContext = p.kmsContext
+	input.BucketKeyEnabled = p.bucketKeyEnabled
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+	input.CopySourceSSECustomerAlgorithm = p.customerAlgorithm
+	input.CopySourceSSECustomerKey = p.customerKey
+	input.CopySourceSSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p sseParams) applyUploadPart(input *s3.UploadPartInput) {
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p sseParams) applyUploadPartCopy(input *s3.UploadPartCopyInput) {
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+	input.CopySourceSSECustomerAlgorithm = p.customerAlgorithm
+	input.CopySourceSSECustomerKey = p.customerKey
+	input.CopySourceSSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p sseParams) applyComplete(input *s3.CompleteMultipartUploadInput) {
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p sseParams) applyGet(input *s3.GetObjectInput) {
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+}
+
+func (p sseParams) applyHead(input *s3.HeadObjectInput) {
+	input.SSECustomerAlgorithm = p.customerAlgorithm
+	input.SSECustomerKey = p.customerKey
+	input.SSECustomerKeyMD5 = p.customerKeyMD5
+}
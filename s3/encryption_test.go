@@ -0,0 +1,263 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testCustomerKey = bytes.Repeat([]byte{0x42}, SSECustomerKeySize)
+
+func testCustomerKeyMD5() string {
+	digest := md5.Sum(testCustomerKey)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func hasCustomerKey(algorithm *string, key *string, keyMD5 *string) bool {
+	return aws.StringValue(algorithm) == SSECustomerAlgorithm &&
+		aws.StringValue(key) == string(testCustomerKey) &&
+		aws.StringValue(keyMD5) == testCustomerKeyMD5()
+}
+
+func TestEncryptionValidate(t *testing.T) {
+	valid := []Encryption{
+		{},
+		SSES3(),
+		SSEKMS("", nil),
+		{Algorithm: "aws:kms", KMSKeyID: "alias/uploads", KMSContext: map[string]string{"partner": "1"}, BucketKeyEnabled: true},
+		SSEC(testCustomerKey),
+	}
+	for _, encryption := range valid {
+		assert.NoError(t, encryption.Validate())
+	}
+
+	tests := []struct {
+		name       string
+		encryption Encryption
+		err        string
+	}{
+		{"Unknown algorithm", Encryption{Algorithm: "DES"}, "server-side encryption must be one of AES256 or aws:kms"},
+		{"Key ID without KMS", Encryption{Algorithm: "AES256", KMSKeyID: "alias/uploads"}, "a KMS key ID requires aws:kms server-side encryption"},
+		{"Context without KMS", Encryption{KMSContext: map[string]string{"a": "b"}}, "an encryption context requires aws:kms server-side encryption"},
+		{"Bucket key without KMS", Encryption{Algorithm: "AES256", BucketKeyEnabled: true}, "bucket keys require aws:kms server-side encryption"},
+		{"Short customer key", SSEC([]byte("short")), "a customer-provided key must be 32 bytes"},
+		{"Customer key with KMS", Encryption{Algorithm: "aws:kms", CustomerKey: testCustomerKey}, "a customer-provided key cannot be combined with server-side encryption settings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.encryption.Validate(), tt.err)
+			assert.PanicsWithValue(t, "s3: invalid encryption: "+tt.err, func() { (&S3Service{}).WithEncryption(tt.encryption) })
+		})
+	}
+}
+
+func TestUploadEncryption(t *testing.T) {
+	t.Run("SSE-KMS with context and bucket key", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		encryption := SSEKMS("alias/uploads", map[string]string{"partner": "1", "app": "2"})
+		encryption.BucketKeyEnabled = true
+
+		mockClient.On("PutObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.PutObjectInput) bool {
+			context, _ := base64.StdEncoding.DecodeString(aws.StringValue(input.SSEKMSEncryptionContext))
+			return aws.StringValue(input.ServerSideEncryption) == "aws:kms" &&
+				aws.StringValue(input.SSEKMSKeyId) == "alias/uploads" &&
+				string(context) == `{"app":"2","partner":"1"}` &&
+				aws.BoolValue(input.BucketKeyEnabled) &&
+				input.SSECustomerKey == nil
+		})).Return(&s3.PutObjectOutput{ETag: aws.String("\"etag\"")}, nil)
+
+		_, err := service.Upload(context.Background(), "app.apk", strings.NewReader("hello"), UploadOptions{Encryption: &encryption})
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Service SSE-C on every multipart request", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		service.WithEncryption(SSEC(testCustomerKey))
+		body := bytes.Repeat([]byte("a"), MinPartSize+10)
+
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateMultipartUploadInput) bool {
+			return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5) && input.ServerSideEncryption == nil
+		})).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+		mockClient.On("UploadPartWithContext", mock.Anything, mock.MatchedBy(func(input *s3.UploadPartInput) bool {
+			return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+		})).Return(&s3.UploadPartOutput{ETag: aws.String("\"part\"")}, nil).Twice()
+		mockClient.On("CompleteMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CompleteMultipartUploadInput) bool {
+			return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+		})).Return(&s3.CompleteMultipartUploadOutput{ETag: aws.String("\"final-2\"")}, nil)
+
+		_, err := service.Upload(context.Background(), "app.apk", bytes.NewReader(body), UploadOptions{PartSize: MinPartSize})
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Invalid override", func(t *testing.T) {
+		service, _ := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.Upload(context.Background(), "app.apk", strings.NewReader("hello"), UploadOptions{Encryption: &Encryption{Algorithm: "DES"}})
+
+		assert.EqualError(t, err, "server-side encryption must be one of AES256 or aws:kms")
+	})
+}
+
+func TestDownloadEncryption(t *testing.T) {
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	data := []byte("encrypted with a customer key")
+	encryption := SSEC(testCustomerKey)
+
+	mockClient.On("HeadObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadObjectInput) bool {
+		return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	})).Return(&s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(data))), ETag: aws.String("\"etag\"")}, nil)
+	mockClient.On("GetObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+		return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	})).Return(&s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil)
+
+	file, _ := os.Create(filepath.Join(t.TempDir(), "secret.apk"))
+	defer file.Close()
+
+	_, err := service.Download(context.Background(), "secret.apk", file, DownloadOptions{Encryption: &encryption})
+
+	written, _ := os.ReadFile(file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, data, written)
+	mockClient.AssertExpectations(t)
+}
+
+func TestCopyEncryption(t *testing.T) {
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	service.WithEncryption(SSEC(testCustomerKey))
+
+	mockClient.On("HeadObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadObjectInput) bool {
+		return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	})).Return(&s3.HeadObjectOutput{ContentLength: aws.Int64(10), ETag: aws.String("\"etag\"")}, nil)
+	mockClient.On("CopyObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CopyObjectInput) bool {
+		return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5) &&
+			hasCustomerKey(input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey, input.CopySourceSSECustomerKeyMD5)
+	})).Return(&s3.CopyObjectOutput{}, nil)
+
+	err := service.Copy(context.Background(), "a.apk", "b.apk")
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestPresignEncryption(t *testing.T) {
+	oldNow := now
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	defer func() { now = oldNow }()
+
+	metadata := Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3}
+	encodedKey := base64.StdEncoding.EncodeToString(testCustomerKey)
+
+	t.Run("SSE-C GET sends the key as headers", func(t *testing.T) {
+		service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+
+		response, err := service.PresignGetObject("uploads/1-2-3.apk", WithEncryption(SSEC(testCustomerKey)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "AES256", response.Headers["X-Amz-Server-Side-Encryption-Customer-Algorithm"])
+		assert.Equal(t, encodedKey, response.Headers["X-Amz-Server-Side-Encryption-Customer-Key"])
+		assert.Equal(t, testCustomerKeyMD5(), response.Headers["X-Amz-Server-Side-Encryption-Customer-Key-Md5"])
+		assert.NotContains(t, response.PresignedUrl, "customer-key=")
+		assert.NotContains(t, response.PresignedUrl, url.QueryEscape(encodedKey))
+		assert.Contains(t, mustParseURL(response.PresignedUrl).Query().Get("X-Amz-SignedHeaders"), "x-amz-server-side-encryption-customer-key")
+	})
+
+	t.Run("Service encryption applies to PUT", func(t *testing.T) {
+		service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+		service.WithEncryption(SSEKMS("alias/uploads", map[string]string{"partner": "1"}))
+
+		response, err := service.GenerateSignedRequest(metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "aws:kms", response.Headers["X-Amz-Server-Side-Encryption"])
+		assert.Equal(t, "alias/uploads", response.Headers["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"])
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(`{"partner":"1"}`)), response.Headers["X-Amz-Server-Side-Encryption-Context"])
+	})
+
+	t.Run("SSE-C part URLs carry headers", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateMultipartUploadInput) bool {
+			return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+		})).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+
+		upload, err := service.CreatePresignedMultipartUpload(context.Background(), metadata, MinPartSize+1, MinPartSize,
+			WithEncryption(SSEC(testCustomerKey)))
+
+		assert.NoError(t, err)
+		assert.Len(t, upload.Parts, 2)
+		for _, part := range upload.Parts {
+			assert.Equal(t, encodedKey, part.Headers["X-Amz-Server-Side-Encryption-Customer-Key"])
+		}
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("SSE-C override from create through complete", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		opts := []PresignOption{WithEncryption(SSEC(testCustomerKey)), WithExpiry(time.Hour)}
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateMultipartUploadInput) bool {
+			return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+		})).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+		mockClient.On("CompleteMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CompleteMultipartUploadInput) bool {
+			return hasCustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+		})).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+		upload, err := service.CreatePresignedMultipartUpload(context.Background(), metadata, MinPartSize, MinPartSize, opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, encodedKey, upload.Parts[0].Headers["X-Amz-Server-Side-Encryption-Customer-Key"])
+
+		_, err = service.CompleteMultipartUpload(context.Background(), upload.Key, upload.UploadID,
+			[]CompletedPart{{PartNumber: 1, ETag: "\"a\""}}, opts...)
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("POST form fields", func(t *testing.T) {
+		service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+		encryption := SSEKMS("alias/uploads", nil)
+		encryption.BucketKeyEnabled = true
+
+		response, err := service.GeneratePresignedPost(metadata, PostPolicy{}, WithEncryption(encryption))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "aws:kms", response.Fields["x-amz-server-side-encryption"])
+		assert.Equal(t, "alias/uploads", response.Fields["x-amz-server-side-encryption-aws-kms-key-id"])
+		assert.Equal(t, "true", response.Fields["x-amz-server-side-encryption-bucket-key-enabled"])
+
+		raw, _ := base64.StdEncoding.DecodeString(response.Fields["policy"])
+		var document struct {
+			Conditions []interface{} `json:"conditions"`
+		}
+		assert.NoError(t, json.Unmarshal(raw, &document))
+		assert.Contains(t, document.Conditions, map[string]interface{}{"x-amz-server-side-encryption": "aws:kms"})
+	})
+
+	t.Run("SSE-C POST form fields", func(t *testing.T) {
+		service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+
+		response, err := service.GeneratePresignedPost(metadata, PostPolicy{}, WithEncryption(SSEC(testCustomerKey)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "AES256", response.Fields["x-amz-server-side-encryption-customer-algorithm"])
+		assert.Equal(t, encodedKey, response.Fields["x-amz-server-side-encryption-customer-key"])
+		assert.Equal(t, testCustomerKeyMD5(), response.Fields["x-amz-server-side-encryption-customer-key-md5"])
+	})
+}
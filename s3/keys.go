@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	now                   = time.Now
+	defaultKeyTemplate, _ = parseKeyTemplate(DefaultKeyTemplate)
+)
+
+// keyTemplateData is what object key templates are executed against. Besides the
+// Metadata fields it exposes the upload date (UTC), a random UUID and the
+// content hash supplied by the caller, e.g.
+//
+//	{{.Date.Format "2006/01/02"}}/{{.PartnerID}}/{{.UUID}}.apk
+type keyTemplateData struct {
+	Metadata
+	Date        time.Time
+	UUID        string
+	ContentHash string
+}
+
+func parseKeyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultKeyTemplate
+	}
+
+	tmpl, err := template.New("key").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key template: %v", err)
+	}
+
+	// Catch references to unknown fields now rather than on the first upload.
+	if err := tmpl.Execute(&bytes.Buffer{}, keyTemplateData{}); err != nil {
+		return nil, fmt.Errorf("invalid key template: %v", err)
+	}
+
+	return tmpl, nil
+}
+
+func renderKey(prefix string, tmpl *template.Template, metadata Metadata) (string, error) {
+	id, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, keyTemplateData{
+		Metadata:    metadata,
+		Date:        now().UTC(),
+		UUID:        id,
+		ContentHash: metadata.ContentSHA256,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render object key: %v", err)
+	}
+
+	key := strings.TrimSpace(buf.String())
+	if key == "" {
+		return "", errors.New("object key template rendered an empty key")
+	}
+
+	return prefix + key, nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyTemplate(t *testing.T) {
+	t.Run("Default template", func(t *testing.T) {
+		tmpl, err := parseKeyTemplate("")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, tmpl)
+	})
+
+	t.Run("Syntax error", func(t *testing.T) {
+		_, err := parseKeyTemplate("{{.AppID")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid key template")
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		_, err := parseKeyTemplate("{{.Missing}}.apk")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid key template")
+	})
+}
+
+func TestRenderKey(t *testing.T) {
+	oldNow := now
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	defer func() { now = oldNow }()
+
+	metadata := Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3, ContentSHA256: "abc123"}
+
+	t.Run("Default template", func(t *testing.T) {
+		key, err := renderKey(DefaultKeyPrefix, defaultKeyTemplate, metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "uploads/1-2-3.apk", key)
+	})
+
+	t.Run("Date, UUID and content hash", func(t *testing.T) {
+		tmpl, _ := parseKeyTemplate(`{{.Date.Format "2006/01/02"}}/{{.PartnerID}}/{{.ContentHash}}-{{.UUID}}.obb`)
+
+		key, err := renderKey("artifacts/", tmpl, metadata)
+
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^artifacts/2024/05/06/1/abc123-[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\.obb$`), key)
+	})
+
+	t.Run("Empty key", func(t *testing.T) {
+		tmpl, _ := parseKeyTemplate(`{{.ContentHash}}`)
+
+		_, err := renderKey("uploads/", tmpl, Metadata{})
+
+		assert.EqualError(t, err, "object key template rendered an empty key")
+	})
+}
@@ -0,0 +1,40 @@
+package s3
+
+import (
+	"log/slog"
+
+	"go_aws_services/session"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ownHandle gives the service an S3 handle of its own the first time it is
+// configured, so that its settings do not leak to other services sharing the
+// package-wide handle.
+func (s *S3Service) ownHandle() *s3.S3 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.handle == nil {
+		s.handle = newS3(getAwsSession())
+		s.client = s.handle
+	}
+
+	return s.handle
+}
+
+// WithLogger logs every call of this service with session.LogHandler, replacing
+// the logger of the session. A nil logger turns logging off for this service.
+// It is not safe to call while the service is in use, and returns the service
+// to allow chaining.
+func (s *S3Service) WithLogger(logger *slog.Logger, opts session.LogOptions) *S3Service {
+	handlers := &s.ownHandle().Handlers
+
+	handlers.Complete.RemoveByName(session.LogHandlerName)
+
+	if logger != nil {
+		handlers.Complete.PushBackNamed(session.LogHandler(logger, opts))
+	}
+
+	return s
+}
@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_aws_services/session"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Request-Id", "REQ123")
+		w.Header().Set("Content-Length", "42")
+	}))
+	defer server.Close()
+
+	oldAwsSession, oldNewS3 := getAwsSession, newS3
+	defer func() { getAwsSession, newS3 = oldAwsSession, oldNewS3 }()
+
+	sess := newTestSession()
+	sess.Config.Endpoint = aws.String(server.URL)
+	sess.Config.S3ForcePathStyle = aws.Bool(true)
+	getAwsSession = func() *awssession.Session { return sess }
+	newS3 = s3.New
+
+	service, err := mockNewS3Service("uploads", "", "")
+	require.NoError(t, err)
+	shared := service.api()
+
+	var buf bytes.Buffer
+	service.WithLogger(slog.New(slog.NewJSONHandler(&buf, nil)), session.LogOptions{LogKeys: true})
+
+	_, err = service.Head(context.Background(), "1-2-3.apk")
+	require.NoError(t, err)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "HeadObject", record["operation"])
+	assert.Equal(t, "uploads", record["bucket"])
+	assert.Equal(t, "1-2-3.apk", record["key"])
+	assert.Equal(t, "REQ123", record["requestId"])
+	assert.NotSame(t, shared, service.api())
+	assert.Same(t, shared, s3Client)
+}
@@ -1,49 +1,169 @@
 package s3
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
 
+var _ S3ServiceInterface = (*S3Service)(nil)
+
 // S3ServiceInterface define a interface para as funções S3.
 type S3ServiceInterface interface {
 	PreSign(req *request.Request, expire time.Duration) (string, error)
-	PutObjectRequest(metadata Metadata) (PresignedUrlResponse, error)
-	GenerateSignedRequest(metadata Metadata) (PresignedUrlResponse, error)
+	PutObjectRequest(metadata Metadata, opts ...PresignOption) (*request.Request, *s3.PutObjectOutput, error)
+	GenerateSignedRequest(metadata Metadata, opts ...PresignOption) (PresignedUrlResponse, error)
+	PresignGetObject(key string, opts ...PresignOption) (PresignedUrlResponse, error)
+	PresignHeadObject(key string, opts ...PresignOption) (PresignedUrlResponse, error)
+	PresignDeleteObject(key string, opts ...PresignOption) (PresignedUrlResponse, error)
+	GeneratePresignedPost(metadata Metadata, policy PostPolicy, opts ...PresignOption) (PresignedPostResponse, error)
+	CreatePresignedMultipartUpload(ctx context.Context, metadata Metadata, size int64, partSize int64, opts ...PresignOption) (PresignedMultipartUpload, error)
+	PresignUploadParts(key string, uploadID string, partNumbers []int64, opts ...PresignOption) ([]PresignedPart, error)
+	CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart, opts ...PresignOption) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, key string, uploadID string) error
+}
+
+// NewS3Service creates a service that stores objects in bucket under keyPrefix.
+// Object keys are rendered from keyTemplate, a text/template executed against the
+// upload Metadata plus .Date, .UUID and .ContentHash. An empty keyTemplate uses
+// DefaultKeyTemplate.
+//
+// Parameters:
+//
+//	bucket (string): The bucket objects are stored in.
+//	keyPrefix (string): The prefix prepended to every rendered key, e.g. "uploads/".
+//	keyTemplate (string): The template used to render the rest of the key.
+//
+// Returns:
+//
+//	(*S3Service, error): The configured service, or an error if the bucket is empty or the template is invalid.
+func NewS3Service(bucket string, keyPrefix string, keyTemplate string) (*S3Service, error) {
+	if bucket == "" {
+		return nil, errors.New("bucket cannot be empty")
+	}
+
+	tmpl, err := parseKeyTemplate(keyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Service{
+		bucket:      bucket,
+		keyPrefix:   keyPrefix,
+		keyTemplate: tmpl,
+		client:      initAwsS3(),
+	}, nil
+}
+
+// api returns the client requests are sent with, creating it on first use so
+// that the zero value is safe to share between goroutines.
+func (s *S3Service) api() s3iface.S3API {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client == nil {
+		s.client = initAwsS3()
+	}
+
+	return s.client
+}
+
+func (s *S3Service) bucketName() string {
+	if s.bucket == "" {
+		return DefaultBucket
+	}
+
+	return s.bucket
+}
+
+// effectivePrefix returns the prefix object keys are rendered under, which is
+// DefaultKeyPrefix for the zero value.
+func (s *S3Service) effectivePrefix() string {
+	if s.keyTemplate == nil {
+		return DefaultKeyPrefix
+	}
+
+	return s.keyPrefix
 }
 
-// S3Service é uma implementação da interface S3ServiceInterface.
-type S3Service struct{}
+// ObjectKey renders the object key an upload described by metadata is stored under.
+func (s *S3Service) ObjectKey(metadata Metadata) (string, error) {
+	tmpl := s.keyTemplate
+	if tmpl == nil {
+		tmpl = defaultKeyTemplate
+	}
+
+	return renderKey(s.effectivePrefix(), tmpl, metadata)
+}
 
+// PreSign presigns an arbitrary request for expire, hoisting any headers into the
+// query string. Prefer the Presign* and GenerateSignedRequest methods, which also
+// report the signed headers and the expiry time.
 func (s *S3Service) PreSign(req *request.Request, expire time.Duration) (string, error) {
 	return req.Presign(expire)
 
 }
 
-func (s *S3Service) PutObjectRequest(metadata Metadata) (*request.Request, *s3.PutObjectOutput) {
-	s3Client := initAwsS3()
+// PutObjectRequest builds, without sending or signing it, the PutObject request
+// for the object described by metadata with the constraints in opts applied.
+func (s *S3Service) PutObjectRequest(metadata Metadata, opts ...PresignOption) (*request.Request, *s3.PutObjectOutput, error) {
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.putObjectRequest(metadata, o)
+}
+
+func (s *S3Service) putObjectRequest(metadata Metadata, o *presignOptions) (*request.Request, *s3.PutObjectOutput, error) {
+	key, err := s.ObjectKey(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	}
+
+	if err := applyPutConstraints(input, metadata, o); err != nil {
+		return nil, nil, err
+	}
 
-	return s3Client.PutObjectRequest(&s3.PutObjectInput{
-		Bucket: aws.String("brunojet-storage"),
-		Key:    aws.String(fmt.Sprintf("uploads/%d-%d-%d.apk", metadata.PartnerID, metadata.AppID, metadata.DeviceModelID)),
-	})
+	s.sseParams(o).applyPut(input)
 
+	req, output := s.api().PutObjectRequest(input)
+
+	return req, output, nil
 }
 
-func (s *S3Service) GenerateSignedRequest(metadata Metadata) (PresignedUrlResponse, error) {
-	req, _ := s.PutObjectRequest(metadata)
-	urlStr, err := s.PreSign(req, 15*time.Minute)
+// GenerateSignedRequest presigns an upload of the object described by metadata.
+// Constraints passed as options (content type, length, checksums, encryption and
+// upload metadata) are part of the signature, so S3 rejects uploads that do not
+// send the returned Headers with exactly these values.
+func (s *S3Service) GenerateSignedRequest(metadata Metadata, opts ...PresignOption) (PresignedUrlResponse, error) {
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return PresignedUrlResponse{}, err
+	}
 
+	req, _, err := s.putObjectRequest(metadata, o)
 	if err != nil {
-		return PresignedUrlResponse{}, fmt.Errorf("failed to sign request: %v", err)
+		return PresignedUrlResponse{}, err
 	}
 
-	return PresignedUrlResponse{
-		ID:           metadata.AppID,
-		PresignedUrl: urlStr,
-	}, nil
+	response, err := s.presign(req, http.MethodPut, aws.StringValue(req.Params.(*s3.PutObjectInput).Key), o)
+	if err != nil {
+		return PresignedUrlResponse{}, err
+	}
+
+	response.ID = metadata.AppID
+
+	return response, nil
 }
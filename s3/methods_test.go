@@ -0,0 +1,200 @@
+package s3
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewS3Service(t *testing.T) {
+	t.Run("Valid service", func(t *testing.T) {
+		service, err := mockNewS3Service("test-bucket", "builds/", "{{.AppID}}.apk")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test-bucket", service.bucket)
+		assert.Equal(t, "builds/", service.keyPrefix)
+		assert.NotNil(t, service.client)
+	})
+
+	t.Run("Empty bucket", func(t *testing.T) {
+		service, err := mockNewS3Service("", "builds/", "")
+
+		assert.Nil(t, service)
+		assert.EqualError(t, err, "bucket cannot be empty")
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		service, err := mockNewS3Service("test-bucket", "builds/", "{{.Nope}}")
+
+		assert.Nil(t, service)
+		assert.Error(t, err)
+	})
+}
+
+func TestGenerateSignedRequest(t *testing.T) {
+	metadata := Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3}
+
+	t.Run("Configured service", func(t *testing.T) {
+		service, _ := mockNewS3Service("test-bucket", "builds/", "{{.PartnerID}}/{{.AppID}}.apk")
+
+		response, err := service.GenerateSignedRequest(metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, response.ID)
+		assert.Equal(t, "builds/1/2.apk", response.Key)
+		assert.Equal(t, "PUT", response.Method)
+
+		u, err := url.Parse(response.PresignedUrl)
+		assert.NoError(t, err)
+		assert.Equal(t, "test-bucket.s3.amazonaws.com", u.Host)
+		assert.Equal(t, "/builds/1/2.apk", u.Path)
+		assert.Equal(t, "900", u.Query().Get("X-Amz-Expires"))
+	})
+
+	t.Run("Zero value keeps the default bucket and key", func(t *testing.T) {
+		mockNewS3Service("unused", "", "")
+		service := &S3Service{}
+
+		response, err := service.GenerateSignedRequest(metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "uploads/1-2-3.apk", response.Key)
+
+		u, _ := url.Parse(response.PresignedUrl)
+		assert.Equal(t, "brunojet-storage.s3.amazonaws.com", u.Host)
+	})
+
+	t.Run("Zero value creates one client when shared", func(t *testing.T) {
+		mockNewS3Service("unused", "", "")
+		service := &S3Service{}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.PresignGetObject("1-2-3.apk")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.Same(t, s3Client, service.api())
+	})
+}
+
+func TestGenerateSignedRequestConstraints(t *testing.T) {
+	service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+
+	t.Run("Constraints are signed headers", func(t *testing.T) {
+		metadata := Metadata{
+			PartnerID:     1,
+			AppID:         2,
+			DeviceModelID: 3,
+			ContentSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		}
+
+		response, err := service.GenerateSignedRequest(metadata,
+			WithContentType("application/vnd.android.package-archive"),
+			WithContentLength(1024),
+			WithContentMD5("1B2M2Y8AsgTpgAmY7PhCfg=="),
+			WithServerSideEncryption("aws:kms", "alias/uploads"),
+			WithUploadMetadata(),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"Content-Length":                              "1024",
+			"Content-Md5":                                 "1B2M2Y8AsgTpgAmY7PhCfg==",
+			"Content-Type":                                "application/vnd.android.package-archive",
+			"X-Amz-Checksum-Sha256":                       "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+			"X-Amz-Meta-Appid":                            "2",
+			"X-Amz-Meta-Devicemodelid":                    "3",
+			"X-Amz-Meta-Partnerid":                        "1",
+			"X-Amz-Server-Side-Encryption":                "aws:kms",
+			"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": "alias/uploads",
+		}, response.Headers)
+
+		signedHeaders := strings.Split(mustParseURL(response.PresignedUrl).Query().Get("X-Amz-SignedHeaders"), ";")
+		assert.Contains(t, signedHeaders, "content-type")
+		assert.Contains(t, signedHeaders, "content-length")
+		assert.Contains(t, signedHeaders, "x-amz-checksum-sha256")
+		assert.Contains(t, signedHeaders, "x-amz-meta-partnerid")
+	})
+
+	t.Run("Explicit checksum wins over metadata", func(t *testing.T) {
+		metadata := Metadata{AppID: 2, ContentSHA256: "not-used"}
+
+		response, err := service.GenerateSignedRequest(metadata, WithChecksumSHA256("abc="))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc=", response.Headers["X-Amz-Checksum-Sha256"])
+	})
+
+	t.Run("Invalid content hash", func(t *testing.T) {
+		_, err := service.GenerateSignedRequest(Metadata{ContentSHA256: "xyz"})
+
+		assert.EqualError(t, err, "content SHA-256 must be a hex-encoded digest")
+	})
+
+	t.Run("KMS key without aws:kms", func(t *testing.T) {
+		_, err := service.GenerateSignedRequest(Metadata{}, WithServerSideEncryption("AES256", "alias/uploads"))
+
+		assert.EqualError(t, err, "a KMS key ID requires aws:kms server-side encryption")
+	})
+
+	t.Run("Negative content length", func(t *testing.T) {
+		_, err := service.GenerateSignedRequest(Metadata{}, WithContentLength(-1))
+
+		assert.EqualError(t, err, "content length cannot be negative")
+	})
+}
+
+func TestGenerateSignedRequestExpiry(t *testing.T) {
+	oldNow := now
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	defer func() { now = oldNow }()
+
+	service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+
+	t.Run("Default expiry", func(t *testing.T) {
+		response, err := service.GenerateSignedRequest(Metadata{AppID: 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 5, 6, 7, 23, 9, 0, time.UTC), response.ExpiresAt)
+	})
+
+	t.Run("Per-call expiry", func(t *testing.T) {
+		response, err := service.GenerateSignedRequest(Metadata{AppID: 2}, WithExpiry(2*time.Hour))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "7200", mustParseURL(response.PresignedUrl).Query().Get("X-Amz-Expires"))
+		assert.Equal(t, time.Date(2024, 5, 6, 9, 8, 9, 0, time.UTC), response.ExpiresAt)
+	})
+
+	t.Run("Invalid expiry", func(t *testing.T) {
+		_, err := service.GenerateSignedRequest(Metadata{AppID: 2}, WithExpiry(0))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestPutObjectRequest(t *testing.T) {
+	service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+
+	req, output, err := service.PutObjectRequest(Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3}, WithContentType("text/plain"))
+
+	assert.NoError(t, err)
+	assert.NotNil(t, output)
+
+	input := req.Params.(*s3.PutObjectInput)
+	assert.Equal(t, "test-bucket", aws.StringValue(input.Bucket))
+	assert.Equal(t, "uploads/1-2-3.apk", aws.StringValue(input.Key))
+	assert.Equal(t, "text/plain", aws.StringValue(input.ContentType))
+}
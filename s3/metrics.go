@@ -0,0 +1,21 @@
+package s3
+
+import (
+	"go_aws_services/metrics"
+)
+
+// WithMetrics records Prometheus metrics for this service's calls in m,
+// replacing any inherited from the session. A nil m removes them. It is not
+// safe to call while the service is in use, and returns the service to allow
+// chaining.
+func (s *S3Service) WithMetrics(m *metrics.Metrics) *S3Service {
+	handlers := &s.ownHandle().Handlers
+
+	if m == nil {
+		metrics.Uninstall(handlers)
+	} else {
+		m.Install(handlers)
+	}
+
+	return s
+}
@@ -0,0 +1,324 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// CreatePresignedMultipartUpload starts a multipart upload of size bytes for the
+// object described by metadata and presigns one UploadPart URL per part, so that
+// a client can upload a large object in parts and retry each one on its own.
+// Content type, server-side encryption and upload metadata options apply to the
+// created upload; the expiry option applies to every part URL. Content length
+// and checksum options bind a single PUT and are refused. The same options must
+// be passed to CompleteMultipartUpload, since S3 requires a customer-provided
+// key on every request of the upload.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the CreateMultipartUpload call.
+//	metadata (Metadata): The upload the object belongs to, used to render the key.
+//	size (int64): The total size of the object in bytes.
+//	partSize (int64): The size of every part but the last, 0 for DefaultPartSize.
+//	opts (...PresignOption): The expiry and the constraints of the upload.
+//
+// Returns:
+//
+//	(PresignedMultipartUpload, error): The upload ID and part URLs, or an error if the upload could not be created.
+func (s *S3Service) CreatePresignedMultipartUpload(ctx context.Context, metadata Metadata, size int64, partSize int64, opts ...PresignOption) (PresignedMultipartUpload, error) {
+	o, err := newPartPresignOptions(opts)
+	if err != nil {
+		return PresignedMultipartUpload{}, err
+	}
+
+	if partSize == 0 {
+		partSize = DefaultPartSize
+	}
+
+	partCount, err := countParts(size, partSize)
+	if err != nil {
+		return PresignedMultipartUpload{}, err
+	}
+
+	key, err := s.ObjectKey(metadata)
+	if err != nil {
+		return PresignedMultipartUpload{}, err
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	}
+
+	if o.contentType != "" {
+		input.ContentType = aws.String(o.contentType)
+	}
+
+	s.sseParams(o).applyCreateMultipart(input)
+
+	if o.includeMetadata {
+		input.Metadata = aws.StringMap(metadataHeaders(metadata))
+	}
+
+	created, err := s.api().CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return PresignedMultipartUpload{}, err
+	}
+
+	uploadID := aws.StringValue(created.UploadId)
+
+	partNumbers := make([]int64, partCount)
+	for i := range partNumbers {
+		partNumbers[i] = int64(i + 1)
+	}
+
+	parts, expiresAt, err := s.presignParts(key, uploadID, partNumbers, o)
+	if err != nil {
+		s.abortUpload(key, uploadID)
+		return PresignedMultipartUpload{}, err
+	}
+
+	return PresignedMultipartUpload{
+		ID:        metadata.AppID,
+		Key:       key,
+		UploadID:  uploadID,
+		PartSize:  partSize,
+		Parts:     parts,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// PresignUploadParts presigns new URLs for some parts of an existing multipart
+// upload, e.g. to resume an upload whose original URLs have expired. It takes
+// the same options as CreatePresignedMultipartUpload.
+func (s *S3Service) PresignUploadParts(key string, uploadID string, partNumbers []int64, opts ...PresignOption) ([]PresignedPart, error) {
+	o, err := newPartPresignOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, number := range partNumbers {
+		if number < 1 || number > MaxUploadParts {
+			return nil, fmt.Errorf("part number must be between 1 and %d", MaxUploadParts)
+		}
+	}
+
+	parts, _, err := s.presignParts(key, uploadID, partNumbers, o)
+
+	return parts, err
+}
+
+// newPartPresignOptions reads the options of a multipart upload, refusing those
+// that bind the size or checksum of a single PUT and cannot hold for every part.
+func newPartPresignOptions(opts []PresignOption) (*presignOptions, error) {
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.contentLength != nil || o.contentMD5 != "" || o.checksumSHA256 != "" {
+		return nil, errors.New("content length and checksum options cannot apply to the parts of a multipart upload")
+	}
+
+	return o, nil
+}
+
+func (s *S3Service) presignParts(key string, uploadID string, partNumbers []int64, o *presignOptions) ([]PresignedPart, time.Time, error) {
+	parts := make([]PresignedPart, 0, len(partNumbers))
+	sse := s.sseParams(o)
+
+	var expiresAt time.Time
+	for _, number := range partNumbers {
+		input := &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucketName()),
+			Key:        aws.String(key),
+			UploadId:   aws.String(uploadID),
+			PartNumber: aws.Int64(number),
+		}
+
+		sse.applyUploadPart(input)
+
+		req, _ := s.api().UploadPartRequest(input)
+
+		response, err := s.presign(req, http.MethodPut, key, o)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		parts = append(parts, PresignedPart{PartNumber: number, URL: response.PresignedUrl, Headers: response.Headers})
+		expiresAt = response.ExpiresAt
+	}
+
+	return parts, expiresAt, nil
+}
+
+// CompleteMultipartUpload assembles the object from the parts a client uploaded
+// through presigned URLs. Parts may be given in any order but each part number
+// must appear once, with the ETag S3 returned for it. Of opts, only the
+// encryption applies, so the options the upload was created with can be passed
+// as they are.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the CompleteMultipartUpload call.
+//	key (string): The object key.
+//	uploadID (string): The ID returned by CreatePresignedMultipartUpload.
+//	parts ([]CompletedPart): The part numbers and ETags reported by the client.
+//	opts (...PresignOption): The options the upload was created with.
+//
+// Returns:
+//
+//	(*s3.CompleteMultipartUploadOutput, error): The output from the CompleteMultipartUpload operation, or an error if it failed.
+func (s *S3Service) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart, opts ...PresignOption) (*s3.CompleteMultipartUploadOutput, error) {
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadID == "" {
+		return nil, errors.New("upload ID cannot be empty")
+	}
+
+	if len(parts) == 0 {
+		return nil, errors.New("at least one part is required")
+	}
+
+	completed := make([]*s3.CompletedPart, 0, len(parts))
+	seen := make(map[int64]bool, len(parts))
+
+	for _, part := range parts {
+		if part.PartNumber < 1 || part.PartNumber > MaxUploadParts {
+			return nil, fmt.Errorf("part number must be between 1 and %d", MaxUploadParts)
+		}
+
+		if part.ETag == "" {
+			return nil, fmt.Errorf("part %d has no ETag", part.PartNumber)
+		}
+
+		if seen[part.PartNumber] {
+			return nil, fmt.Errorf("part %d was given more than once", part.PartNumber)
+		}
+		seen[part.PartNumber] = true
+
+		completed = append(completed, &s3.CompletedPart{
+			PartNumber: aws.Int64(part.PartNumber),
+			ETag:       aws.String(part.ETag),
+		})
+	}
+
+	sort.Slice(completed, func(i, j int) bool {
+		return aws.Int64Value(completed[i].PartNumber) < aws.Int64Value(completed[j].PartNumber)
+	})
+
+	input := &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucketName()),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	}
+
+	s.sseParams(o).applyComplete(input)
+
+	return s.api().CompleteMultipartUploadWithContext(ctx, input)
+}
+
+// AbortMultipartUpload cancels a multipart upload and discards its parts.
+func (s *S3Service) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	if uploadID == "" {
+		return errors.New("upload ID cannot be empty")
+	}
+
+	_, err := s.api().AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucketName()),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+
+	return err
+}
+
+// SweepStaleUploads aborts every multipart upload under the service's key prefix
+// that was started more than olderThan ago. Incomplete uploads are billed for
+// their stored parts until they are aborted, so this should run periodically.
+// It keeps going when a single abort fails and reports the first such error.
+// A service without a key prefix refuses to sweep, since it would abort the
+// uploads of everything else stored in the bucket.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the list and abort calls.
+//	olderThan (time.Duration): How old an upload must be to be considered stale.
+//
+// Returns:
+//
+//	(int, error): The number of uploads aborted, and an error if listing or any abort failed.
+func (s *S3Service) SweepStaleUploads(ctx context.Context, olderThan time.Duration) (int, error) {
+	if olderThan <= 0 {
+		return 0, errors.New("olderThan must be positive")
+	}
+
+	prefix := s.effectivePrefix()
+	if prefix == "" {
+		return 0, errors.New("refusing to sweep a bucket without a key prefix")
+	}
+
+	cutoff := now().Add(-olderThan)
+
+	var stale []*s3.MultipartUpload
+	err := s.api().ListMultipartUploadsPagesWithContext(ctx, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(s.bucketName()),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListMultipartUploadsOutput, lastPage bool) bool {
+		for _, upload := range page.Uploads {
+			if aws.TimeValue(upload.Initiated).Before(cutoff) {
+				stale = append(stale, upload)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		aborted  int
+		firstErr error
+	)
+
+	for _, upload := range stale {
+		err := s.AbortMultipartUpload(ctx, aws.StringValue(upload.Key), aws.StringValue(upload.UploadId))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to abort upload of %s: %v", aws.StringValue(upload.Key), err)
+			}
+			continue
+		}
+		aborted++
+	}
+
+	return aborted, firstErr
+}
+
+func countParts(size int64, partSize int64) (int, error) {
+	if size <= 0 {
+		return 0, errors.New("size must be positive")
+	}
+
+	if partSize < MinPartSize {
+		return 0, fmt.Errorf("part size must be at least %d bytes", MinPartSize)
+	}
+
+	count := (size + partSize - 1) / partSize
+	if count > MaxUploadParts {
+		return 0, fmt.Errorf("upload exceeds %d parts, increase the part size", MaxUploadParts)
+	}
+
+	return int(count), nil
+}
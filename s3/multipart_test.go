@@ -0,0 +1,217 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreatePresignedMultipartUpload(t *testing.T) {
+	oldNow := now
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	defer func() { now = oldNow }()
+
+	metadata := Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3}
+
+	t.Run("Presigns one URL per part", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, &s3.CreateMultipartUploadInput{
+			Bucket:      aws.String("test-bucket"),
+			Key:         aws.String("1-2-3.apk"),
+			ContentType: aws.String("application/octet-stream"),
+		}).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+
+		upload, err := service.CreatePresignedMultipartUpload(context.Background(), metadata, 2*MinPartSize+1, MinPartSize,
+			WithContentType("application/octet-stream"), WithExpiry(time.Hour))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, upload.ID)
+		assert.Equal(t, "upload-1", upload.UploadID)
+		assert.Equal(t, int64(MinPartSize), upload.PartSize)
+		assert.Equal(t, time.Date(2024, 5, 6, 8, 8, 9, 0, time.UTC), upload.ExpiresAt)
+		assert.Len(t, upload.Parts, 3)
+
+		for i, part := range upload.Parts {
+			u, err := url.Parse(part.URL)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(i+1), part.PartNumber)
+			assert.Equal(t, "upload-1", u.Query().Get("uploadId"))
+			assert.Equal(t, strconv.Itoa(i+1), u.Query().Get("partNumber"))
+			assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+		}
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Too many parts", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.CreatePresignedMultipartUpload(context.Background(), metadata, MinPartSize*(MaxUploadParts+1), MinPartSize)
+
+		assert.Error(t, err)
+		mockClient.AssertNotCalled(t, "CreateMultipartUploadWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid size", func(t *testing.T) {
+		service, _ := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.CreatePresignedMultipartUpload(context.Background(), metadata, 0, 0)
+
+		assert.EqualError(t, err, "size must be positive")
+	})
+
+	t.Run("Single PUT constraints", func(t *testing.T) {
+		for _, opt := range []PresignOption{WithContentLength(10), WithContentMD5("md5"), WithChecksumSHA256("sha")} {
+			service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+
+			_, err := service.CreatePresignedMultipartUpload(context.Background(), metadata, MinPartSize+1, MinPartSize, opt)
+
+			assert.EqualError(t, err, "content length and checksum options cannot apply to the parts of a multipart upload")
+			mockClient.AssertNotCalled(t, "CreateMultipartUploadWithContext", mock.Anything, mock.Anything)
+
+			_, err = service.PresignUploadParts("1-2-3.apk", "upload-1", []int64{1}, opt)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestPresignUploadParts(t *testing.T) {
+	service, _ := mockNewS3ServiceWithClient("test-bucket")
+
+	parts, err := service.PresignUploadParts("1-2-3.apk", "upload-1", []int64{4, 7})
+
+	assert.NoError(t, err)
+	assert.Len(t, parts, 2)
+	assert.Equal(t, int64(7), parts[1].PartNumber)
+
+	_, err = service.PresignUploadParts("1-2-3.apk", "upload-1", []int64{0})
+	assert.Error(t, err)
+}
+
+func TestCompleteMultipartUpload(t *testing.T) {
+	t.Run("Sorts parts", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("CompleteMultipartUploadWithContext", mock.Anything, &s3.CompleteMultipartUploadInput{
+			Bucket:   aws.String("test-bucket"),
+			Key:      aws.String("1-2-3.apk"),
+			UploadId: aws.String("upload-1"),
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: []*s3.CompletedPart{
+				{PartNumber: aws.Int64(1), ETag: aws.String("\"a\"")},
+				{PartNumber: aws.Int64(2), ETag: aws.String("\"b\"")},
+			}},
+		}).Return(&s3.CompleteMultipartUploadOutput{ETag: aws.String("\"final-2\"")}, nil)
+
+		output, err := service.CompleteMultipartUpload(context.Background(), "1-2-3.apk", "upload-1", []CompletedPart{
+			{PartNumber: 2, ETag: "\"b\""},
+			{PartNumber: 1, ETag: "\"a\""},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "\"final-2\"", aws.StringValue(output.ETag))
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Duplicate part", func(t *testing.T) {
+		service, _ := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.CompleteMultipartUpload(context.Background(), "1-2-3.apk", "upload-1", []CompletedPart{
+			{PartNumber: 1, ETag: "\"a\""},
+			{PartNumber: 1, ETag: "\"b\""},
+		})
+
+		assert.EqualError(t, err, "part 1 was given more than once")
+	})
+
+	t.Run("Missing ETag", func(t *testing.T) {
+		service, _ := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.CompleteMultipartUpload(context.Background(), "1-2-3.apk", "upload-1", []CompletedPart{{PartNumber: 1}})
+
+		assert.EqualError(t, err, "part 1 has no ETag")
+	})
+
+	t.Run("No parts", func(t *testing.T) {
+		service, _ := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.CompleteMultipartUpload(context.Background(), "1-2-3.apk", "upload-1", nil)
+
+		assert.EqualError(t, err, "at least one part is required")
+	})
+}
+
+func TestAbortMultipartUpload(t *testing.T) {
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	mockClient.On("AbortMultipartUploadWithContext", mock.Anything, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("test-bucket"),
+		Key:      aws.String("1-2-3.apk"),
+		UploadId: aws.String("upload-1"),
+	}).Return(&s3.AbortMultipartUploadOutput{}, nil)
+
+	assert.NoError(t, service.AbortMultipartUpload(context.Background(), "1-2-3.apk", "upload-1"))
+	assert.EqualError(t, service.AbortMultipartUpload(context.Background(), "1-2-3.apk", ""), "upload ID cannot be empty")
+	mockClient.AssertExpectations(t)
+}
+
+func TestSweepStaleUploads(t *testing.T) {
+	oldNow := now
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	defer func() { now = oldNow }()
+
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	service.keyPrefix = "uploads/"
+
+	mockClient.On("ListMultipartUploadsPagesWithContext", mock.Anything, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String("test-bucket"),
+		Prefix: aws.String("uploads/"),
+	}).Return(&s3.ListMultipartUploadsOutput{Uploads: []*s3.MultipartUpload{
+		{Key: aws.String("uploads/old.apk"), UploadId: aws.String("old"), Initiated: aws.Time(time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC))},
+		{Key: aws.String("uploads/failing.apk"), UploadId: aws.String("failing"), Initiated: aws.Time(time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC))},
+		{Key: aws.String("uploads/new.apk"), UploadId: aws.String("new"), Initiated: aws.Time(time.Date(2024, 5, 6, 7, 0, 0, 0, time.UTC))},
+	}}, nil)
+	mockClient.On("AbortMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.AbortMultipartUploadInput) bool {
+		return aws.StringValue(input.UploadId) == "old"
+	})).Return(&s3.AbortMultipartUploadOutput{}, nil)
+	mockClient.On("AbortMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.AbortMultipartUploadInput) bool {
+		return aws.StringValue(input.UploadId) == "failing"
+	})).Return((*s3.AbortMultipartUploadOutput)(nil), errors.New("access denied"))
+
+	aborted, err := service.SweepStaleUploads(context.Background(), 24*time.Hour)
+
+	assert.Equal(t, 1, aborted)
+	assert.EqualError(t, err, "failed to abort upload of uploads/failing.apk: access denied")
+	mockClient.AssertNumberOfCalls(t, "AbortMultipartUploadWithContext", 2)
+}
+
+func TestSweepStaleUploadsPrefix(t *testing.T) {
+	t.Run("Zero value sweeps the default prefix", func(t *testing.T) {
+		_, mockClient := mockNewS3ServiceWithClient("unused")
+		service := &S3Service{}
+
+		mockClient.On("ListMultipartUploadsPagesWithContext", mock.Anything, &s3.ListMultipartUploadsInput{
+			Bucket: aws.String(DefaultBucket),
+			Prefix: aws.String(DefaultKeyPrefix),
+		}).Return(&s3.ListMultipartUploadsOutput{}, nil)
+
+		aborted, err := service.SweepStaleUploads(context.Background(), time.Hour)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, aborted)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Empty prefix is refused", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+
+		_, err := service.SweepStaleUploads(context.Background(), time.Hour)
+
+		assert.EqualError(t, err, "refusing to sweep a bucket without a key prefix")
+		mockClient.AssertNotCalled(t, "ListMultipartUploadsPagesWithContext", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,388 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ObjectIterator walks the results of List one object at a time, requesting the
+// next page from S3 only when the current one is used up. It follows the
+// bufio.Scanner pattern:
+//
+//	it := service.List(ctx, "uploads/12-", ListOptions{})
+//	for it.Next() {
+//		object := it.Object()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type ObjectIterator struct {
+	ctx     context.Context
+	service *S3Service
+	input   *s3.ListObjectsV2Input
+	page    []ObjectInfo
+	current ObjectInfo
+	done    bool
+	err     error
+}
+
+// List returns an iterator over the objects whose keys start with prefix, in
+// lexicographic key order. Common prefixes found with opts.Delimiter are
+// returned as entries with IsPrefix set, ordered among the objects.
+func (s *S3Service) List(ctx context.Context, prefix string, opts ListOptions) *ObjectIterator {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName()),
+		Prefix: aws.String(prefix),
+	}
+
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+
+	if opts.StartAfter != "" {
+		input.StartAfter = aws.String(opts.StartAfter)
+	}
+
+	if opts.PageSize > 0 {
+		input.MaxKeys = aws.Int64(opts.PageSize)
+	}
+
+	return &ObjectIterator{ctx: ctx, service: s, input: input}
+}
+
+// Next advances to the next object and reports whether there is one. It returns
+// false at the end of the listing or when a request fails; see Err.
+func (it *ObjectIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+
+	it.current = it.page[0]
+	it.page = it.page[1:]
+
+	return true
+}
+
+// Object returns the object Next advanced to.
+func (it *ObjectIterator) Object() ObjectInfo {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *ObjectIterator) Err() error {
+	return it.err
+}
+
+func (it *ObjectIterator) fetch() {
+	output, err := it.service.api().ListObjectsV2WithContext(it.ctx, it.input)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	page := make([]ObjectInfo, 0, len(output.Contents)+len(output.CommonPrefixes))
+
+	for _, object := range output.Contents {
+		page = append(page, ObjectInfo{
+			Key:          aws.StringValue(object.Key),
+			Size:         aws.Int64Value(object.Size),
+			ETag:         aws.StringValue(object.ETag),
+			LastModified: aws.TimeValue(object.LastModified),
+			StorageClass: aws.StringValue(object.StorageClass),
+		})
+	}
+
+	for _, prefix := range output.CommonPrefixes {
+		page = append(page, ObjectInfo{Key: aws.StringValue(prefix.Prefix), IsPrefix: true})
+	}
+
+	sort.Slice(page, func(i, j int) bool {
+		return page[i].Key < page[j].Key
+	})
+
+	it.page = page
+
+	if aws.BoolValue(output.IsTruncated) {
+		it.input.ContinuationToken = output.NextContinuationToken
+	} else {
+		it.done = true
+	}
+}
+
+// Head returns the size, checksum, content type and user metadata of the object
+// stored under key without fetching its contents.
+func (s *S3Service) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	input := &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucketName()),
+		Key:          aws.String(key),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	}
+
+	s.encryption.params().applyHead(input)
+
+	output, err := s.api().HeadObjectWithContext(ctx, input)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{
+		Key:            key,
+		Size:           aws.Int64Value(output.ContentLength),
+		ETag:           aws.StringValue(output.ETag),
+		LastModified:   aws.TimeValue(output.LastModified),
+		StorageClass:   aws.StringValue(output.StorageClass),
+		ContentType:    aws.StringValue(output.ContentType),
+		ChecksumSHA256: aws.StringValue(output.ChecksumSHA256),
+		Metadata:       aws.StringValueMap(output.Metadata),
+	}, nil
+}
+
+// Delete removes the object stored under key. Deleting a key that does not exist
+// succeeds.
+func (s *S3Service) Delete(ctx context.Context, key string) error {
+	_, err := s.api().DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
+// DeleteMany removes keys with as few DeleteObjects requests as possible, sending
+// at most MaxDeleteObjects keys per request. It deletes every key it can and
+// reports the keys S3 refused in the returned error.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the delete requests.
+//	keys ([]string): The keys to delete.
+//
+// Returns:
+//
+//	(int, error): The number of keys deleted, and an error listing the keys that were not.
+func (s *S3Service) DeleteMany(ctx context.Context, keys []string) (int, error) {
+	var (
+		deleted int
+		errs    []error
+	)
+
+	for start := 0; start < len(keys); start += MaxDeleteObjects {
+		chunk := keys[start:min(start+MaxDeleteObjects, len(keys))]
+
+		objects := make([]*s3.ObjectIdentifier, len(chunk))
+		for i, key := range chunk {
+			objects[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+		}
+
+		output, err := s.api().DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucketName()),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, errors.Join(append(errs, err)...)
+		}
+
+		for _, failure := range output.Errors {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %s", aws.StringValue(failure.Key), aws.StringValue(failure.Message)))
+		}
+
+		deleted += len(chunk) - len(output.Errors)
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// Copy copies the object stored under sourceKey to destinationKey in the same
+// bucket, keeping its content type and user metadata. Objects larger than
+// MaxCopyObjectSize, which CopyObject cannot handle, are copied server side in
+// CopyPartSize parts with a multipart upload. The copy is encrypted with the
+// service's encryption, which must also hold the source's customer-provided key.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the copy.
+//	sourceKey (string): The key of the object to copy.
+//	destinationKey (string): The key of the copy.
+//
+// Returns:
+//
+//	(error): An error if the copy failed.
+func (s *S3Service) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	source, err := s.Head(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+
+	copySource := s.copySource(sourceKey)
+
+	if source.Size <= MaxCopyObjectSize {
+		input := &s3.CopyObjectInput{
+			Bucket:            aws.String(s.bucketName()),
+			Key:               aws.String(destinationKey),
+			CopySource:        aws.String(copySource),
+			CopySourceIfMatch: aws.String(source.ETag),
+		}
+
+		s.encryption.params().applyCopy(input)
+
+		_, err := s.api().CopyObjectWithContext(ctx, input)
+		return err
+	}
+
+	return s.multipartCopy(ctx, copySource, destinationKey, source)
+}
+
+// copySource returns the URL-encoded bucket/key form CopySource expects.
+func (s *S3Service) copySource(key string) string {
+	return (&url.URL{Path: s.bucketName() + "/" + key}).EscapedPath()
+}
+
+func (s *S3Service) multipartCopy(ctx context.Context, copySource string, destinationKey string, source ObjectInfo) error {
+	createInput := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(destinationKey),
+	}
+
+	if source.ContentType != "" {
+		createInput.ContentType = aws.String(source.ContentType)
+	}
+
+	if len(source.Metadata) > 0 {
+		createInput.Metadata = aws.StringMap(source.Metadata)
+	}
+
+	s.encryption.params().applyCreateMultipart(createInput)
+
+	created, err := s.api().CreateMultipartUploadWithContext(ctx, createInput)
+	if err != nil {
+		return err
+	}
+
+	uploadID := aws.StringValue(created.UploadId)
+
+	parts, err := s.copyParts(ctx, copySource, destinationKey, uploadID, source.ETag, copyRanges(source.Size))
+	if err != nil {
+		s.abortUpload(destinationKey, uploadID)
+		return err
+	}
+
+	completeInput := &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucketName()),
+		Key:             aws.String(destinationKey),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}
+
+	s.encryption.params().applyComplete(completeInput)
+
+	_, err = s.api().CompleteMultipartUploadWithContext(ctx, completeInput)
+	if err != nil {
+		s.abortUpload(destinationKey, uploadID)
+		return err
+	}
+
+	return nil
+}
+
+// copyRanges splits an object of size bytes into the ranges copied as parts:
+// CopyPartSize each, or larger when that would take more than MaxUploadParts.
+func copyRanges(size int64) []byteRange {
+	partSize := max(CopyPartSize, (size+MaxUploadParts-1)/MaxUploadParts)
+
+	var ranges []byteRange
+	for start := int64(0); start < size; start += partSize {
+		ranges = append(ranges, byteRange{start: start, end: min(start+partSize, size) - 1})
+	}
+
+	return ranges
+}
+
+// copyParts copies ranges with DefaultUploadConcurrency parallel UploadPartCopy
+// requests and returns the completed parts in order.
+func (s *S3Service) copyParts(ctx context.Context, copySource string, key string, uploadID string, etag string, ranges []byteRange) ([]*s3.CompletedPart, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		parts   = make([]*s3.CompletedPart, len(ranges))
+		copyErr error
+		sse     = s.encryption.params()
+	)
+
+	queue := make(chan int)
+
+	for i := 0; i < DefaultUploadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range queue {
+				partNumber := int64(index + 1)
+
+				input := &s3.UploadPartCopyInput{
+					Bucket:            aws.String(s.bucketName()),
+					Key:               aws.String(key),
+					UploadId:          aws.String(uploadID),
+					PartNumber:        aws.Int64(partNumber),
+					CopySource:        aws.String(copySource),
+					CopySourceRange:   aws.String(ranges[index].header()),
+					CopySourceIfMatch: aws.String(etag),
+				}
+
+				sse.applyUploadPartCopy(input)
+
+				output, err := s.api().UploadPartCopyWithContext(ctx, input)
+				if err != nil {
+					mu.Lock()
+					if copyErr == nil {
+						copyErr = err
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+
+				parts[index] = &s3.CompletedPart{
+					PartNumber: aws.Int64(partNumber),
+					ETag:       output.CopyPartResult.ETag,
+				}
+			}
+		}()
+	}
+
+	for index := range ranges {
+		select {
+		case queue <- index:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if copyErr == nil && ctx.Err() != nil {
+		copyErr = ctx.Err()
+	}
+
+	if copyErr != nil {
+		return nil, copyErr
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,237 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestList(t *testing.T) {
+	t.Run("Follows continuation tokens", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("ListObjectsV2WithContext", mock.Anything, mock.MatchedBy(func(input *s3.ListObjectsV2Input) bool {
+			return input.ContinuationToken == nil
+		})).Return(&s3.ListObjectsV2Output{
+			Contents: []*s3.Object{
+				{Key: aws.String("uploads/1-2-3.apk"), Size: aws.Int64(10)},
+				{Key: aws.String("uploads/1-2-4.apk"), Size: aws.Int64(20)},
+			},
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("token-1"),
+		}, nil).Once()
+		mockClient.On("ListObjectsV2WithContext", mock.Anything, mock.MatchedBy(func(input *s3.ListObjectsV2Input) bool {
+			return aws.StringValue(input.ContinuationToken) == "token-1"
+		})).Return(&s3.ListObjectsV2Output{
+			Contents:    []*s3.Object{{Key: aws.String("uploads/1-2-5.apk"), Size: aws.Int64(30)}},
+			IsTruncated: aws.Bool(false),
+		}, nil).Once()
+
+		it := service.List(context.Background(), "uploads/1-2-", ListOptions{PageSize: 2})
+
+		var keys []string
+		for it.Next() {
+			keys = append(keys, it.Object().Key)
+		}
+
+		assert.NoError(t, it.Err())
+		assert.Equal(t, []string{"uploads/1-2-3.apk", "uploads/1-2-4.apk", "uploads/1-2-5.apk"}, keys)
+		assert.False(t, it.Next())
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Groups common prefixes", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("ListObjectsV2WithContext", mock.Anything, &s3.ListObjectsV2Input{
+			Bucket:    aws.String("test-bucket"),
+			Prefix:    aws.String("uploads/"),
+			Delimiter: aws.String("/"),
+		}).Return(&s3.ListObjectsV2Output{
+			Contents:       []*s3.Object{{Key: aws.String("uploads/readme.txt")}},
+			CommonPrefixes: []*s3.CommonPrefix{{Prefix: aws.String("uploads/1/")}, {Prefix: aws.String("uploads/2/")}},
+		}, nil)
+
+		it := service.List(context.Background(), "uploads/", ListOptions{Delimiter: "/"})
+
+		var objects []ObjectInfo
+		for it.Next() {
+			objects = append(objects, it.Object())
+		}
+
+		assert.NoError(t, it.Err())
+		assert.Equal(t, []ObjectInfo{
+			{Key: "uploads/1/", IsPrefix: true},
+			{Key: "uploads/2/", IsPrefix: true},
+			{Key: "uploads/readme.txt"},
+		}, objects)
+	})
+
+	t.Run("Request failure", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("ListObjectsV2WithContext", mock.Anything, mock.Anything).Return((*s3.ListObjectsV2Output)(nil), errors.New("access denied"))
+
+		it := service.List(context.Background(), "uploads/", ListOptions{})
+
+		assert.False(t, it.Next())
+		assert.EqualError(t, it.Err(), "access denied")
+	})
+}
+
+func TestHead(t *testing.T) {
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	mockClient.On("HeadObjectWithContext", mock.Anything, &s3.HeadObjectInput{
+		Bucket:       aws.String("test-bucket"),
+		Key:          aws.String("uploads/1-2-3.apk"),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	}).Return(&s3.HeadObjectOutput{
+		ContentLength:  aws.Int64(42),
+		ContentType:    aws.String("application/vnd.android.package-archive"),
+		ETag:           aws.String("\"etag\""),
+		ChecksumSHA256: aws.String("checksum"),
+		Metadata:       map[string]*string{"Appid": aws.String("2")},
+	}, nil)
+
+	info, err := service.Head(context.Background(), "uploads/1-2-3.apk")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), info.Size)
+	assert.Equal(t, "application/vnd.android.package-archive", info.ContentType)
+	assert.Equal(t, "checksum", info.ChecksumSHA256)
+	assert.Equal(t, map[string]string{"Appid": "2"}, info.Metadata)
+}
+
+func TestDelete(t *testing.T) {
+	service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+	mockClient.On("DeleteObjectWithContext", mock.Anything, &s3.DeleteObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("uploads/1-2-3.apk"),
+	}).Return(&s3.DeleteObjectOutput{}, nil)
+
+	assert.NoError(t, service.Delete(context.Background(), "uploads/1-2-3.apk"))
+	mockClient.AssertExpectations(t)
+}
+
+func TestDeleteMany(t *testing.T) {
+	keys := make([]string, 2500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("uploads/%d.apk", i)
+	}
+
+	t.Run("Chunks keys", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("DeleteObjectsWithContext", mock.Anything, mock.MatchedBy(func(input *s3.DeleteObjectsInput) bool {
+			return len(input.Delete.Objects) <= MaxDeleteObjects && aws.BoolValue(input.Delete.Quiet)
+		})).Return(&s3.DeleteObjectsOutput{}, nil)
+
+		deleted, err := service.DeleteMany(context.Background(), keys)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2500, deleted)
+		mockClient.AssertNumberOfCalls(t, "DeleteObjectsWithContext", 3)
+	})
+
+	t.Run("Reports refused keys", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockClient.On("DeleteObjectsWithContext", mock.Anything, mock.Anything).Return(&s3.DeleteObjectsOutput{
+			Errors: []*s3.Error{{Key: aws.String("uploads/1.apk"), Message: aws.String("Access Denied")}},
+		}, nil)
+
+		deleted, err := service.DeleteMany(context.Background(), keys[:3])
+
+		assert.Equal(t, 2, deleted)
+		assert.EqualError(t, err, "failed to delete uploads/1.apk: Access Denied")
+	})
+
+	t.Run("No keys", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+
+		deleted, err := service.DeleteMany(context.Background(), nil)
+
+		assert.NoError(t, err)
+		assert.Zero(t, deleted)
+		mockClient.AssertNotCalled(t, "DeleteObjectsWithContext", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCopy(t *testing.T) {
+	mockHead := func(mockClient *mockS3Client, size int64) {
+		mockClient.On("HeadObjectWithContext", mock.Anything, mock.Anything).Return(&s3.HeadObjectOutput{
+			ContentLength: aws.Int64(size),
+			ContentType:   aws.String("application/vnd.android.package-archive"),
+			ETag:          aws.String("\"source\""),
+		}, nil)
+	}
+
+	t.Run("Small object", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockHead(mockClient, 1024)
+		mockClient.On("CopyObjectWithContext", mock.Anything, &s3.CopyObjectInput{
+			Bucket:            aws.String("test-bucket"),
+			Key:               aws.String("archive/app 1.apk"),
+			CopySource:        aws.String("test-bucket/uploads/app%201.apk"),
+			CopySourceIfMatch: aws.String("\"source\""),
+		}).Return(&s3.CopyObjectOutput{}, nil)
+
+		err := service.Copy(context.Background(), "uploads/app 1.apk", "archive/app 1.apk")
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Large object uses multipart copy", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		size := int64(MaxCopyObjectSize + 1)
+		mockHead(mockClient, size)
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateMultipartUploadInput) bool {
+			return aws.StringValue(input.ContentType) == "application/vnd.android.package-archive"
+		})).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+		mockClient.On("UploadPartCopyWithContext", mock.Anything, mock.Anything).Return(&s3.UploadPartCopyOutput{
+			CopyPartResult: &s3.CopyPartResult{ETag: aws.String("\"part\"")},
+		}, nil)
+		mockClient.On("CompleteMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CompleteMultipartUploadInput) bool {
+			parts := input.MultipartUpload.Parts
+			return len(parts) == 11 && aws.Int64Value(parts[10].PartNumber) == 11
+		})).Return(&s3.CompleteMultipartUploadOutput{}, nil)
+
+		err := service.Copy(context.Background(), "uploads/big.obb", "archive/big.obb")
+
+		assert.NoError(t, err)
+		mockClient.AssertCalled(t, "UploadPartCopyWithContext", mock.Anything, mock.MatchedBy(func(input *s3.UploadPartCopyInput) bool {
+			return aws.Int64Value(input.PartNumber) == 11 &&
+				aws.StringValue(input.CopySourceRange) == fmt.Sprintf("bytes=%d-%d", 10*CopyPartSize, size-1)
+		}))
+		mockClient.AssertNotCalled(t, "CopyObjectWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed part copy aborts", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		mockHead(mockClient, MaxCopyObjectSize+1)
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.Anything).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+		mockClient.On("UploadPartCopyWithContext", mock.Anything, mock.Anything).Return((*s3.UploadPartCopyOutput)(nil), errors.New("slow down"))
+		mockClient.On("AbortMultipartUploadWithContext", mock.Anything, mock.Anything).Return(&s3.AbortMultipartUploadOutput{}, nil)
+
+		err := service.Copy(context.Background(), "uploads/big.obb", "archive/big.obb")
+
+		assert.EqualError(t, err, "slow down")
+		mockClient.AssertCalled(t, "AbortMultipartUploadWithContext", mock.Anything, mock.Anything)
+		mockClient.AssertNotCalled(t, "CompleteMultipartUploadWithContext", mock.Anything, mock.Anything)
+	})
+	t.Run("Part count stays within the upload limit", func(t *testing.T) {
+		size := int64(5 << 40)
+
+		ranges := copyRanges(size)
+
+		assert.LessOrEqual(t, len(ranges), MaxUploadParts)
+		assert.Equal(t, int64(0), ranges[0].start)
+		assert.Equal(t, size-1, ranges[len(ranges)-1].end)
+		for i := 1; i < len(ranges); i++ {
+			assert.Equal(t, ranges[i-1].end+1, ranges[i].start)
+		}
+		assert.LessOrEqual(t, ranges[0].end-ranges[0].start+1, int64(MaxCopyObjectSize))
+	})
+}
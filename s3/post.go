@@ -0,0 +1,225 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+	shortDateFormat  = "20060102"
+)
+
+// GeneratePresignedPost builds an HTML form upload (URL plus form fields) for the
+// object described by metadata. The policy document binds the bucket, the key,
+// the upload metadata and every condition in policy, so S3 rejects uploads that
+// are too large, have the wrong content type or tamper with the fields.
+//
+// Parameters:
+//
+//	metadata (Metadata): The upload the object key and x-amz-meta-* fields are derived from.
+//	policy (PostPolicy): The size, content type, status and metadata conditions.
+//	opts (...PresignOption): The expiry of the policy and the encryption of the object.
+//
+// Returns:
+//
+//	(PresignedPostResponse, error): The form URL and fields, or an error if the policy is invalid or signing failed.
+func (s *S3Service) GeneratePresignedPost(metadata Metadata, policy PostPolicy, opts ...PresignOption) (PresignedPostResponse, error) {
+	if err := validatePostPolicy(policy); err != nil {
+		return PresignedPostResponse{}, err
+	}
+
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return PresignedPostResponse{}, err
+	}
+
+	key, err := s.ObjectKey(metadata)
+	if err != nil {
+		return PresignedPostResponse{}, err
+	}
+
+	creds, region := s.signingConfig()
+
+	value, err := creds.Get()
+	if err != nil {
+		return PresignedPostResponse{}, fmt.Errorf("failed to sign request: %v", err)
+	}
+
+	signedAt := now().UTC()
+	expiresAt := signedAt.Add(o.expiry)
+	credential := fmt.Sprintf("%s/%s/%s/s3/aws4_request", value.AccessKeyID, signedAt.Format(shortDateFormat), region)
+
+	fields := map[string]string{
+		"key":              key,
+		"x-amz-algorithm":  signingAlgorithm,
+		"x-amz-credential": credential,
+		"x-amz-date":       signedAt.Format(amzDateFormat),
+	}
+
+	if value.SessionToken != "" {
+		fields["x-amz-security-token"] = value.SessionToken
+	}
+
+	if policy.SuccessActionStatus != 0 {
+		fields["success_action_status"] = strconv.Itoa(policy.SuccessActionStatus)
+	}
+
+	for name, v := range metadataHeaders(metadata) {
+		fields["x-amz-meta-"+name] = v
+	}
+
+	for name, v := range policy.Metadata {
+		fields["x-amz-meta-"+strings.ToLower(name)] = v
+	}
+
+	encryption := s.encryption
+	if o.encryption != nil {
+		encryption = *o.encryption
+	}
+
+	for name, v := range encryption.formFields() {
+		fields[name] = v
+	}
+
+	conditions := []interface{}{
+		map[string]string{"bucket": s.bucketName()},
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		conditions = append(conditions, map[string]string{name: fields[name]})
+	}
+
+	if maxLength := maxContentLength(policy); maxLength > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", policy.MinContentLength, maxLength})
+	}
+
+	if policy.ContentTypePrefix != "" {
+		conditions = append(conditions, []interface{}{"starts-with", "$Content-Type", policy.ContentTypePrefix})
+	}
+
+	document, err := json.Marshal(map[string]interface{}{
+		"expiration": expiresAt.Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return PresignedPostResponse{}, err
+	}
+
+	encodedPolicy := base64.StdEncoding.EncodeToString(document)
+	signingKey := deriveSigningKey(value.SecretAccessKey, signedAt, region, "s3")
+
+	fields["policy"] = encodedPolicy
+	fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(signingKey, []byte(encodedPolicy)))
+
+	return PresignedPostResponse{
+		ID:        metadata.AppID,
+		Key:       key,
+		URL:       s.bucketURL(region),
+		Fields:    fields,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// maxContentLength returns the upper bound of the content-length-range
+// condition, or 0 when the policy does not restrict the size. S3 only checks a
+// range, so a minimum alone is paired with the largest size a POST can upload.
+func maxContentLength(policy PostPolicy) int64 {
+	if policy.MaxContentLength == 0 && policy.MinContentLength > 0 {
+		return MaxPutObjectSize
+	}
+
+	return policy.MaxContentLength
+}
+
+func validatePostPolicy(policy PostPolicy) error {
+	if policy.MinContentLength < 0 || policy.MaxContentLength < 0 {
+		return errors.New("content length range cannot be negative")
+	}
+
+	if maxLength := maxContentLength(policy); maxLength > 0 && policy.MinContentLength > maxLength {
+		return errors.New("minimum content length cannot exceed the maximum")
+	}
+
+	switch policy.SuccessActionStatus {
+	case 0, 200, 201, 204:
+		return nil
+	default:
+		return errors.New("success action status must be one of 200, 201 or 204")
+	}
+}
+
+// signingConfig returns the credentials and region of the service's client,
+// falling back to the shared session when the client is not an *s3.S3.
+func (s *S3Service) signingConfig() (*credentials.Credentials, string) {
+	if client, ok := s.api().(*s3.S3); ok {
+		return client.Config.Credentials, aws.StringValue(client.Config.Region)
+	}
+
+	sess := getAwsSession()
+	return sess.Config.Credentials, aws.StringValue(sess.Config.Region)
+}
+
+// bucketURL returns the URL browser forms post to: virtual-hosted style on the
+// client's endpoint, or path style when the client forces it.
+func (s *S3Service) bucketURL(region string) string {
+	client, ok := s.api().(*s3.S3)
+	if !ok {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", s.bucketName(), region)
+	}
+
+	if aws.BoolValue(client.Config.S3ForcePathStyle) {
+		return strings.TrimSuffix(client.Endpoint, "/") + "/" + s.bucketName() + "/"
+	}
+
+	u, err := url.Parse(client.Endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", s.bucketName(), region)
+	}
+
+	return fmt.Sprintf("%s://%s.%s/", u.Scheme, s.bucketName(), u.Host)
+}
+
+// metadataHeaders returns the user metadata (without the x-amz-meta- prefix)
+// that records which upload an object belongs to.
+func metadataHeaders(metadata Metadata) map[string]string {
+	return map[string]string{
+		"partnerid":     strconv.Itoa(metadata.PartnerID),
+		"appid":         strconv.Itoa(metadata.AppID),
+		"devicemodelid": strconv.Itoa(metadata.DeviceModelID),
+	}
+}
+
+func deriveSigningKey(secret string, t time.Time, region string, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), []byte(t.Format(shortDateFormat)))
+	key = hmacSHA256(key, []byte(region))
+	key = hmacSHA256(key, []byte(service))
+	return hmacSHA256(key, []byte("aws4_request"))
+}
+
+func hmacSHA256(key []byte, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation.
+	key := deriveSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", time.Date(2012, 2, 15, 0, 0, 0, 0, time.UTC), "us-east-1", "iam")
+
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+func TestGeneratePresignedPost(t *testing.T) {
+	oldNow := now
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	defer func() { now = oldNow }()
+
+	service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+	metadata := Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3}
+
+	t.Run("Policy with constraints", func(t *testing.T) {
+		response, err := service.GeneratePresignedPost(metadata, PostPolicy{
+			MinContentLength:    1,
+			MaxContentLength:    100 * 1024 * 1024,
+			ContentTypePrefix:   "application/vnd.android",
+			SuccessActionStatus: 201,
+			Metadata:            map[string]string{"Build": "42"},
+		}, WithExpiry(time.Hour))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, response.ID)
+		assert.Equal(t, "uploads/1-2-3.apk", response.Key)
+		assert.Equal(t, "https://test-bucket.s3.amazonaws.com/", response.URL)
+		assert.Equal(t, time.Date(2024, 5, 6, 8, 8, 9, 0, time.UTC), response.ExpiresAt)
+
+		fields := response.Fields
+		assert.Equal(t, "uploads/1-2-3.apk", fields["key"])
+		assert.Equal(t, "AWS4-HMAC-SHA256", fields["x-amz-algorithm"])
+		assert.Equal(t, "AKIDEXAMPLE/20240506/us-east-1/s3/aws4_request", fields["x-amz-credential"])
+		assert.Equal(t, "20240506T070809Z", fields["x-amz-date"])
+		assert.Equal(t, "201", fields["success_action_status"])
+		assert.Equal(t, "1", fields["x-amz-meta-partnerid"])
+		assert.Equal(t, "2", fields["x-amz-meta-appid"])
+		assert.Equal(t, "3", fields["x-amz-meta-devicemodelid"])
+		assert.Equal(t, "42", fields["x-amz-meta-build"])
+		assert.Len(t, fields["x-amz-signature"], 64)
+
+		raw, err := base64.StdEncoding.DecodeString(fields["policy"])
+		assert.NoError(t, err)
+
+		var document struct {
+			Expiration string        `json:"expiration"`
+			Conditions []interface{} `json:"conditions"`
+		}
+		assert.NoError(t, json.Unmarshal(raw, &document))
+		assert.Equal(t, "2024-05-06T08:08:09.000Z", document.Expiration)
+		assert.Contains(t, document.Conditions, map[string]interface{}{"bucket": "test-bucket"})
+		assert.Contains(t, document.Conditions, map[string]interface{}{"key": "uploads/1-2-3.apk"})
+		assert.Contains(t, document.Conditions, map[string]interface{}{"x-amz-meta-build": "42"})
+		assert.Contains(t, document.Conditions, []interface{}{"content-length-range", float64(1), float64(100 * 1024 * 1024)})
+		assert.Contains(t, document.Conditions, []interface{}{"starts-with", "$Content-Type", "application/vnd.android"})
+	})
+
+	t.Run("Signature covers the policy", func(t *testing.T) {
+		a, _ := service.GeneratePresignedPost(metadata, PostPolicy{MaxContentLength: 10})
+		b, _ := service.GeneratePresignedPost(metadata, PostPolicy{MaxContentLength: 20})
+
+		assert.NotEqual(t, a.Fields["policy"], b.Fields["policy"])
+		assert.NotEqual(t, a.Fields["x-amz-signature"], b.Fields["x-amz-signature"])
+	})
+
+	t.Run("Minimum length only", func(t *testing.T) {
+		response, err := service.GeneratePresignedPost(metadata, PostPolicy{MinContentLength: 10})
+		assert.NoError(t, err)
+
+		raw, _ := base64.StdEncoding.DecodeString(response.Fields["policy"])
+		var document struct {
+			Conditions []interface{} `json:"conditions"`
+		}
+		assert.NoError(t, json.Unmarshal(raw, &document))
+		assert.Contains(t, document.Conditions, []interface{}{"content-length-range", float64(10), float64(MaxPutObjectSize)})
+
+		_, err = service.GeneratePresignedPost(metadata, PostPolicy{MinContentLength: MaxPutObjectSize + 1})
+		assert.EqualError(t, err, "minimum content length cannot exceed the maximum")
+	})
+
+	t.Run("Invalid length range", func(t *testing.T) {
+		_, err := service.GeneratePresignedPost(metadata, PostPolicy{MinContentLength: 10, MaxContentLength: 5})
+
+		assert.EqualError(t, err, "minimum content length cannot exceed the maximum")
+	})
+
+	t.Run("Invalid success status", func(t *testing.T) {
+		_, err := service.GeneratePresignedPost(metadata, PostPolicy{SuccessActionStatus: 302})
+
+		assert.EqualError(t, err, "success action status must be one of 200, 201 or 204")
+	})
+}
@@ -0,0 +1,276 @@
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// PresignOption customises a single presigned URL.
+type PresignOption func(*presignOptions)
+
+type presignOptions struct {
+	expiry                     time.Duration
+	responseContentDisposition string
+	responseContentType        string
+	contentType                string
+	contentLength              *int64
+	contentMD5                 string
+	checksumSHA256             string
+	encryption                 *Encryption
+	includeMetadata            bool
+}
+
+// WithExpiry sets how long the presigned URL stays valid. It defaults to
+// DefaultPresignExpiry and cannot exceed MaxPresignExpiry.
+func WithExpiry(expiry time.Duration) PresignOption {
+	return func(o *presignOptions) {
+		o.expiry = expiry
+	}
+}
+
+// WithResponseContentDisposition overrides the Content-Disposition header S3
+// returns for a presigned GET, e.g. `attachment; filename="app.apk"`.
+func WithResponseContentDisposition(disposition string) PresignOption {
+	return func(o *presignOptions) {
+		o.responseContentDisposition = disposition
+	}
+}
+
+// WithResponseContentType overrides the Content-Type header S3 returns for a
+// presigned GET.
+func WithResponseContentType(contentType string) PresignOption {
+	return func(o *presignOptions) {
+		o.responseContentType = contentType
+	}
+}
+
+// WithContentType binds the Content-Type of a presigned PUT.
+func WithContentType(contentType string) PresignOption {
+	return func(o *presignOptions) {
+		o.contentType = contentType
+	}
+}
+
+// WithContentLength binds the exact size in bytes of a presigned PUT.
+func WithContentLength(length int64) PresignOption {
+	return func(o *presignOptions) {
+		o.contentLength = &length
+	}
+}
+
+// WithContentMD5 binds the base64-encoded MD5 digest of a presigned PUT body.
+func WithContentMD5(md5 string) PresignOption {
+	return func(o *presignOptions) {
+		o.contentMD5 = md5
+	}
+}
+
+// WithChecksumSHA256 binds the base64-encoded SHA-256 checksum of a presigned PUT
+// body. When it is not given, Metadata.ContentSHA256 is used if set.
+func WithChecksumSHA256(checksum string) PresignOption {
+	return func(o *presignOptions) {
+		o.checksumSHA256 = checksum
+	}
+}
+
+// WithServerSideEncryption requires a presigned PUT to be stored encrypted with
+// algorithm (AES256 or aws:kms), using kmsKeyID when the algorithm is aws:kms.
+func WithServerSideEncryption(algorithm string, kmsKeyID string) PresignOption {
+	return WithEncryption(Encryption{Algorithm: algorithm, KMSKeyID: kmsKeyID})
+}
+
+// WithEncryption overrides the service's encryption for one presigned URL. Uploads
+// are stored with encryption; with a customer-provided key, downloads and part
+// uploads also send it. The key is then one of the returned Headers, never part
+// of the URL, so it must be passed to the client over a secure channel.
+func WithEncryption(encryption Encryption) PresignOption {
+	return func(o *presignOptions) {
+		o.encryption = &encryption
+	}
+}
+
+// WithUploadMetadata stores the partner, app and device model IDs of the upload
+// as x-amz-meta-* user metadata on a presigned PUT.
+func WithUploadMetadata() PresignOption {
+	return func(o *presignOptions) {
+		o.includeMetadata = true
+	}
+}
+
+func newPresignOptions(opts []PresignOption) (*presignOptions, error) {
+	o := &presignOptions{expiry: DefaultPresignExpiry}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.expiry <= 0 || o.expiry > MaxPresignExpiry {
+		return nil, fmt.Errorf("presign expiry must be between 0 and %s", MaxPresignExpiry)
+	}
+
+	if o.contentLength != nil && *o.contentLength < 0 {
+		return nil, errors.New("content length cannot be negative")
+	}
+
+	if o.encryption != nil {
+		if err := o.encryption.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+// sseParams returns the encryption parameters for a presigned request: the
+// encryption set in o, or the service's.
+func (s *S3Service) sseParams(o *presignOptions) sseParams {
+	if o.encryption != nil {
+		return o.encryption.params()
+	}
+
+	return s.encryption.params()
+}
+
+// applyPutConstraints copies the constraints in o into input so they become part
+// of the signature.
+func applyPutConstraints(input *s3.PutObjectInput, metadata Metadata, o *presignOptions) error {
+	if o.contentType != "" {
+		input.ContentType = aws.String(o.contentType)
+	}
+
+	if o.contentLength != nil {
+		input.ContentLength = aws.Int64(*o.contentLength)
+	}
+
+	if o.contentMD5 != "" {
+		input.ContentMD5 = aws.String(o.contentMD5)
+	}
+
+	checksum := o.checksumSHA256
+	if checksum == "" && metadata.ContentSHA256 != "" {
+		digest, err := hex.DecodeString(metadata.ContentSHA256)
+		if err != nil || len(digest) != sha256.Size {
+			return errors.New("content SHA-256 must be a hex-encoded digest")
+		}
+		checksum = base64.StdEncoding.EncodeToString(digest)
+	}
+
+	if checksum != "" {
+		input.ChecksumSHA256 = aws.String(checksum)
+	}
+
+	if o.includeMetadata {
+		input.Metadata = aws.StringMap(metadataHeaders(metadata))
+	}
+
+	return nil
+}
+
+// presign signs req and describes the resulting URL. Headers are never hoisted
+// into the query string, so every constraint set on the request is returned in
+// Headers and must be sent by the client exactly as given.
+func (s *S3Service) presign(req *request.Request, method string, key string, o *presignOptions) (PresignedUrlResponse, error) {
+	signedAt := now()
+
+	req.NotHoist = true
+
+	urlStr, signedHeaders, err := req.PresignRequest(o.expiry)
+	if err != nil {
+		return PresignedUrlResponse{}, fmt.Errorf("failed to sign request: %v", err)
+	}
+
+	var headers map[string]string
+	if len(signedHeaders) > 0 {
+		headers = make(map[string]string, len(signedHeaders))
+		for name, values := range signedHeaders {
+			headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ",")
+		}
+	}
+
+	return PresignedUrlResponse{
+		Key:          key,
+		Method:       method,
+		PresignedUrl: urlStr,
+		Headers:      headers,
+		ExpiresAt:    signedAt.Add(o.expiry).UTC(),
+	}, nil
+}
+
+// PresignGetObject returns a URL that downloads the object stored under key.
+//
+// Parameters:
+//
+//	key (string): The object key.
+//	opts (...PresignOption): The expiry and response header overrides.
+//
+// Returns:
+//
+//	(PresignedUrlResponse, error): The presigned GET URL, or an error if signing failed.
+func (s *S3Service) PresignGetObject(key string, opts ...PresignOption) (PresignedUrlResponse, error) {
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return PresignedUrlResponse{}, err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	}
+
+	if o.responseContentDisposition != "" {
+		input.ResponseContentDisposition = aws.String(o.responseContentDisposition)
+	}
+
+	if o.responseContentType != "" {
+		input.ResponseContentType = aws.String(o.responseContentType)
+	}
+
+	s.sseParams(o).applyGet(input)
+
+	req, _ := s.api().GetObjectRequest(input)
+
+	return s.presign(req, http.MethodGet, key, o)
+}
+
+// PresignHeadObject returns a URL that reads the metadata of the object stored under key.
+func (s *S3Service) PresignHeadObject(key string, opts ...PresignOption) (PresignedUrlResponse, error) {
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return PresignedUrlResponse{}, err
+	}
+
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	}
+
+	s.sseParams(o).applyHead(input)
+
+	req, _ := s.api().HeadObjectRequest(input)
+
+	return s.presign(req, http.MethodHead, key, o)
+}
+
+// PresignDeleteObject returns a URL that deletes the object stored under key.
+func (s *S3Service) PresignDeleteObject(key string, opts ...PresignOption) (PresignedUrlResponse, error) {
+	o, err := newPresignOptions(opts)
+	if err != nil {
+		return PresignedUrlResponse{}, err
+	}
+
+	req, _ := s.api().DeleteObjectRequest(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(key),
+	})
+
+	return s.presign(req, http.MethodDelete, key, o)
+}
@@ -0,0 +1,74 @@
+package s3
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresignGetObject(t *testing.T) {
+	oldNow := now
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	defer func() { now = oldNow }()
+
+	service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+
+	t.Run("Default expiry", func(t *testing.T) {
+		response, err := service.PresignGetObject("uploads/1-2-3.apk")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "GET", response.Method)
+		assert.Equal(t, "uploads/1-2-3.apk", response.Key)
+		assert.Equal(t, time.Date(2024, 5, 6, 7, 23, 9, 0, time.UTC), response.ExpiresAt)
+
+		u, _ := url.Parse(response.PresignedUrl)
+		assert.Equal(t, "/uploads/1-2-3.apk", u.Path)
+		assert.Equal(t, "900", u.Query().Get("X-Amz-Expires"))
+	})
+
+	t.Run("Response overrides and custom expiry", func(t *testing.T) {
+		response, err := service.PresignGetObject("uploads/1-2-3.apk",
+			WithExpiry(time.Hour),
+			WithResponseContentDisposition(`attachment; filename="app.apk"`),
+			WithResponseContentType("application/vnd.android.package-archive"),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 5, 6, 8, 8, 9, 0, time.UTC), response.ExpiresAt)
+
+		query, _ := url.ParseQuery(mustParseURL(response.PresignedUrl).RawQuery)
+		assert.Equal(t, "3600", query.Get("X-Amz-Expires"))
+		assert.Equal(t, `attachment; filename="app.apk"`, query.Get("response-content-disposition"))
+		assert.Equal(t, "application/vnd.android.package-archive", query.Get("response-content-type"))
+	})
+
+	t.Run("Expiry out of range", func(t *testing.T) {
+		_, err := service.PresignGetObject("uploads/1-2-3.apk", WithExpiry(8*24*time.Hour))
+
+		assert.EqualError(t, err, "presign expiry must be between 0 and 168h0m0s")
+	})
+}
+
+func TestPresignHeadAndDeleteObject(t *testing.T) {
+	service, _ := mockNewS3Service("test-bucket", "uploads/", "")
+
+	head, err := service.PresignHeadObject("uploads/a.apk", WithExpiry(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "HEAD", head.Method)
+	assert.Equal(t, "60", mustParseURL(head.PresignedUrl).Query().Get("X-Amz-Expires"))
+
+	del, err := service.PresignDeleteObject("uploads/a.apk")
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE", del.Method)
+	assert.Equal(t, "/uploads/a.apk", mustParseURL(del.PresignedUrl).Path)
+}
+
+func mustParseURL(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
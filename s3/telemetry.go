@@ -0,0 +1,21 @@
+package s3
+
+import (
+	"go_aws_services/telemetry"
+)
+
+// WithInstrumentation traces this service's calls and records their metrics
+// with instrumentation, replacing any inherited from the session. A nil
+// instrumentation removes it. It is not safe to call while the service is in
+// use, and returns the service to allow chaining.
+func (s *S3Service) WithInstrumentation(instrumentation *telemetry.Instrumentation) *S3Service {
+	handlers := &s.ownHandle().Handlers
+
+	if instrumentation == nil {
+		telemetry.Uninstall(handlers)
+	} else {
+		instrumentation.Install(handlers)
+	}
+
+	return s
+}
@@ -1,12 +1,292 @@
 package s3
 
+import (
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+const (
+	DefaultBucket        = "brunojet-storage"
+	DefaultKeyPrefix     = "uploads/"
+	DefaultKeyTemplate   = "{{.PartnerID}}-{{.AppID}}-{{.DeviceModelID}}.apk"
+	DefaultPresignExpiry = 15 * time.Minute
+	MaxPresignExpiry     = 7 * 24 * time.Hour
+)
+
+const (
+	MinPartSize              = 5 * 1024 * 1024
+	DefaultPartSize          = 8 * 1024 * 1024
+	MaxUploadParts           = 10000
+	DefaultUploadConcurrency = 4
+	DefaultPartRetries       = 3
+)
+
+const (
+	MaxDeleteObjects  = 1000
+	MaxPutObjectSize  = 5 * 1024 * 1024 * 1024
+	MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
+	CopyPartSize      = 512 * 1024 * 1024
+)
+
+// S3Service é uma implementação da interface S3ServiceInterface.
+// The zero value uploads to DefaultBucket using DefaultKeyPrefix and DefaultKeyTemplate,
+// and like a service from NewS3Service it is safe for concurrent use.
+type S3Service struct {
+	bucket      string
+	keyPrefix   string
+	keyTemplate *template.Template
+	encryption  Encryption
+	// mu guards the lazy creation of handle and client.
+	mu     sync.Mutex
+	handle *s3.S3
+	client s3iface.S3API
+}
+
+// Metadata identifies an upload. ContentSHA256 is the optional hex-encoded
+// SHA-256 digest of the file; when set it is available to key templates and is
+// bound into presigned PUTs as the object checksum.
 type Metadata struct {
-	PartnerID     int `json:"partnerId"`
-	AppID         int `json:"appId"`
-	DeviceModelID int `json:"deviceModelId"`
+	PartnerID     int    `json:"partnerId"`
+	AppID         int    `json:"appId"`
+	DeviceModelID int    `json:"deviceModelId"`
+	ContentSHA256 string `json:"contentSha256,omitempty"`
+}
+
+// PostPolicy lists the conditions a browser upload through a presigned POST
+// form must satisfy. A zero MaxContentLength leaves the size unrestricted, up to
+// MaxPutObjectSize when a MinContentLength is set, and a zero SuccessActionStatus keeps S3's default 204 response. Metadata holds extra
+// x-amz-meta-* fields the form must post with exactly these values.
+type PostPolicy struct {
+	MinContentLength    int64
+	MaxContentLength    int64
+	ContentTypePrefix   string
+	SuccessActionStatus int
+	Metadata            map[string]string
+}
+
+type PresignedPostResponse struct {
+	ID        int               `json:"id"`
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Fields    map[string]string `json:"fields"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// UploadOptions controls how Upload splits and sends an object. Zero values fall
+// back to DefaultPartSize, DefaultUploadConcurrency and DefaultPartRetries.
+// Progress, when set, is called after every part with the running byte count;
+// calls are serialized, so it needs no locking of its own, but a slow callback
+// holds up the other parts. Encryption overrides the service's encryption for
+// this upload.
+type UploadOptions struct {
+	PartSize    int64
+	Concurrency int
+	MaxRetries  int
+	ContentType string
+	Metadata    map[string]string
+	Progress    func(UploadProgress)
+	Encryption  *Encryption
+}
+
+// UploadProgress reports an uploaded part: its number and the total bytes
+// uploaded so far. Parts finish out of order, so PartNumber is not monotonic
+// while BytesUploaded is.
+type UploadProgress struct {
+	PartNumber    int64
+	BytesUploaded int64
+}
+
+// UploadOutput describes a finished upload. ContentSHA256 is the hex-encoded
+// SHA-256 digest of the whole object, while ChecksumSHA256 is the checksum S3
+// stored, which for multipart uploads is a checksum of the part checksums.
+type UploadOutput struct {
+	Key            string `json:"key"`
+	ETag           string `json:"etag"`
+	Size           int64  `json:"size"`
+	Parts          int    `json:"parts"`
+	ContentSHA256  string `json:"contentSha256"`
+	ChecksumSHA256 string `json:"checksumSha256,omitempty"`
+}
+
+// ListOptions controls List. With a Delimiter, keys that share a prefix up to the
+// next delimiter are grouped into a single ObjectInfo with IsPrefix set, as in a
+// directory listing. PageSize sets how many keys each request returns, at most 1000.
+type ListOptions struct {
+	Delimiter  string
+	StartAfter string
+	PageSize   int64
+}
+
+// ObjectInfo describes an object, or a common prefix when IsPrefix is set. List
+// fills in the key, size, ETag, last modified time and storage class; Head also
+// fills in the content type, checksum and user metadata.
+type ObjectInfo struct {
+	Key            string            `json:"key"`
+	IsPrefix       bool              `json:"isPrefix,omitempty"`
+	Size           int64             `json:"size"`
+	ETag           string            `json:"etag,omitempty"`
+	LastModified   time.Time         `json:"lastModified,omitempty"`
+	StorageClass   string            `json:"storageClass,omitempty"`
+	ContentType    string            `json:"contentType,omitempty"`
+	ChecksumSHA256 string            `json:"checksumSha256,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// DownloadOptions controls how Download fetches an object. Zero values fall back
+// to DefaultPartSize, DefaultUploadConcurrency and DefaultPartRetries. Offset
+// resumes an interrupted download: bytes before it are assumed to be written
+// already and are not fetched again. A resumed download must also pass the ETag
+// of the *DownloadError it resumes from, so that it only continues with the same
+// version of the object. Progress, when set, is called after every part with
+// the running byte count; calls are serialized, so it needs no locking of its
+// own, but a slow callback holds up the other parts. Encryption is only needed
+// for objects encrypted with a customer-provided key and defaults to the
+// service's.
+type DownloadOptions struct {
+	PartSize    int64
+	Concurrency int
+	MaxRetries  int
+	Offset      int64
+	ETag        string
+	Progress    func(int64)
+	Encryption  *Encryption
+}
+
+// DownloadOutput describes a finished download. Written counts only the bytes
+// fetched by this call, so it is Size minus the resume offset.
+type DownloadOutput struct {
+	Key     string `json:"key"`
+	ETag    string `json:"etag"`
+	Size    int64  `json:"size"`
+	Written int64  `json:"written"`
+}
+
+// PresignedMultipartUpload is returned to a client that uploads a large object
+// itself. The client PUTs part N of PartSize bytes (the last part may be shorter)
+// to Parts[N-1].URL, keeps the ETag header of every response and sends them back
+// to CompleteMultipartUpload.
+type PresignedMultipartUpload struct {
+	ID        int             `json:"id"`
+	Key       string          `json:"key"`
+	UploadID  string          `json:"uploadId"`
+	PartSize  int64           `json:"partSize"`
+	Parts     []PresignedPart `json:"parts"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+// PresignedPart is the URL for one part. Headers, set when the object is
+// encrypted with a customer-provided key, must be sent with the PUT.
+type PresignedPart struct {
+	PartNumber int64             `json:"partNumber"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// CompletedPart is the ETag a client received after uploading one part.
+type CompletedPart struct {
+	PartNumber int64  `json:"partNumber"`
+	ETag       string `json:"etag"`
 }
 
 type PresignedUrlResponse struct {
-	ID           int    `json:"id"`
-	PresignedUrl string `json:"presignedUrl"`
+	ID           int               `json:"id"`
+	Key          string            `json:"key"`
+	Method       string            `json:"method"`
+	PresignedUrl string            `json:"presignedUrl"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	ExpiresAt    time.Time         `json:"expiresAt"`
+}
+
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+
+	SettingCORS              = "cors"
+	SettingLifecycle         = "lifecycle"
+	SettingVersioning        = "versioning"
+	SettingPublicAccessBlock = "publicAccessBlock"
+
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// BucketConfig is the desired configuration of a bucket. Settings left at their
+// zero value (a nil slice, an empty Versioning or a nil PublicAccessBlock) are
+// not managed and are left as they are; a non-nil empty CORS or Lifecycle slice
+// removes every rule.
+type BucketConfig struct {
+	CORS              []CORSRule         `json:"cors,omitempty"`
+	Lifecycle         []LifecycleRule    `json:"lifecycle,omitempty"`
+	Versioning        string             `json:"versioning,omitempty"`
+	PublicAccessBlock *PublicAccessBlock `json:"publicAccessBlock,omitempty"`
+}
+
+// CORSRule allows browsers on AllowedOrigins to send AllowedMethods requests.
+// S3 applies the first rule that matches, so the order of rules matters.
+type CORSRule struct {
+	ID             string   `json:"id,omitempty"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+	AllowedMethods []string `json:"allowedMethods"`
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+	ExposeHeaders  []string `json:"exposeHeaders,omitempty"`
+	MaxAgeSeconds  int64    `json:"maxAgeSeconds,omitempty"`
+}
+
+// LifecycleRule expires objects under Prefix. Zero day counts leave the
+// corresponding action out of the rule, but every rule needs at least one.
+type LifecycleRule struct {
+	ID                                 string `json:"id"`
+	Prefix                             string `json:"prefix"`
+	Disabled                           bool   `json:"disabled,omitempty"`
+	ExpirationDays                     int64  `json:"expirationDays,omitempty"`
+	NoncurrentVersionExpirationDays    int64  `json:"noncurrentVersionExpirationDays,omitempty"`
+	AbortIncompleteMultipartUploadDays int64  `json:"abortIncompleteMultipartUploadDays,omitempty"`
+}
+
+type PublicAccessBlock struct {
+	BlockPublicAcls       bool `json:"blockPublicAcls"`
+	IgnorePublicAcls      bool `json:"ignorePublicAcls"`
+	BlockPublicPolicy     bool `json:"blockPublicPolicy"`
+	RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
+}
+
+// BucketPlan lists the changes ApplyBucketConfig makes, or would make in a dry
+// run, to bring a bucket to the desired BucketConfig.
+type BucketPlan struct {
+	Bucket  string         `json:"bucket"`
+	Changes []BucketChange `json:"changes"`
+}
+
+// BucketChange replaces the Current value of one setting with Desired. Action is
+// ActionCreate, ActionUpdate or ActionDelete.
+type BucketChange struct {
+	Setting string      `json:"setting"`
+	Action  string      `json:"action"`
+	Current interface{} `json:"current,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+const (
+	SSECustomerAlgorithm = "AES256"
+	SSECustomerKeySize   = 32
+)
+
+// Encryption selects how S3 encrypts objects at rest. The zero value sends no
+// encryption headers, so the bucket's default encryption applies. Algorithm is
+// AES256 for SSE-S3 or aws:kms for SSE-KMS, which may name a KMSKeyID (the AWS
+// managed key otherwise), bind a KMSContext that every later read must be
+// allowed to decrypt under, and use an S3 Bucket Key to cut KMS requests.
+// CustomerKey instead selects SSE-C: S3 encrypts with the given 256-bit key and
+// does not store it, so every read, copy and part upload must send it again.
+type Encryption struct {
+	Algorithm        string            `json:"algorithm,omitempty"`
+	KMSKeyID         string            `json:"kmsKeyId,omitempty"`
+	KMSContext       map[string]string `json:"kmsContext,omitempty"`
+	BucketKeyEnabled bool              `json:"bucketKeyEnabled,omitempty"`
+	CustomerKey      []byte            `json:"-"`
 }
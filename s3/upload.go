@@ -0,0 +1,350 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const partRetryBackoff = 200 * time.Millisecond
+
+type uploadPart struct {
+	number int64
+	data   []byte
+}
+
+// Upload stores everything read from body under key. Bodies that fit in a single
+// part are sent with one PutObject; larger ones use a multipart upload whose parts
+// are sent concurrently and retried individually. Every part carries a SHA-256
+// checksum that S3 verifies, and a failed multipart upload is aborted so no
+// incomplete parts are left behind.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the upload.
+//	key (string): The object key.
+//	body (io.Reader): The object contents.
+//	opts (UploadOptions): The part size, concurrency, retries, content type, metadata, progress callback and encryption.
+//
+// Returns:
+//
+//	(*UploadOutput, error): The key, ETag, size and checksums of the object, or an error if the upload failed.
+func (s *S3Service) Upload(ctx context.Context, key string, body io.Reader, opts UploadOptions) (*UploadOutput, error) {
+	if err := applyUploadDefaults(&opts); err != nil {
+		return nil, err
+	}
+
+	encryption, err := s.resolveEncryption(opts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	opts.Encryption = &encryption
+
+	digest := sha256.New()
+	reader := io.TeeReader(body, digest)
+
+	first, err := readPart(reader, opts.PartSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(first)) < opts.PartSize {
+		return s.putSingle(ctx, key, first, digest, opts)
+	}
+
+	return s.putMultipart(ctx, key, first, reader, digest, opts)
+}
+
+func applyUploadDefaults(opts *UploadOptions) error {
+	if opts.PartSize == 0 {
+		opts.PartSize = DefaultPartSize
+	}
+
+	if opts.PartSize < MinPartSize {
+		return fmt.Errorf("part size must be at least %d bytes", MinPartSize)
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultUploadConcurrency
+	}
+
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = DefaultPartRetries
+	}
+
+	return nil
+}
+
+// readPart reads up to size bytes. A short read means the body is exhausted.
+func readPart(r io.Reader, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+func checksumSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (s *S3Service) putSingle(ctx context.Context, key string, data []byte, digest hash.Hash, opts UploadOptions) (*UploadOutput, error) {
+	input := &s3.PutObjectInput{
+		Bucket:         aws.String(s.bucketName()),
+		Key:            aws.String(key),
+		Body:           bytes.NewReader(data),
+		ContentLength:  aws.Int64(int64(len(data))),
+		ChecksumSHA256: aws.String(checksumSHA256(data)),
+	}
+
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+
+	if len(opts.Metadata) > 0 {
+		input.Metadata = aws.StringMap(opts.Metadata)
+	}
+
+	opts.Encryption.params().applyPut(input)
+
+	output, err := s.api().PutObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(UploadProgress{PartNumber: 1, BytesUploaded: int64(len(data))})
+	}
+
+	return &UploadOutput{
+		Key:            key,
+		ETag:           aws.StringValue(output.ETag),
+		Size:           int64(len(data)),
+		Parts:          1,
+		ContentSHA256:  hex.EncodeToString(digest.Sum(nil)),
+		ChecksumSHA256: aws.StringValue(output.ChecksumSHA256),
+	}, nil
+}
+
+func (s *S3Service) putMultipart(ctx context.Context, key string, first []byte, body io.Reader, digest hash.Hash, opts UploadOptions) (*UploadOutput, error) {
+	createInput := &s3.CreateMultipartUploadInput{
+		Bucket:            aws.String(s.bucketName()),
+		Key:               aws.String(key),
+		ChecksumAlgorithm: aws.String(s3.ChecksumAlgorithmSha256),
+	}
+
+	if opts.ContentType != "" {
+		createInput.ContentType = aws.String(opts.ContentType)
+	}
+
+	if len(opts.Metadata) > 0 {
+		createInput.Metadata = aws.StringMap(opts.Metadata)
+	}
+
+	opts.Encryption.params().applyCreateMultipart(createInput)
+
+	created, err := s.api().CreateMultipartUploadWithContext(ctx, createInput)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadID := aws.StringValue(created.UploadId)
+
+	parts, size, err := s.uploadParts(ctx, key, uploadID, first, body, opts)
+	if err != nil {
+		s.abortUpload(key, uploadID)
+		return nil, err
+	}
+
+	completeInput := &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucketName()),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}
+
+	opts.Encryption.params().applyComplete(completeInput)
+
+	completed, err := s.api().CompleteMultipartUploadWithContext(ctx, completeInput)
+	if err != nil {
+		s.abortUpload(key, uploadID)
+		return nil, err
+	}
+
+	return &UploadOutput{
+		Key:            key,
+		ETag:           aws.StringValue(completed.ETag),
+		Size:           size,
+		Parts:          len(parts),
+		ContentSHA256:  hex.EncodeToString(digest.Sum(nil)),
+		ChecksumSHA256: aws.StringValue(completed.ChecksumSHA256),
+	}, nil
+}
+
+// uploadParts reads body part by part and sends the parts with opts.Concurrency
+// workers. It stops at the first part that fails after all of its retries.
+func (s *S3Service) uploadParts(ctx context.Context, key string, uploadID string, first []byte, body io.Reader, opts UploadOptions) ([]*s3.CompletedPart, int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		parts     []*s3.CompletedPart
+		uploaded  int64
+		uploadErr error
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		if uploadErr == nil {
+			uploadErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	queue := make(chan uploadPart)
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for part := range queue {
+				completed, err := s.uploadPart(ctx, key, uploadID, part, opts.Encryption.params(), opts.MaxRetries)
+				if err != nil {
+					fail(err)
+					continue
+				}
+
+				mu.Lock()
+				parts = append(parts, completed)
+				uploaded += int64(len(part.data))
+				if opts.Progress != nil {
+					opts.Progress(UploadProgress{PartNumber: part.number, BytesUploaded: uploaded})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var size int64
+	data := first
+
+	for number := int64(1); len(data) > 0; number++ {
+		if number > MaxUploadParts {
+			fail(fmt.Errorf("upload exceeds %d parts, increase the part size", MaxUploadParts))
+			break
+		}
+
+		size += int64(len(data))
+
+		select {
+		case queue <- uploadPart{number: number, data: data}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		next, err := readPart(body, opts.PartSize)
+		if err != nil {
+			fail(err)
+			break
+		}
+		data = next
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if uploadErr == nil && ctx.Err() != nil {
+		uploadErr = ctx.Err()
+	}
+
+	if uploadErr != nil {
+		return nil, 0, uploadErr
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return aws.Int64Value(parts[i].PartNumber) < aws.Int64Value(parts[j].PartNumber)
+	})
+
+	return parts, size, nil
+}
+
+func (s *S3Service) uploadPart(ctx context.Context, key string, uploadID string, part uploadPart, sse sseParams, maxRetries int) (*s3.CompletedPart, error) {
+	checksum := checksumSHA256(part.data)
+	backoff := partRetryBackoff
+
+	var err error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		input := &s3.UploadPartInput{
+			Bucket:         aws.String(s.bucketName()),
+			Key:            aws.String(key),
+			UploadId:       aws.String(uploadID),
+			PartNumber:     aws.Int64(part.number),
+			Body:           bytes.NewReader(part.data),
+			ContentLength:  aws.Int64(int64(len(part.data))),
+			ChecksumSHA256: aws.String(checksum),
+		}
+
+		sse.applyUploadPart(input)
+
+		var output *s3.UploadPartOutput
+		output, err = s.api().UploadPartWithContext(ctx, input)
+		if err == nil {
+			return &s3.CompletedPart{
+				PartNumber:     aws.Int64(part.number),
+				ETag:           output.ETag,
+				ChecksumSHA256: aws.String(checksum),
+			}, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("part %d failed after %d retries: %v", part.number, maxRetries, err)
+}
+
+// abortUpload discards the parts of a failed multipart upload. It uses a fresh
+// context because the upload's own context is often the reason it failed.
+func (s *S3Service) abortUpload(key string, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s.api().AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucketName()),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+}
@@ -0,0 +1,129 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpload(t *testing.T) {
+	t.Run("Small body uses a single put", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		body := []byte("hello")
+
+		mockClient.On("PutObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.PutObjectInput) bool {
+			return aws.StringValue(input.Key) == "app.apk" &&
+				aws.StringValue(input.ChecksumSHA256) == checksumSHA256(body) &&
+				aws.StringValue(input.ContentType) == "application/vnd.android.package-archive"
+		})).Return(&s3.PutObjectOutput{ETag: aws.String("\"etag\"")}, nil)
+
+		output, err := service.Upload(context.Background(), "app.apk", bytes.NewReader(body), UploadOptions{
+			ContentType: "application/vnd.android.package-archive",
+		})
+
+		sum := sha256.Sum256(body)
+		assert.NoError(t, err)
+		assert.Equal(t, "\"etag\"", output.ETag)
+		assert.Equal(t, int64(5), output.Size)
+		assert.Equal(t, 1, output.Parts)
+		assert.Equal(t, hex.EncodeToString(sum[:]), output.ContentSHA256)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Large body uses parallel parts", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		body := bytes.Repeat([]byte("a"), int(MinPartSize)*2+10)
+
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateMultipartUploadInput) bool {
+			return aws.StringValue(input.ChecksumAlgorithm) == s3.ChecksumAlgorithmSha256
+		})).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+		mockClient.On("UploadPartWithContext", mock.Anything, mock.AnythingOfType("*s3.UploadPartInput")).Return(&s3.UploadPartOutput{ETag: aws.String("\"part\"")}, nil)
+		mockClient.On("CompleteMultipartUploadWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CompleteMultipartUploadInput) bool {
+			parts := input.MultipartUpload.Parts
+			return len(parts) == 3 &&
+				aws.Int64Value(parts[0].PartNumber) == 1 &&
+				aws.Int64Value(parts[2].PartNumber) == 3
+		})).Return(&s3.CompleteMultipartUploadOutput{ETag: aws.String("\"final-3\"")}, nil)
+
+		var mu sync.Mutex
+		var reported []int64
+
+		output, err := service.Upload(context.Background(), "app.apk", bytes.NewReader(body), UploadOptions{
+			PartSize:    MinPartSize,
+			Concurrency: 2,
+			Progress: func(progress UploadProgress) {
+				mu.Lock()
+				reported = append(reported, progress.BytesUploaded)
+				mu.Unlock()
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "\"final-3\"", output.ETag)
+		assert.Equal(t, int64(len(body)), output.Size)
+		assert.Equal(t, 3, output.Parts)
+		assert.Len(t, reported, 3)
+		assert.Contains(t, reported, int64(len(body)))
+		mockClient.AssertNumberOfCalls(t, "UploadPartWithContext", 3)
+		mockClient.AssertNotCalled(t, "AbortMultipartUploadWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed part is retried", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		body := bytes.Repeat([]byte("b"), int(MinPartSize)+1)
+
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.Anything).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+		mockClient.On("UploadPartWithContext", mock.Anything, mock.MatchedBy(func(input *s3.UploadPartInput) bool {
+			return aws.Int64Value(input.PartNumber) == 1
+		})).Return((*s3.UploadPartOutput)(nil), errors.New("connection reset")).Once()
+		mockClient.On("UploadPartWithContext", mock.Anything, mock.Anything).Return(&s3.UploadPartOutput{ETag: aws.String("\"part\"")}, nil)
+		mockClient.On("CompleteMultipartUploadWithContext", mock.Anything, mock.Anything).Return(&s3.CompleteMultipartUploadOutput{ETag: aws.String("\"final-2\"")}, nil)
+
+		output, err := service.Upload(context.Background(), "app.apk", bytes.NewReader(body), UploadOptions{PartSize: MinPartSize})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, output.Parts)
+		mockClient.AssertNumberOfCalls(t, "UploadPartWithContext", 3)
+	})
+
+	t.Run("Aborts when a part keeps failing", func(t *testing.T) {
+		service, mockClient := mockNewS3ServiceWithClient("test-bucket")
+		body := bytes.Repeat([]byte("c"), int(MinPartSize)+1)
+
+		mockClient.On("CreateMultipartUploadWithContext", mock.Anything, mock.Anything).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+		mockClient.On("UploadPartWithContext", mock.Anything, mock.Anything).Return((*s3.UploadPartOutput)(nil), errors.New("access denied"))
+		mockClient.On("AbortMultipartUploadWithContext", mock.Anything, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String("test-bucket"),
+			Key:      aws.String("app.apk"),
+			UploadId: aws.String("upload-1"),
+		}).Return(&s3.AbortMultipartUploadOutput{}, nil)
+
+		output, err := service.Upload(context.Background(), "app.apk", bytes.NewReader(body), UploadOptions{
+			PartSize:   MinPartSize,
+			MaxRetries: 1,
+		})
+
+		assert.Nil(t, output)
+		assert.Error(t, err)
+		mockClient.AssertCalled(t, "AbortMultipartUploadWithContext", mock.Anything, mock.Anything)
+		mockClient.AssertNotCalled(t, "CompleteMultipartUploadWithContext", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Part size below minimum", func(t *testing.T) {
+		service, _ := mockNewS3ServiceWithClient("test-bucket")
+
+		output, err := service.Upload(context.Background(), "app.apk", bytes.NewReader(nil), UploadOptions{PartSize: 1024})
+
+		assert.Nil(t, output)
+		assert.Error(t, err)
+	})
+}
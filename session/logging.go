@@ -0,0 +1,160 @@
+package session
+
+import (
+	"log/slog"
+	"time"
+
+	"go_aws_services/internal/observe"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// LogHandlerName names the handler LogHandler returns, so it can be replaced.
+const LogHandlerName = "go_aws_services.Log"
+
+// Redacted replaces values that LogOptions does not allow to be logged.
+const Redacted = "[REDACTED]"
+
+// LogOptions controls what LogHandler writes. Key values and item bodies are
+// redacted unless they are enabled, since they usually hold customer data.
+type LogOptions struct {
+	// Level is the level of successful calls. Failed calls are logged at
+	// slog.LevelError.
+	Level slog.Level
+	// LogKeys logs DynamoDB primary keys and pagination keys, and S3 object keys.
+	LogKeys bool
+	// LogItems logs DynamoDB items and expression attribute values.
+	LogItems bool
+}
+
+// LogHandler returns an SDK handler that logs one record per call once it has
+// completed, after any retries. Every record has the service, operation,
+// table or bucket, latency, retry count, AWS request ID and, for failed calls,
+// the error code. DynamoDB calls also log the consumed capacity when it was
+// requested.
+//
+// Parameters:
+//
+//	logger (*slog.Logger): The logger to write to.
+//	opts (LogOptions): The level and redaction settings.
+//
+// Returns:
+//
+//	request.NamedHandler: A handler for the Complete list of a session or client.
+func LogHandler(logger *slog.Logger, opts LogOptions) request.NamedHandler {
+	return request.NamedHandler{
+		Name: LogHandlerName,
+		Fn: func(r *request.Request) {
+			level := opts.Level
+			if r.Error != nil {
+				level = slog.LevelError
+			}
+
+			ctx := r.Context()
+			if !logger.Enabled(ctx, level) {
+				return
+			}
+
+			logger.LogAttrs(ctx, level, "aws call", callAttrs(r, opts)...)
+		},
+	}
+}
+
+func callAttrs(r *request.Request, opts LogOptions) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("service", r.ClientInfo.ServiceName),
+		slog.String("operation", r.Operation.Name),
+	}
+
+	if table, ok := observe.Field[*string](r.Params, "TableName"); ok {
+		attrs = append(attrs, slog.String("table", aws.StringValue(table)))
+	}
+
+	if bucket, ok := observe.Field[*string](r.Params, "Bucket"); ok {
+		attrs = append(attrs, slog.String("bucket", aws.StringValue(bucket)))
+	}
+
+	if key, ok := observe.Field[*string](r.Params, "Key"); ok {
+		attrs = append(attrs, slog.String("key", redact(aws.StringValue(key), opts.LogKeys)))
+	}
+
+	for _, f := range []struct{ name, attr string }{
+		{"Key", "key"},
+		{"ExclusiveStartKey", "exclusiveStartKey"},
+	} {
+		if av, ok := observe.Field[map[string]*dynamodb.AttributeValue](r.Params, f.name); ok {
+			attrs = append(attrs, itemAttr(f.attr, av, opts.LogKeys))
+		}
+	}
+
+	for _, f := range []struct{ name, attr string }{
+		{"Item", "item"},
+		{"ExpressionAttributeValues", "values"},
+	} {
+		if av, ok := observe.Field[map[string]*dynamodb.AttributeValue](r.Params, f.name); ok {
+			attrs = append(attrs, itemAttr(f.attr, av, opts.LogItems))
+		}
+	}
+
+	if r.Error == nil {
+		if av, ok := observe.Field[map[string]*dynamodb.AttributeValue](r.Data, "Item"); ok {
+			attrs = append(attrs, itemAttr("result", av, opts.LogItems))
+		}
+
+		if av, ok := observe.Field[map[string]*dynamodb.AttributeValue](r.Data, "LastEvaluatedKey"); ok {
+			attrs = append(attrs, itemAttr("lastEvaluatedKey", av, opts.LogKeys))
+		}
+
+		if consumed := observe.ConsumedCapacity(r.Data); len(consumed) > 0 {
+			var units float64
+			for _, c := range consumed {
+				units += aws.Float64Value(c.CapacityUnits)
+			}
+			attrs = append(attrs, slog.Float64("consumedCapacity", units))
+		}
+	}
+
+	attrs = append(attrs,
+		slog.Duration("latency", time.Since(r.Time)),
+		slog.Int("retries", r.RetryCount),
+	)
+
+	if r.RequestID != "" {
+		attrs = append(attrs, slog.String("requestId", r.RequestID))
+	}
+
+	if r.Error != nil {
+		code := "Unknown"
+		if aerr, ok := r.Error.(awserr.Error); ok {
+			code = aerr.Code()
+		}
+		attrs = append(attrs, slog.String("errorCode", code), slog.String("error", r.Error.Error()))
+	}
+
+	return attrs
+}
+
+func redact(value string, allowed bool) string {
+	if !allowed {
+		return Redacted
+	}
+
+	return value
+}
+
+func itemAttr(name string, av map[string]*dynamodb.AttributeValue, allowed bool) slog.Attr {
+	if !allowed {
+		return slog.String(name, Redacted)
+	}
+
+	var item map[string]interface{}
+	if err := dynamodbattribute.UnmarshalMap(av, &item); err != nil {
+		return slog.String(name, err.Error())
+	}
+
+	return slog.Any(name, item)
+}
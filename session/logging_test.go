@@ -0,0 +1,132 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logCall runs the handler for r and returns the record it wrote, if any.
+func logCall(t *testing.T, opts LogOptions, r *request.Request) map[string]interface{} {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	LogHandler(logger, opts).Fn(r)
+
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+func getItemRequest() *request.Request {
+	return &request.Request{
+		ClientInfo: metadata.ClientInfo{ServiceName: "dynamodb"},
+		Operation:  &request.Operation{Name: "GetItem"},
+		Params: &dynamodb.GetItemInput{
+			TableName: aws.String("users"),
+			Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String("123")}},
+		},
+		Data: &dynamodb.GetItemOutput{
+			Item:             map[string]*dynamodb.AttributeValue{"id": {S: aws.String("123")}, "ssn": {S: aws.String("123-45-6789")}},
+			ConsumedCapacity: &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(0.5)},
+		},
+		Time:       time.Now().Add(-time.Second),
+		RetryCount: 2,
+		RequestID:  "REQ123",
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	t.Run("Logs call details and redacts by default", func(t *testing.T) {
+		record := logCall(t, LogOptions{}, getItemRequest())
+
+		assert.Equal(t, "INFO", record["level"])
+		assert.Equal(t, "aws call", record["msg"])
+		assert.Equal(t, "dynamodb", record["service"])
+		assert.Equal(t, "GetItem", record["operation"])
+		assert.Equal(t, "users", record["table"])
+		assert.Equal(t, Redacted, record["key"])
+		assert.Equal(t, Redacted, record["result"])
+		assert.Equal(t, 0.5, record["consumedCapacity"])
+		assert.Equal(t, float64(2), record["retries"])
+		assert.Equal(t, "REQ123", record["requestId"])
+		assert.GreaterOrEqual(t, record["latency"], float64(time.Second))
+		assert.NotContains(t, record, "errorCode")
+	})
+
+	t.Run("Logs keys and items when allowed", func(t *testing.T) {
+		record := logCall(t, LogOptions{LogKeys: true, LogItems: true}, getItemRequest())
+
+		assert.Equal(t, map[string]interface{}{"id": "123"}, record["key"])
+		assert.Equal(t, map[string]interface{}{"id": "123", "ssn": "123-45-6789"}, record["result"])
+	})
+
+	t.Run("Redacts keys and items independently", func(t *testing.T) {
+		record := logCall(t, LogOptions{LogKeys: true}, getItemRequest())
+
+		assert.Equal(t, map[string]interface{}{"id": "123"}, record["key"])
+		assert.Equal(t, Redacted, record["result"])
+	})
+
+	t.Run("Logs failures at error level with the error code", func(t *testing.T) {
+		r := getItemRequest()
+		r.Error = awserr.New("ProvisionedThroughputExceededException", "slow down", nil)
+
+		record := logCall(t, LogOptions{}, r)
+
+		assert.Equal(t, "ERROR", record["level"])
+		assert.Equal(t, "ProvisionedThroughputExceededException", record["errorCode"])
+		assert.NotContains(t, record, "result")
+		assert.NotContains(t, record, "consumedCapacity")
+	})
+
+	t.Run("Logs bucket and redacted object key", func(t *testing.T) {
+		record := logCall(t, LogOptions{}, &request.Request{
+			ClientInfo: metadata.ClientInfo{ServiceName: "s3"},
+			Operation:  &request.Operation{Name: "PutObject"},
+			Params:     &s3.PutObjectInput{Bucket: aws.String("uploads"), Key: aws.String("1-2-3.apk")},
+			Data:       &s3.PutObjectOutput{},
+			Time:       time.Now(),
+		})
+
+		assert.Equal(t, "uploads", record["bucket"])
+		assert.Equal(t, Redacted, record["key"])
+		assert.NotContains(t, record, "table")
+		assert.NotContains(t, record, "requestId")
+	})
+
+	t.Run("Sums capacity of batch calls", func(t *testing.T) {
+		record := logCall(t, LogOptions{}, &request.Request{
+			Operation: &request.Operation{Name: "BatchWriteItem"},
+			Params:    &dynamodb.BatchWriteItemInput{},
+			Data: &dynamodb.BatchWriteItemOutput{ConsumedCapacity: []*dynamodb.ConsumedCapacity{
+				{CapacityUnits: aws.Float64(2)},
+				{CapacityUnits: aws.Float64(3)},
+			}},
+			Time: time.Now(),
+		})
+
+		assert.Equal(t, float64(5), record["consumedCapacity"])
+	})
+
+	t.Run("Skips disabled levels", func(t *testing.T) {
+		assert.Nil(t, logCall(t, LogOptions{Level: slog.LevelDebug - 1}, getItemRequest()))
+	})
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// ErrorClass selects a group of errors a RetryPolicy retries. Classes can be
+// combined with |.
+type ErrorClass int
+
+const (
+	// RetryThrottling retries throttling errors such as
+	// ProvisionedThroughputExceededException, ThrottlingException and HTTP 429.
+	RetryThrottling ErrorClass = 1 << iota
+	// RetryTransient retries 5xx responses, timeouts and connection errors.
+	RetryTransient
+	// RetryExpiredCredentials retries requests rejected because the credentials
+	// expired, after the SDK has refreshed them.
+	RetryExpiredCredentials
+)
+
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = 50 * time.Millisecond
+	DefaultMaxDelay    = 20 * time.Second
+)
+
+// jitter returns a random duration in [0, n]. Tests replace it to get
+// predictable delays.
+var jitter = func(n int64) int64 {
+	return rand.Int64N(n + 1)
+}
+
+// RetryPolicy describes how failed requests are retried. Delays grow
+// exponentially from BaseDelay up to MaxDelay and are drawn with full jitter, so
+// clients throttled together do not retry together. Zero fields fall back to
+// DefaultMaxAttempts, DefaultBaseDelay, DefaultMaxDelay and
+// RetryThrottling|RetryTransient.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one. 1
+	// disables retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Retryable   ErrorClass
+	// RetryableCodes lists additional error codes to retry.
+	RetryableCodes []string
+}
+
+// DefaultRetryPolicy returns the policy zero fields fall back to.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		Retryable:   RetryThrottling | RetryTransient,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaults.BaseDelay
+	}
+
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaults.MaxDelay
+	}
+
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+
+	if p.Retryable == 0 {
+		p.Retryable = defaults.Retryable
+	}
+
+	return p
+}
+
+// Retryer returns the policy as an SDK retryer, for use in an aws.Config or on a
+// service client.
+func (p RetryPolicy) Retryer() request.Retryer {
+	return retryer{policy: p.withDefaults()}
+}
+
+type retryer struct {
+	policy RetryPolicy
+}
+
+func (r retryer) MaxRetries() int {
+	return r.policy.MaxAttempts - 1
+}
+
+// RetryRules returns a delay drawn uniformly from zero to the exponential
+// backoff for the attempt, capped at MaxDelay.
+func (r retryer) RetryRules(req *request.Request) time.Duration {
+	backoff := r.policy.MaxDelay
+	if req.RetryCount < 62 {
+		if scaled := r.policy.BaseDelay << req.RetryCount; scaled > 0 && scaled < backoff {
+			backoff = scaled
+		}
+	}
+
+	return time.Duration(jitter(int64(backoff)))
+}
+
+// ShouldRetry reports whether the error of req belongs to one of the policy's
+// classes. A decision already made by another handler is kept.
+func (r retryer) ShouldRetry(req *request.Request) bool {
+	if req.Retryable != nil {
+		return *req.Retryable
+	}
+
+	if req.Error == nil {
+		return false
+	}
+
+	classes := r.policy.Retryable
+
+	if classes&RetryThrottling != 0 && req.IsErrorThrottle() {
+		return true
+	}
+
+	// The SDK counts expired credentials as retryable, but they have a class of
+	// their own here.
+	if classes&RetryTransient != 0 && !req.IsErrorExpired() {
+		if req.IsErrorRetryable() {
+			return true
+		}
+
+		if req.HTTPResponse != nil && req.HTTPResponse.StatusCode >= http.StatusInternalServerError && req.HTTPResponse.StatusCode != http.StatusNotImplemented {
+			return true
+		}
+	}
+
+	if classes&RetryExpiredCredentials != 0 && req.IsErrorExpired() {
+		return true
+	}
+
+	if aerr, ok := req.Error.(awserr.Error); ok {
+		return slices.Contains(r.policy.RetryableCodes, aerr.Code())
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func failedRequest(err error, status int) *request.Request {
+	return &request.Request{Error: err, HTTPResponse: &http.Response{StatusCode: status}}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	retryer := RetryPolicy{}.Retryer()
+
+	assert.Equal(t, DefaultMaxAttempts-1, retryer.MaxRetries())
+	assert.Equal(t, 0, RetryPolicy{MaxAttempts: 1}.Retryer().MaxRetries())
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	throttled := failedRequest(awserr.New("ProvisionedThroughputExceededException", "slow down", nil), http.StatusBadRequest)
+	unavailable := failedRequest(awserr.New("ServiceUnavailable", "", nil), http.StatusServiceUnavailable)
+	internal := failedRequest(awserr.New("InternalServerError", "", nil), http.StatusInternalServerError)
+	expired := failedRequest(awserr.New("ExpiredTokenException", "", nil), http.StatusBadRequest)
+	conditional := failedRequest(awserr.New("TransactionConflictException", "", nil), http.StatusBadRequest)
+	validation := failedRequest(awserr.New("ValidationException", "", nil), http.StatusBadRequest)
+
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		request *request.Request
+		want    bool
+	}{
+		{"Throttling by default", RetryPolicy{}, throttled, true},
+		{"5xx by default", RetryPolicy{}, internal, true},
+		{"Validation errors never", RetryPolicy{}, validation, false},
+		{"Throttling not selected", RetryPolicy{Retryable: RetryTransient}, throttled, false},
+		{"Transient not selected", RetryPolicy{Retryable: RetryThrottling}, internal, false},
+		{"Throttling status without transient", RetryPolicy{Retryable: RetryThrottling}, unavailable, true},
+		{"Expired credentials not by default", RetryPolicy{}, expired, false},
+		{"Expired credentials selected", RetryPolicy{Retryable: RetryExpiredCredentials}, expired, true},
+		{"Additional code", RetryPolicy{RetryableCodes: []string{"TransactionConflictException"}}, conditional, true},
+		{"Decision already made", RetryPolicy{}, &request.Request{Error: errors.New("x"), Retryable: aws.Bool(true)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Retryer().ShouldRetry(tt.request))
+		})
+	}
+}
+
+func TestRetryPolicyRetryRules(t *testing.T) {
+	original := jitter
+	jitter = func(n int64) int64 { return n }
+	defer func() { jitter = original }()
+
+	retryer := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 100 * time.Millisecond}.Retryer()
+
+	delays := make([]time.Duration, 0, 6)
+	for _, count := range []int{0, 1, 2, 3, 4, 100} {
+		delays = append(delays, retryer.RetryRules(&request.Request{RetryCount: count}))
+	}
+
+	assert.Equal(t, []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		80 * time.Millisecond,
+		100 * time.Millisecond,
+		100 * time.Millisecond,
+	}, delays)
+}
+
+func TestRetryPolicyFullJitter(t *testing.T) {
+	retryer := RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}.Retryer()
+
+	for i := 0; i < 100; i++ {
+		delay := retryer.RetryRules(&request.Request{RetryCount: 3})
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, time.Millisecond)
+	}
+}
+
+func TestSessionConfiguration(t *testing.T) {
+	originalNewSession := newSession
+	defer func() {
+		newSession = originalNewSession
+		sess, once, retryPolicy, logHandler, installers = nil, sync.Once{}, nil, nil, nil
+	}()
+	sess, once, retryPolicy, logHandler, installers = nil, sync.Once{}, nil, nil, nil
+
+	var cfg *aws.Config
+	newSession = func(cfgs ...*aws.Config) (*session.Session, error) {
+		cfg = cfgs[0]
+		return &session.Session{}, nil
+	}
+
+	require.NoError(t, SetRetryPolicy(RetryPolicy{MaxAttempts: 5}))
+	require.NoError(t, SetLogger(slog.Default(), LogOptions{}))
+	require.NoError(t, AddHandlers(func(handlers *request.Handlers) {
+		handlers.Send.PushBack(func(*request.Request) {})
+	}))
+
+	created := GetAWSSession()
+
+	require.NotNil(t, cfg.Retryer)
+	assert.Equal(t, 4, cfg.Retryer.(request.Retryer).MaxRetries())
+	assert.Equal(t, 1, created.Handlers.Complete.Len())
+	assert.Equal(t, 1, created.Handlers.Send.Len())
+	assert.Equal(t, ErrSessionCreated, SetRetryPolicy(RetryPolicy{}))
+	assert.Equal(t, ErrSessionCreated, SetLogger(slog.Default(), LogOptions{}))
+	assert.Equal(t, ErrSessionCreated, AddHandlers(func(*request.Handlers) {}))
+}
+
+func TestSetLoggerNil(t *testing.T) {
+	originalNewSession := newSession
+	defer func() {
+		newSession = originalNewSession
+		sess, once, retryPolicy, logHandler, installers = nil, sync.Once{}, nil, nil, nil
+	}()
+	sess, once, retryPolicy, logHandler, installers = nil, sync.Once{}, nil, nil, nil
+
+	newSession = func(cfgs ...*aws.Config) (*session.Session, error) {
+		return &session.Session{}, nil
+	}
+
+	require.NoError(t, SetLogger(slog.Default(), LogOptions{}))
+	require.NoError(t, SetLogger(nil, LogOptions{}))
+
+	assert.Nil(t, logHandler)
+	assert.Equal(t, 0, GetAWSSession().Handlers.Complete.Len())
+}
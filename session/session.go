@@ -1,31 +1,111 @@
 package session
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
 var (
-	sess *session.Session
-	once sync.Once
+	sess        *session.Session
+	once        sync.Once
+	mu          sync.Mutex
+	retryPolicy *RetryPolicy
+	logHandler  *request.NamedHandler
+	installers  []func(*request.Handlers)
 )
 
+var ErrSessionCreated = errors.New("the AWS session has already been created")
+
 var newSession = session.NewSession // função auxiliar para criar a sessão
 
 func initAWSSession() {
-	var err error
-	sess, err = newSession(&aws.Config{
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg := &aws.Config{
 		Region: aws.String("us-east-1"),
-	})
+	}
+	if retryPolicy != nil {
+		cfg = request.WithRetryer(cfg, retryPolicy.Retryer())
+	}
+
+	var err error
+	sess, err = newSession(cfg)
 	if err != nil {
 		panic(fmt.Sprintf("failed to create session: %v", err))
 	}
+
+	if logHandler != nil {
+		sess.Handlers.Complete.PushBackNamed(*logHandler)
+	}
+
+	for _, install := range installers {
+		install(&sess.Handlers)
+	}
+}
+
+// SetRetryPolicy sets the retry policy of every client created from the shared
+// session. Clients can still override it. It must be called before the first
+// call to GetAWSSession and returns ErrSessionCreated afterwards.
+func SetRetryPolicy(policy RetryPolicy) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sess != nil {
+		return ErrSessionCreated
+	}
+
+	retryPolicy = &policy
+
+	return nil
+}
+
+// SetLogger logs every call of every client created from the shared session with
+// LogHandler. A nil logger turns logging off again. It must be called before the
+// first call to GetAWSSession and returns ErrSessionCreated afterwards.
+func SetLogger(logger *slog.Logger, opts LogOptions) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sess != nil {
+		return ErrSessionCreated
+	}
+
+	if logger == nil {
+		logHandler = nil
+		return nil
+	}
+
+	handler := LogHandler(logger, opts)
+	logHandler = &handler
+
+	return nil
 }
 
 func GetAWSSession() *session.Session {
 	once.Do(initAWSSession)
 	return sess
 }
+
+// AddHandlers registers install to add handlers to the shared session, which
+// every client created from it inherits, e.g. the Install method of a
+// telemetry.Instrumentation. It must be called before the first call to
+// GetAWSSession and returns ErrSessionCreated afterwards.
+func AddHandlers(install func(*request.Handlers)) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sess != nil {
+		return ErrSessionCreated
+	}
+
+	installers = append(installers, install)
+
+	return nil
+}
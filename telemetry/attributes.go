@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"go_aws_services/internal/observe"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+var rpcSystemAWS = semconv.RPCSystemKey.String("aws-api")
+
+// requestAttributes describes a call before it is sent, following the semantic
+// conventions for the AWS SDK, DynamoDB and S3. Object keys are only included
+// when objectKeys is set.
+func requestAttributes(r *request.Request, objectKeys bool) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		rpcSystemAWS,
+		semconv.RPCService(r.ClientInfo.ServiceID),
+		semconv.RPCMethod(r.Operation.Name),
+	}
+
+	if r.Config.Region != nil {
+		attrs = append(attrs, semconv.CloudRegion(*r.Config.Region))
+	}
+
+	if tables := observe.TableNames(r.Params); len(tables) > 0 {
+		attrs = append(attrs,
+			semconv.DBSystemNameAWSDynamoDB,
+			semconv.DBOperationName(r.Operation.Name),
+			semconv.AWSDynamoDBTableNames(tables...))
+	}
+
+	if v, ok := observe.Field[*string](r.Params, "IndexName"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBIndexName(*v))
+	}
+	if v, ok := observe.Field[*bool](r.Params, "ConsistentRead"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBConsistentRead(*v))
+	}
+	if v, ok := observe.Field[*int64](r.Params, "Limit"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBLimit(int(*v)))
+	}
+	if v, ok := observe.Field[*string](r.Params, "ProjectionExpression"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBProjection(*v))
+	}
+	if v, ok := observe.Field[*string](r.Params, "Select"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBSelect(*v))
+	}
+	if v, ok := observe.Field[*bool](r.Params, "ScanIndexForward"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBScanForward(*v))
+	}
+	if v, ok := observe.Field[*int64](r.Params, "Segment"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBSegment(int(*v)))
+	}
+	if v, ok := observe.Field[*int64](r.Params, "TotalSegments"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBTotalSegments(int(*v)))
+	}
+
+	if v, ok := observe.Field[*string](r.Params, "Bucket"); ok {
+		attrs = append(attrs, semconv.AWSS3Bucket(*v))
+	}
+	if v, ok := observe.Field[*string](r.Params, "Key"); ok && objectKeys {
+		attrs = append(attrs, semconv.AWSS3Key(*v))
+	}
+	if v, ok := observe.Field[*string](r.Params, "UploadId"); ok {
+		attrs = append(attrs, semconv.AWSS3UploadID(*v))
+	}
+	if v, ok := observe.Field[*int64](r.Params, "PartNumber"); ok {
+		attrs = append(attrs, semconv.AWSS3PartNumber(int(*v)))
+	}
+	if v, ok := observe.Field[*string](r.Params, "CopySource"); ok && objectKeys {
+		attrs = append(attrs, semconv.AWSS3CopySource(*v))
+	}
+
+	return attrs
+}
+
+// responseAttributes describes the result of a successful DynamoDB call: the
+// items it returned or scanned and the capacity it consumed.
+func responseAttributes(r *request.Request) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+
+	if v, ok := observe.Field[*int64](r.Data, "Count"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBCount(int(*v)))
+	}
+	if v, ok := observe.Field[*int64](r.Data, "ScannedCount"); ok {
+		attrs = append(attrs, semconv.AWSDynamoDBScannedCount(int(*v)))
+	}
+
+	var capacity []*dynamodb.ConsumedCapacity
+	if v, ok := observe.Field[*dynamodb.ConsumedCapacity](r.Data, "ConsumedCapacity"); ok {
+		capacity = append(capacity, v)
+	}
+	if v, ok := observe.Field[[]*dynamodb.ConsumedCapacity](r.Data, "ConsumedCapacity"); ok {
+		capacity = append(capacity, v...)
+	}
+
+	if len(capacity) > 0 {
+		encoded := make([]string, 0, len(capacity))
+		for _, c := range capacity {
+			// Encoded the way DynamoDB returned it, without the unset fields.
+			if b, err := jsonutil.BuildJSON(c); err == nil {
+				encoded = append(encoded, string(b))
+			}
+		}
+		attrs = append(attrs, semconv.AWSDynamoDBConsumedCapacity(encoded...))
+	}
+
+	return attrs
+}
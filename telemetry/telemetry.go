@@ -0,0 +1,187 @@
+package telemetry
+
+import (
+	"time"
+
+	"go_aws_services/internal/observe"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracerProvider sets the provider spans are created with. It defaults to
+// the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider metrics are recorded with. It defaults to
+// the global provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = provider
+	}
+}
+
+// WithObjectKeys records the keys of the S3 objects a call reads or writes in
+// the aws.s3.key and aws.s3.copy_source span attributes. Keys often contain
+// customer or user identifiers, so they are left out by default.
+func WithObjectKeys(enabled bool) Option {
+	return func(o *options) {
+		o.objectKeys = enabled
+	}
+}
+
+// New creates the tracer and instruments of an Instrumentation.
+//
+// Parameters:
+//
+//	opts (...Option): The tracer and meter providers, and whether object keys are recorded.
+//
+// Returns:
+//
+//	(*Instrumentation, error): The instrumentation, or an error if an instrument could not be created.
+func New(opts ...Option) (*Instrumentation, error) {
+	o := &options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	meter := o.meterProvider.Meter(ScopeName)
+
+	duration, err := meter.Float64Histogram(DurationMetric,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of AWS calls, including retries."))
+	if err != nil {
+		return nil, err
+	}
+
+	throttles, err := meter.Int64Counter(ThrottlesMetric,
+		metric.WithUnit("{attempt}"),
+		metric.WithDescription("Number of AWS call attempts that were throttled."))
+	if err != nil {
+		return nil, err
+	}
+
+	errors, err := meter.Int64Counter(ErrorsMetric,
+		metric.WithUnit("{call}"),
+		metric.WithDescription("Number of AWS calls that failed after any retries."))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Instrumentation{
+		tracer:     o.tracerProvider.Tracer(ScopeName),
+		duration:   duration,
+		throttles:  throttles,
+		errors:     errors,
+		objectKeys: o.objectKeys,
+	}, nil
+}
+
+// Install adds the instrumentation to handlers, replacing any instrumentation
+// installed before, so it can be called on a client that inherited handlers
+// from an instrumented session.
+func (i *Instrumentation) Install(handlers *request.Handlers) {
+	observe.Install(handlers, handlerName, observer{i})
+}
+
+// Uninstall removes the instrumentation from handlers.
+func Uninstall(handlers *request.Handlers) {
+	observe.Uninstall(handlers, handlerName)
+}
+
+// observer traces calls for an Instrumentation without adding the observe
+// methods to its API.
+type observer struct {
+	*Instrumentation
+}
+
+// call is what Start hands over to Retry and End.
+type call struct {
+	span    trace.Span
+	started time.Time
+}
+
+func (o observer) Start(r *request.Request) interface{} {
+	ctx, span := o.tracer.Start(r.Context(), spanName(r),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(requestAttributes(r, o.objectKeys)...))
+	r.SetContext(ctx)
+
+	return call{span: span, started: time.Now()}
+}
+
+func (o observer) Retry(r *request.Request, state interface{}) {
+	c := state.(call)
+
+	code := observe.ErrorType(r.Error)
+	c.span.AddEvent("retry", trace.WithAttributes(semconv.ErrorTypeKey.String(code)))
+
+	if r.IsErrorThrottle() {
+		o.throttles.Add(r.Context(), 1, metric.WithAttributes(append(metricAttributes(r), semconv.ErrorTypeKey.String(code))...))
+	}
+}
+
+func (o observer) End(r *request.Request, state interface{}) {
+	c := state.(call)
+
+	ctx := r.Context()
+	attrs := metricAttributes(r)
+
+	if r.Error != nil {
+		code := observe.ErrorType(r.Error)
+		attrs = append(attrs, semconv.ErrorTypeKey.String(code))
+
+		c.span.RecordError(r.Error)
+		c.span.SetStatus(codes.Error, code)
+		c.span.SetAttributes(semconv.ErrorTypeKey.String(code))
+		o.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+	} else {
+		c.span.SetAttributes(responseAttributes(r)...)
+	}
+
+	if r.RequestID != "" {
+		c.span.SetAttributes(semconv.AWSRequestID(r.RequestID))
+	}
+
+	if r.HTTPResponse != nil && r.HTTPResponse.StatusCode > 0 {
+		c.span.SetAttributes(semconv.HTTPResponseStatusCode(r.HTTPResponse.StatusCode))
+	}
+
+	o.duration.Record(ctx, time.Since(c.started).Seconds(), metric.WithAttributes(attrs...))
+	c.span.End()
+}
+
+func spanName(r *request.Request) string {
+	return r.ClientInfo.ServiceID + "." + r.Operation.Name
+}
+
+// metricAttributes are the low-cardinality attributes shared by all metrics.
+func metricAttributes(r *request.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		rpcSystemAWS,
+		semconv.RPCService(r.ClientInfo.ServiceID),
+		semconv.RPCMethod(r.Operation.Name),
+	}
+
+	if tables := observe.TableNames(r.Params); len(tables) > 0 {
+		attrs = append(attrs, semconv.AWSDynamoDBTableNames(tables...))
+	}
+
+	if bucket := observe.BucketName(r.Params); bucket != "" {
+		attrs = append(attrs, semconv.AWSS3Bucket(bucket))
+	}
+
+	return attrs
+}
@@ -0,0 +1,280 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go_aws_services/internal/awstest"
+	"go_aws_services/session"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type harness struct {
+	instrumentation *Instrumentation
+	spans           *tracetest.InMemoryExporter
+	reader          *sdkmetric.ManualReader
+}
+
+func newHarness(t *testing.T, opts ...Option) *harness {
+	t.Helper()
+
+	spans := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+
+	instrumentation, err := New(append([]Option{
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	}, opts...)...)
+	require.NoError(t, err)
+
+	return &harness{instrumentation: instrumentation, spans: spans, reader: reader}
+}
+
+// metrics returns the collected metrics by name.
+func (h *harness) metrics(t *testing.T) map[string]metricdata.Metrics {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, h.reader.Collect(context.Background(), &rm))
+
+	metrics := make(map[string]metricdata.Metrics)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
+
+var queried = awstest.RespondJSON(http.StatusOK, `{"Count":2,"ScannedCount":5,"Items":[{"id":{"S":"1"}},{"id":{"S":"2"}}],"ConsumedCapacity":{"TableName":"users","CapacityUnits":1.5}}`)
+
+func queryInput() *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		TableName:                 aws.String("users"),
+		IndexName:                 aws.String("by-email"),
+		ConsistentRead:            aws.Bool(false),
+		Limit:                     aws.Int64(10),
+		KeyConditionExpression:    aws.String("email = :email"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":email": {S: aws.String("a@example.com")}},
+		ReturnConsumedCapacity:    aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestDynamoDBSpan(t *testing.T) {
+	h := newHarness(t)
+	client := dynamodb.New(awstest.NewSession(t, queried))
+	h.instrumentation.Install(&client.Handlers)
+
+	_, err := client.Query(queryInput())
+	require.NoError(t, err)
+
+	spans := h.spans.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	attrs := attributes(span)
+
+	assert.Equal(t, "DynamoDB.Query", span.Name)
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+	assert.Equal(t, codes.Unset, span.Status.Code)
+	assert.Equal(t, "aws-api", attrs["rpc.system"].AsString())
+	assert.Equal(t, "DynamoDB", attrs["rpc.service"].AsString())
+	assert.Equal(t, "Query", attrs["rpc.method"].AsString())
+	assert.Equal(t, "us-east-1", attrs["cloud.region"].AsString())
+	assert.Equal(t, "aws.dynamodb", attrs["db.system.name"].AsString())
+	assert.Equal(t, []string{"users"}, attrs["aws.dynamodb.table_names"].AsStringSlice())
+	assert.Equal(t, "by-email", attrs["aws.dynamodb.index_name"].AsString())
+	assert.False(t, attrs["aws.dynamodb.consistent_read"].AsBool())
+	assert.Equal(t, int64(10), attrs["aws.dynamodb.limit"].AsInt64())
+	assert.Equal(t, int64(2), attrs["aws.dynamodb.count"].AsInt64())
+	assert.Equal(t, int64(5), attrs["aws.dynamodb.scanned_count"].AsInt64())
+	assert.Equal(t, []string{`{"CapacityUnits":1.5,"TableName":"users"}`}, attrs["aws.dynamodb.consumed_capacity"].AsStringSlice())
+	assert.Equal(t, "REQ123", attrs["aws.request_id"].AsString())
+	assert.Equal(t, int64(200), attrs["http.response.status_code"].AsInt64())
+
+	metrics := h.metrics(t)
+	duration := metrics[DurationMetric].Data.(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+	method, _ := duration.DataPoints[0].Attributes.Value("rpc.method")
+	assert.Equal(t, "Query", method.AsString())
+	assert.NotContains(t, metrics, ErrorsMetric)
+}
+
+func TestBatchSpanTableNames(t *testing.T) {
+	h := newHarness(t)
+	client := dynamodb.New(awstest.NewSession(t, awstest.OK))
+	h.instrumentation.Install(&client.Handlers)
+
+	_, err := client.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: map[string]*dynamodb.KeysAndAttributes{
+		"users":  {Keys: []map[string]*dynamodb.AttributeValue{{"id": {S: aws.String("1")}}}},
+		"orders": {Keys: []map[string]*dynamodb.AttributeValue{{"id": {S: aws.String("1")}}}},
+	}})
+	require.NoError(t, err)
+
+	spans := h.spans.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, []string{"orders", "users"}, attributes(spans[0])["aws.dynamodb.table_names"].AsStringSlice())
+}
+
+func TestThrottlesAndRetries(t *testing.T) {
+	h := newHarness(t)
+	client := dynamodb.New(awstest.NewSession(t, awstest.Throttled, awstest.Throttled, queried))
+	client.Retryer = session.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}.Retryer()
+	h.instrumentation.Install(&client.Handlers)
+
+	_, err := client.Query(queryInput())
+	require.NoError(t, err)
+
+	spans := h.spans.GetSpans()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Events, 2)
+	assert.Equal(t, "retry", spans[0].Events[0].Name)
+
+	metrics := h.metrics(t)
+	throttles := metrics[ThrottlesMetric].Data.(metricdata.Sum[int64])
+	require.Len(t, throttles.DataPoints, 1)
+	assert.Equal(t, int64(2), throttles.DataPoints[0].Value)
+	code, _ := throttles.DataPoints[0].Attributes.Value("error.type")
+	assert.Equal(t, "ProvisionedThroughputExceededException", code.AsString())
+	assert.NotContains(t, metrics, ErrorsMetric)
+}
+
+func TestErrors(t *testing.T) {
+	h := newHarness(t)
+	client := dynamodb.New(awstest.NewSession(t, awstest.Invalid))
+	h.instrumentation.Install(&client.Handlers)
+
+	_, err := client.Query(queryInput())
+	require.Error(t, err)
+
+	spans := h.spans.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "ValidationException", spans[0].Status.Description)
+	assert.Equal(t, "ValidationException", attributes(spans[0])["error.type"].AsString())
+	assert.NotContains(t, attributes(spans[0]), attribute.Key("aws.dynamodb.count"))
+
+	errors := h.metrics(t)[ErrorsMetric].Data.(metricdata.Sum[int64])
+	require.Len(t, errors.DataPoints, 1)
+	assert.Equal(t, int64(1), errors.DataPoints[0].Value)
+	table, _ := errors.DataPoints[0].Attributes.Value("aws.dynamodb.table_names")
+	assert.Equal(t, []string{"users"}, table.AsStringSlice())
+}
+
+func TestS3Span(t *testing.T) {
+	h := newHarness(t)
+	client := s3.New(awstest.NewSession(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Request-Id", "S3REQ")
+	}))
+	h.instrumentation.Install(&client.Handlers)
+
+	_, err := client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("uploads"),
+		Key:    aws.String("1-2-3.apk"),
+		Body:   strings.NewReader("apk"),
+	})
+	require.NoError(t, err)
+
+	spans := h.spans.GetSpans()
+	require.Len(t, spans, 1)
+	attrs := attributes(spans[0])
+
+	assert.Equal(t, "S3.PutObject", spans[0].Name)
+	assert.Equal(t, "uploads", attrs["aws.s3.bucket"].AsString())
+	assert.NotContains(t, attrs, attribute.Key("aws.s3.key"))
+	assert.Equal(t, "S3REQ", attrs["aws.request_id"].AsString())
+	assert.NotContains(t, attrs, attribute.Key("db.system.name"))
+}
+
+func TestS3SpanObjectKeys(t *testing.T) {
+	h := newHarness(t, WithObjectKeys(true))
+	client := s3.New(awstest.NewSession(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+	}))
+	h.instrumentation.Install(&client.Handlers)
+
+	_, err := client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String("uploads"),
+		Key:        aws.String("1-2-3.apk"),
+		CopySource: aws.String("uploads/incoming/1-2-3.apk"),
+	})
+	require.NoError(t, err)
+
+	spans := h.spans.GetSpans()
+	require.Len(t, spans, 1)
+	attrs := attributes(spans[0])
+
+	assert.Equal(t, "1-2-3.apk", attrs["aws.s3.key"].AsString())
+	assert.Equal(t, "uploads/incoming/1-2-3.apk", attrs["aws.s3.copy_source"].AsString())
+}
+
+func TestPresignHasNoSpan(t *testing.T) {
+	h := newHarness(t)
+	client := s3.New(awstest.NewSession(t, awstest.RespondJSON(http.StatusOK, "")))
+	h.instrumentation.Install(&client.Handlers)
+
+	req, _ := client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String("uploads"), Key: aws.String("a")})
+	_, err := req.Presign(time.Minute)
+	require.NoError(t, err)
+
+	assert.Empty(t, h.spans.GetSpans())
+}
+
+func TestInstallReplaces(t *testing.T) {
+	first, second := newHarness(t), newHarness(t)
+	client := dynamodb.New(awstest.NewSession(t, queried))
+
+	first.instrumentation.Install(&client.Handlers)
+	second.instrumentation.Install(&client.Handlers)
+
+	_, err := client.Query(queryInput())
+	require.NoError(t, err)
+
+	assert.Empty(t, first.spans.GetSpans())
+	assert.Len(t, second.spans.GetSpans(), 1)
+
+	Uninstall(&client.Handlers)
+	_, err = client.Query(queryInput())
+	require.NoError(t, err)
+
+	assert.Len(t, second.spans.GetSpans(), 1)
+}
+
+func TestParentSpan(t *testing.T) {
+	h := newHarness(t)
+	client := dynamodb.New(awstest.NewSession(t, queried))
+	h.instrumentation.Install(&client.Handlers)
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(h.spans))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	_, err := client.QueryWithContext(ctx, queryInput())
+	parent.End()
+	require.NoError(t, err)
+
+	spans := h.spans.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+}
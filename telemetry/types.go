@@ -0,0 +1,41 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter.
+const ScopeName = "go_aws_services/telemetry"
+
+const (
+	// DurationMetric records how long each call took, including retries.
+	DurationMetric = "aws.client.operation.duration"
+	// ThrottlesMetric counts attempts that AWS throttled.
+	ThrottlesMetric = "aws.client.throttles"
+	// ErrorsMetric counts calls that failed after any retries.
+	ErrorsMetric = "aws.client.errors"
+)
+
+// handlerName prefixes the names of the handlers the instrumentation installs.
+const handlerName = "go_aws_services.Telemetry"
+
+// Instrumentation traces AWS calls and records their metrics. It is installed
+// into the handlers of a session or client, so every call made through them is
+// captured, including calls made on the raw SDK client.
+type Instrumentation struct {
+	tracer     trace.Tracer
+	duration   metric.Float64Histogram
+	throttles  metric.Int64Counter
+	errors     metric.Int64Counter
+	objectKeys bool
+}
+
+// Option customises an Instrumentation.
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	objectKeys     bool
+}
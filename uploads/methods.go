@@ -0,0 +1,268 @@
+package uploads
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	tables "go_aws_services/dynamodb"
+	storage "go_aws_services/s3"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+var (
+	ErrSessionNotFound  = errors.New("upload session not found")
+	ErrSessionExpired   = errors.New("upload session expired")
+	ErrObjectMissing    = errors.New("uploaded object not found")
+	ErrSizeMismatch     = errors.New("uploaded object size does not match")
+	ErrChecksumMismatch = errors.New("uploaded object checksum does not match")
+	ErrSessionConflict  = errors.New("upload session was changed by another request")
+)
+
+var (
+	_ SessionStore = (*tables.DynamoDBClient)(nil)
+	_ ObjectStore  = (*storage.S3Service)(nil)
+)
+
+var now = time.Now
+
+// NewManager creates a Manager that stores sessions in store and presigns and
+// verifies uploads with objects. Sessions that are not completed within ttl
+// expire; a zero ttl uses DefaultSessionTTL.
+func NewManager(store SessionStore, objects ObjectStore, ttl time.Duration) (*Manager, error) {
+	if store == nil || objects == nil {
+		return nil, errors.New("store and objects cannot be nil")
+	}
+
+	if ttl == 0 {
+		ttl = DefaultSessionTTL
+	}
+
+	if ttl < 0 || ttl > storage.MaxPresignExpiry {
+		return nil, fmt.Errorf("session TTL must be between 0 and %s", storage.MaxPresignExpiry)
+	}
+
+	return &Manager{store: store, objects: objects, ttl: ttl}, nil
+}
+
+// SessionID returns the ID of the session for the upload described by metadata.
+func SessionID(metadata storage.Metadata) string {
+	return fmt.Sprintf("%d#%d#%d", metadata.PartnerID, metadata.AppID, metadata.DeviceModelID)
+}
+
+// Begin presigns an upload of size bytes for the object described by metadata
+// and records it as pending. The URL expires together with the session, and the
+// size and metadata.ContentSHA256, when set, are bound into the signature.
+// Starting a session again for the same metadata replaces the previous one.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the operation.
+//	metadata (storage.Metadata): The upload to start.
+//	size (int64): The exact size of the file in bytes.
+//	opts (...storage.PresignOption): Additional constraints for the presigned PUT.
+//
+// Returns:
+//
+//	(Session, storage.PresignedUrlResponse, error): The pending session and the presigned URL, or an error if either could not be created.
+func (m *Manager) Begin(ctx context.Context, metadata storage.Metadata, size int64, opts ...storage.PresignOption) (Session, storage.PresignedUrlResponse, error) {
+	if size <= 0 {
+		return Session{}, storage.PresignedUrlResponse{}, errors.New("size must be positive")
+	}
+
+	opts = append(opts, storage.WithContentLength(size), storage.WithExpiry(m.ttl))
+
+	response, err := m.objects.GenerateSignedRequest(metadata, opts...)
+	if err != nil {
+		return Session{}, storage.PresignedUrlResponse{}, err
+	}
+
+	createdAt := now().UTC()
+
+	session := Session{
+		ID:            SessionID(metadata),
+		PartnerID:     metadata.PartnerID,
+		AppID:         metadata.AppID,
+		DeviceModelID: metadata.DeviceModelID,
+		Key:           response.Key,
+		Status:        StatusPending,
+		Size:          size,
+		ContentSHA256: metadata.ContentSHA256,
+		CreatedAt:     createdAt,
+		ExpiresAt:     createdAt.Add(m.ttl).Unix(),
+	}
+
+	if err := m.save(session); err != nil {
+		return Session{}, storage.PresignedUrlResponse{}, err
+	}
+
+	return session, response, nil
+}
+
+// Get returns the session for the upload described by metadata. A pending
+// session past its expiry is reported with status EXPIRED.
+func (m *Manager) Get(ctx context.Context, metadata storage.Metadata) (Session, error) {
+	output, err := m.store.GetItem(map[string]interface{}{KeySchema.HashKey: SessionID(metadata)})
+	if err != nil {
+		return Session{}, err
+	}
+
+	if len(output.Item) == 0 {
+		return Session{}, ErrSessionNotFound
+	}
+
+	var session Session
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &session); err != nil {
+		return Session{}, err
+	}
+
+	if session.Status == StatusPending && !now().Before(time.Unix(session.ExpiresAt, 0)) {
+		session.Status = StatusExpired
+	}
+
+	return session, nil
+}
+
+// Complete verifies that the object of a pending session arrived in S3 with the
+// expected size and checksum and marks the session COMPLETE. Completing a session
+// that is already complete returns it unchanged. A session that expired before
+// the object was verified is marked EXPIRED. Both writes only replace the pending
+// session that was read, so when Begin starts a new session for the same upload
+// in the meantime, Complete fails with ErrSessionConflict.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context for the operation.
+//	metadata (storage.Metadata): The upload to complete.
+//
+// Returns:
+//
+//	(Session, error): The completed session, or an error describing why the upload could not be verified.
+func (m *Manager) Complete(ctx context.Context, metadata storage.Metadata) (Session, error) {
+	session, err := m.Get(ctx, metadata)
+	if err != nil {
+		return Session{}, err
+	}
+
+	switch session.Status {
+	case StatusComplete:
+		return session, nil
+	case StatusExpired:
+		if err := m.replacePending(session); err != nil {
+			return Session{}, err
+		}
+		return session, ErrSessionExpired
+	}
+
+	object, err := m.objects.Head(ctx, session.Key)
+	if err != nil {
+		if isNotFound(err) {
+			return session, ErrObjectMissing
+		}
+		return session, err
+	}
+
+	if object.Size != session.Size {
+		return session, fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, session.Size, object.Size)
+	}
+
+	if session.ContentSHA256 != "" {
+		expected, err := checksumFromHex(session.ContentSHA256)
+		if err != nil {
+			return session, err
+		}
+		if object.ChecksumSHA256 != expected {
+			return session, ErrChecksumMismatch
+		}
+	}
+
+	session.Status = StatusComplete
+	session.ETag = object.ETag
+	session.CompletedAt = now().UTC()
+
+	if err := m.replacePending(session); err != nil {
+		return Session{}, err
+	}
+
+	return session, nil
+}
+
+// save writes session, replacing any session stored for the same upload.
+func (m *Manager) save(session Session) error {
+	_, err := m.store.PutItem(sessionItem(session))
+
+	return err
+}
+
+// replacePending writes session only if the stored session is still the
+// pending one it was read from. The session ID is the same for every upload of
+// an artifact, so without the check a Complete racing a new Begin would
+// overwrite the newer pending session. The conflict is reported as
+// ErrSessionConflict.
+func (m *Manager) replacePending(session Session) error {
+	_, err := m.store.PutItemWithCondition(sessionItem(session), tables.Condition{
+		Expression: "#status = :pending AND #createdAt = :createdAt",
+		Names:      map[string]string{"#status": "status", "#createdAt": "createdAt"},
+		Values:     map[string]interface{}{":pending": StatusPending, ":createdAt": session.CreatedAt},
+	})
+	if isConditionFailed(err) {
+		return ErrSessionConflict
+	}
+
+	return err
+}
+
+func sessionItem(session Session) map[string]interface{} {
+	item := map[string]interface{}{
+		KeySchema.HashKey:  session.ID,
+		"partnerId":        session.PartnerID,
+		"appId":            session.AppID,
+		"deviceModelId":    session.DeviceModelID,
+		"key":              session.Key,
+		"status":           session.Status,
+		"size":             session.Size,
+		"createdAt":        session.CreatedAt,
+		ExpiresAtAttribute: session.ExpiresAt,
+	}
+
+	if session.ContentSHA256 != "" {
+		item["contentSha256"] = session.ContentSHA256
+	}
+
+	if session.ETag != "" {
+		item["etag"] = session.ETag
+	}
+
+	if !session.CompletedAt.IsZero() {
+		item["completedAt"] = session.CompletedAt
+	}
+
+	return item
+}
+
+// checksumFromHex converts a hex SHA-256 digest to the base64 form S3 reports.
+func checksumFromHex(digest string) (string, error) {
+	sum, err := hex.DecodeString(digest)
+	if err != nil {
+		return "", errors.New("content SHA-256 must be a hex-encoded digest")
+	}
+
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+func isConditionFailed(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+func isNotFound(err error) bool {
+	var failure awserr.RequestFailure
+	return errors.As(err, &failure) && failure.StatusCode() == http.StatusNotFound
+}
@@ -0,0 +1,281 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tables "go_aws_services/dynamodb"
+	"go_aws_services/dynamodb/dynamodbtest"
+	storage "go_aws_services/s3"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const contentSHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+var (
+	metadata  = storage.Metadata{PartnerID: 1, AppID: 2, DeviceModelID: 3, ContentSHA256: contentSHA256}
+	createdAt = time.Date(2024, 5, 6, 7, 0, 0, 0, time.UTC)
+)
+
+func fixNow(t *testing.T, at time.Time) {
+	oldNow := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = oldNow })
+}
+
+func pendingSession() Session {
+	return Session{
+		ID:            "1#2#3",
+		PartnerID:     1,
+		AppID:         2,
+		DeviceModelID: 3,
+		Key:           "uploads/1-2-3.apk",
+		Status:        StatusPending,
+		Size:          4,
+		ContentSHA256: contentSHA256,
+		CreatedAt:     createdAt,
+		ExpiresAt:     createdAt.Add(time.Hour).Unix(),
+	}
+}
+
+func pendingCondition() tables.Condition {
+	return tables.Condition{
+		Expression: "#status = :pending AND #createdAt = :createdAt",
+		Names:      map[string]string{"#status": "status", "#createdAt": "createdAt"},
+		Values:     map[string]interface{}{":pending": StatusPending, ":createdAt": createdAt},
+	}
+}
+
+func mockGetSession(store *mockSessionStore, session Session) {
+	item, _ := dynamodbattribute.MarshalMap(session)
+	store.On("GetItem", map[string]interface{}{"sessionId": "1#2#3"}).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+}
+
+func TestNewManager(t *testing.T) {
+	t.Run("Default TTL", func(t *testing.T) {
+		manager, err := NewManager(new(mockSessionStore), new(mockObjectStore), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultSessionTTL, manager.ttl)
+	})
+
+	t.Run("TTL too long", func(t *testing.T) {
+		_, err := NewManager(new(mockSessionStore), new(mockObjectStore), 8*24*time.Hour)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing store", func(t *testing.T) {
+		_, err := NewManager(nil, new(mockObjectStore), 0)
+
+		assert.EqualError(t, err, "store and objects cannot be nil")
+	})
+}
+
+func TestBegin(t *testing.T) {
+	fixNow(t, createdAt)
+	manager, store, objects := mockNewManager()
+
+	objects.On("GenerateSignedRequest", metadata, mock.MatchedBy(func(opts []storage.PresignOption) bool {
+		return len(opts) == 2
+	})).Return(storage.PresignedUrlResponse{ID: 2, Key: "uploads/1-2-3.apk", PresignedUrl: "https://example.com"}, nil)
+	store.On("PutItem", map[string]interface{}{
+		"sessionId":     "1#2#3",
+		"partnerId":     1,
+		"appId":         2,
+		"deviceModelId": 3,
+		"key":           "uploads/1-2-3.apk",
+		"status":        StatusPending,
+		"size":          int64(4),
+		"contentSha256": contentSHA256,
+		"createdAt":     createdAt,
+		"expiresAt":     createdAt.Add(time.Hour).Unix(),
+	}).Return(&dynamodb.PutItemOutput{}, nil)
+
+	session, response, err := manager.Begin(context.Background(), metadata, 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, pendingSession(), session)
+	assert.Equal(t, "https://example.com", response.PresignedUrl)
+	store.AssertExpectations(t)
+}
+
+func TestGet(t *testing.T) {
+	t.Run("Pending session", func(t *testing.T) {
+		fixNow(t, createdAt.Add(time.Minute))
+		manager, store, _ := mockNewManager()
+		mockGetSession(store, pendingSession())
+
+		session, err := manager.Get(context.Background(), metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, pendingSession(), session)
+	})
+
+	t.Run("Expired session", func(t *testing.T) {
+		fixNow(t, createdAt.Add(2*time.Hour))
+		manager, store, _ := mockNewManager()
+		mockGetSession(store, pendingSession())
+
+		session, err := manager.Get(context.Background(), metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusExpired, session.Status)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		manager, store, _ := mockNewManager()
+		store.On("GetItem", mock.Anything).Return(&dynamodb.GetItemOutput{}, nil)
+
+		_, err := manager.Get(context.Background(), metadata)
+
+		assert.Equal(t, ErrSessionNotFound, err)
+	})
+}
+
+func TestComplete(t *testing.T) {
+	completedAt := createdAt.Add(time.Minute)
+	checksum := "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg="
+
+	t.Run("Verified upload", func(t *testing.T) {
+		fixNow(t, completedAt)
+		manager, store, objects := mockNewManager()
+		mockGetSession(store, pendingSession())
+		objects.On("Head", mock.Anything, "uploads/1-2-3.apk").Return(storage.ObjectInfo{Size: 4, ETag: "\"etag\"", ChecksumSHA256: checksum}, nil)
+		store.On("PutItemWithCondition", mock.MatchedBy(func(item map[string]interface{}) bool {
+			return item["status"] == StatusComplete && item["etag"] == "\"etag\"" && item["completedAt"] == completedAt
+		}), pendingCondition()).Return(&dynamodb.PutItemOutput{}, nil)
+
+		session, err := manager.Complete(context.Background(), metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusComplete, session.Status)
+		assert.Equal(t, completedAt, session.CompletedAt)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Already complete", func(t *testing.T) {
+		manager, store, objects := mockNewManager()
+		completed := pendingSession()
+		completed.Status = StatusComplete
+		mockGetSession(store, completed)
+
+		session, err := manager.Complete(context.Background(), metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusComplete, session.Status)
+		objects.AssertNotCalled(t, "Head", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expired session", func(t *testing.T) {
+		fixNow(t, createdAt.Add(2*time.Hour))
+		manager, store, objects := mockNewManager()
+		mockGetSession(store, pendingSession())
+		store.On("PutItemWithCondition", mock.MatchedBy(func(item map[string]interface{}) bool {
+			return item["status"] == StatusExpired
+		}), pendingCondition()).Return(&dynamodb.PutItemOutput{}, nil)
+
+		_, err := manager.Complete(context.Background(), metadata)
+
+		assert.Equal(t, ErrSessionExpired, err)
+		store.AssertExpectations(t)
+		objects.AssertNotCalled(t, "Head", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Session replaced by a new Begin", func(t *testing.T) {
+		fixNow(t, completedAt)
+		manager, store, objects := mockNewManager()
+		mockGetSession(store, pendingSession())
+		objects.On("Head", mock.Anything, "uploads/1-2-3.apk").Return(storage.ObjectInfo{Size: 4, ETag: "\"etag\"", ChecksumSHA256: checksum}, nil)
+		store.On("PutItemWithCondition", mock.Anything, pendingCondition()).Return((*dynamodb.PutItemOutput)(nil),
+			awserr.NewRequestFailure(awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil), 400, "request-id"))
+
+		_, err := manager.Complete(context.Background(), metadata)
+
+		assert.Equal(t, ErrSessionConflict, err)
+		store.AssertNotCalled(t, "PutItem", mock.Anything)
+	})
+
+	t.Run("Object missing", func(t *testing.T) {
+		fixNow(t, completedAt)
+		manager, store, objects := mockNewManager()
+		mockGetSession(store, pendingSession())
+		objects.On("Head", mock.Anything, mock.Anything).Return(storage.ObjectInfo{}, awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "request-id"))
+
+		_, err := manager.Complete(context.Background(), metadata)
+
+		assert.Equal(t, ErrObjectMissing, err)
+		store.AssertNotCalled(t, "PutItem", mock.Anything)
+	})
+
+	t.Run("Size mismatch", func(t *testing.T) {
+		fixNow(t, completedAt)
+		manager, store, objects := mockNewManager()
+		mockGetSession(store, pendingSession())
+		objects.On("Head", mock.Anything, mock.Anything).Return(storage.ObjectInfo{Size: 5, ChecksumSHA256: checksum}, nil)
+
+		_, err := manager.Complete(context.Background(), metadata)
+
+		assert.True(t, errors.Is(err, ErrSizeMismatch))
+		store.AssertNotCalled(t, "PutItem", mock.Anything)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		fixNow(t, completedAt)
+		manager, store, objects := mockNewManager()
+		mockGetSession(store, pendingSession())
+		objects.On("Head", mock.Anything, mock.Anything).Return(storage.ObjectInfo{Size: 4}, nil)
+
+		_, err := manager.Complete(context.Background(), metadata)
+
+		assert.Equal(t, ErrChecksumMismatch, err)
+		store.AssertNotCalled(t, "PutItem", mock.Anything)
+	})
+}
+
+func TestCompleteRacingBegin(t *testing.T) {
+	fixNow(t, createdAt)
+
+	store, err := tables.NewDynamoDBClientWithAPI(dynamodbtest.New(), "sessions", KeySchema, nil)
+	assert.NoError(t, err)
+	_, err = store.CreateTableAsync()
+	assert.NoError(t, err)
+
+	objects := new(mockObjectStore)
+	manager, _ := NewManager(store, objects, 0)
+
+	objects.On("GenerateSignedRequest", metadata, mock.Anything).Return(storage.PresignedUrlResponse{Key: "uploads/1-2-3.apk"}, nil)
+	_, _, err = manager.Begin(context.Background(), metadata, 4)
+	assert.NoError(t, err)
+
+	// The upload is started again while the first one is being verified.
+	restartedAt := createdAt.Add(time.Minute)
+	objects.On("Head", mock.Anything, "uploads/1-2-3.apk").Run(func(mock.Arguments) {
+		fixNow(t, restartedAt)
+		_, _, err := manager.Begin(context.Background(), metadata, 4)
+		assert.NoError(t, err)
+	}).Return(storage.ObjectInfo{Size: 4, ChecksumSHA256: "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg="}, nil)
+
+	_, err = manager.Complete(context.Background(), metadata)
+	assert.Equal(t, ErrSessionConflict, err)
+
+	session, err := manager.Get(context.Background(), metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, session.Status)
+	assert.Equal(t, restartedAt, session.CreatedAt)
+
+	// Verifying the newer session succeeds.
+	objects.ExpectedCalls = nil
+	objects.On("Head", mock.Anything, "uploads/1-2-3.apk").Return(storage.ObjectInfo{Size: 4, ChecksumSHA256: "n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg="}, nil)
+
+	session, err = manager.Complete(context.Background(), metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusComplete, session.Status)
+}
@@ -0,0 +1,53 @@
+package uploads
+
+import (
+	"context"
+
+	tables "go_aws_services/dynamodb"
+	storage "go_aws_services/s3"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockSessionStore struct {
+	mock.Mock
+}
+
+type mockObjectStore struct {
+	mock.Mock
+}
+
+func (m *mockSessionStore) PutItem(item map[string]interface{}) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(item)
+	return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *mockSessionStore) PutItemWithCondition(item map[string]interface{}, condition tables.Condition) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(item, condition)
+	return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *mockSessionStore) GetItem(key map[string]interface{}) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(key)
+	return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+func (m *mockObjectStore) GenerateSignedRequest(metadata storage.Metadata, opts ...storage.PresignOption) (storage.PresignedUrlResponse, error) {
+	args := m.Called(metadata, opts)
+	return args.Get(0).(storage.PresignedUrlResponse), args.Error(1)
+}
+
+func (m *mockObjectStore) Head(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(storage.ObjectInfo), args.Error(1)
+}
+
+func mockNewManager() (*Manager, *mockSessionStore, *mockObjectStore) {
+	store := new(mockSessionStore)
+	objects := new(mockObjectStore)
+
+	manager, _ := NewManager(store, objects, 0)
+
+	return manager, store, objects
+}
@@ -0,0 +1,70 @@
+package uploads
+
+import (
+	"context"
+	"time"
+
+	tables "go_aws_services/dynamodb"
+	storage "go_aws_services/s3"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	StatusPending  = "PENDING"
+	StatusComplete = "COMPLETE"
+	StatusExpired  = "EXPIRED"
+)
+
+const (
+	DefaultSessionTTL  = time.Hour
+	ExpiresAtAttribute = "expiresAt"
+)
+
+// KeySchema is the key schema of the table sessions are stored in. Pass it to
+// tables.NewDynamoDBClient and enable TTL on ExpiresAtAttribute so DynamoDB
+// removes abandoned sessions.
+var KeySchema = tables.KeySchemaInput{
+	HashKey:            "sessionId",
+	ReadCapacityUnits:  5,
+	WriteCapacityUnits: 5,
+}
+
+// SessionStore persists sessions. *tables.DynamoDBClient implements it.
+type SessionStore interface {
+	PutItem(item map[string]interface{}) (*dynamodb.PutItemOutput, error)
+	PutItemWithCondition(item map[string]interface{}, condition tables.Condition) (*dynamodb.PutItemOutput, error)
+	GetItem(key map[string]interface{}) (*dynamodb.GetItemOutput, error)
+}
+
+// ObjectStore presigns uploads and inspects the uploaded objects.
+// *storage.S3Service implements it.
+type ObjectStore interface {
+	GenerateSignedRequest(metadata storage.Metadata, opts ...storage.PresignOption) (storage.PresignedUrlResponse, error)
+	Head(ctx context.Context, key string) (storage.ObjectInfo, error)
+}
+
+// Manager tracks presigned uploads from the moment the URL is handed out until
+// the object is verified in S3.
+type Manager struct {
+	store   SessionStore
+	objects ObjectStore
+	ttl     time.Duration
+}
+
+// Session is the record of one presigned upload. ExpiresAt is stored in epoch
+// seconds so it can be used as the table's TTL attribute.
+type Session struct {
+	ID            string    `json:"sessionId" dynamodbav:"sessionId"`
+	PartnerID     int       `json:"partnerId" dynamodbav:"partnerId"`
+	AppID         int       `json:"appId" dynamodbav:"appId"`
+	DeviceModelID int       `json:"deviceModelId" dynamodbav:"deviceModelId"`
+	Key           string    `json:"key" dynamodbav:"key"`
+	Status        string    `json:"status" dynamodbav:"status"`
+	Size          int64     `json:"size" dynamodbav:"size"`
+	ContentSHA256 string    `json:"contentSha256,omitempty" dynamodbav:"contentSha256,omitempty"`
+	ETag          string    `json:"etag,omitempty" dynamodbav:"etag,omitempty"`
+	CreatedAt     time.Time `json:"createdAt" dynamodbav:"createdAt"`
+	CompletedAt   time.Time `json:"completedAt,omitempty" dynamodbav:"completedAt,omitempty"`
+	ExpiresAt     int64     `json:"expiresAt" dynamodbav:"expiresAt"`
+}